@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"muvi-discovery-app/internal/handlers"
+	"muvi-discovery-app/internal/models"
 	"muvi-discovery-app/internal/services"
 
-	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 )
 
@@ -33,42 +40,112 @@ func main() {
 	log.Printf("Loaded API keys - TMDB: %s..., OMDB: %s...", tmdbKey[:8], omdbKey[:8])
 
 	// Initialize services
-	tmdbService := services.NewTMDBService(tmdbKey)
+	tmdbService := services.NewTMDBService(tmdbKey, os.Getenv("TMDB_REGION"))
 	omdbService := services.NewOMDBService(omdbKey)
 
+	// Optional features
+	enrich, _ := strconv.ParseBool(os.Getenv("OMDB_ENRICH"))
+	if enrich {
+		log.Printf("OMDB enrichment enabled for list and search results")
+	}
+
+	// Content profile
+	profile := models.ContentProfile{
+		MaxMovieCertification: os.Getenv("CONTENT_MAX_MOVIE_RATING"),
+		MaxTVCertification:    os.Getenv("CONTENT_MAX_TV_RATING"),
+		ExcludeKeywords:       splitList(os.Getenv("CONTENT_EXCLUDE_KEYWORDS")),
+	}
+	profile.AllowAdult, _ = strconv.ParseBool(os.Getenv("CONTENT_ALLOW_ADULT"))
+	for _, id := range splitList(os.Getenv("CONTENT_EXCLUDE_GENRES")) {
+		genreID, err := strconv.Atoi(id)
+		if err != nil {
+			log.Fatalf("CONTENT_EXCLUDE_GENRES: invalid genre ID %q", id)
+		}
+		profile.ExcludeGenres = append(profile.ExcludeGenres, genreID)
+	}
+	tmdbService.SetContentProfile(profile)
+	if profile.Restricted() {
+		log.Printf("Content profile active: %+v", profile)
+	}
+
+	devMode, _ := strconv.ParseBool(os.Getenv("DEV_MODE"))
+	if devMode {
+		log.Printf("Development mode: serving templates and static files from web/")
+	}
+
+	var imageCacheSize int64
+	if mb := os.Getenv("IMAGE_CACHE_MB"); mb != "" {
+		size, err := strconv.ParseInt(mb, 10, 64)
+		if err != nil || size <= 0 {
+			log.Fatalf("IMAGE_CACHE_MB: invalid size %q", mb)
+		}
+		imageCacheSize = size << 20
+	}
+
+	adminToken := os.Getenv("ADMIN_TOKEN")
+	if adminToken == "" {
+		log.Printf("ADMIN_TOKEN is not set, webhooks are disabled")
+	}
+	allowPrivateWebhooks, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE"))
+
 	// Initialize handlers
-	h := handlers.NewHandler(tmdbService, omdbService)
+	h := handlers.NewHandler(tmdbService, omdbService, handlers.Config{
+		DataDir:              os.Getenv("DATA_DIR"),
+		OMDBEnrichment:       enrich,
+		ImageCacheDir:        os.Getenv("IMAGE_CACHE_DIR"),
+		ImageCacheSize:       imageCacheSize,
+		DevMode:              devMode,
+		AdminToken:           adminToken,
+		AllowPrivateWebhooks: allowPrivateWebhooks,
+	})
 
 	// Setup routes
-	r := mux.NewRouter()
-
-	// Static files
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("web/static/"))))
-
-	// Routes
-	r.HandleFunc("/", h.Home).Methods("GET")
-	r.HandleFunc("/movies", h.Movies).Methods("GET")
-	r.HandleFunc("/movies/{id}", h.MovieDetails).Methods("GET")
-	r.HandleFunc("/tv", h.TVShows).Methods("GET")
-	r.HandleFunc("/tv/{id}", h.TVShowDetails).Methods("GET")
-	r.HandleFunc("/search", h.Search).Methods("GET")
-	r.HandleFunc("/discover", h.Discover).Methods("GET")
-	r.HandleFunc("/watchlist", h.Watchlist).Methods("GET")
-
-	// API routes
-	api := r.PathPrefix("/api").Subrouter()
-	api.HandleFunc("/search", h.APISearch).Methods("GET")
-	api.HandleFunc("/watchlist", h.APIWatchlistAdd).Methods("POST")
-	api.HandleFunc("/watchlist/{id}", h.APIWatchlistRemove).Methods("DELETE")
-	api.HandleFunc("/watchlist/{id}/toggle", h.APIWatchlistToggle).Methods("PUT")
-	api.HandleFunc("/movies/{id}/videos", h.APIMovieVideos).Methods("GET")
-	api.HandleFunc("/tv/{id}/videos", h.APITVShowVideos).Methods("GET")
+	r := handlers.NewRouter(h)
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "5000"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	// Event streams never finish on their own, so end them on shutdown
+	srv.RegisterOnShutdown(h.CloseStreams)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Printf("Shutting down, waiting up to %s for requests and background jobs", shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	if err := h.Close(shutdownCtx); err != nil {
+		log.Printf("Error stopping background work: %v", err)
+	}
+	log.Printf("Server stopped")
+}
+
+// shutdownTimeout bounds how long a shutdown waits for in-flight work
+const shutdownTimeout = 15 * time.Second
+
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
@@ -0,0 +1,268 @@
+// Package fulltext is a small in-memory full-text index. Documents are sets
+// of named text fields; queries match whole words, word prefixes and, for
+// longer words, misspellings within a small edit distance.
+package fulltext
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Document is the text to index, keyed by field name (e.g. "title", "overview")
+type Document map[string]string
+
+// Result is a matching document ID with its relevance score
+type Result struct {
+	ID    string
+	Score float64
+}
+
+// Index is safe for concurrent use
+type Index struct {
+	mu       sync.RWMutex
+	weights  map[string]float64
+	docs     map[string]map[string]int      // doc ID -> term -> weighted frequency
+	postings map[string]map[string]struct{} // term -> doc IDs
+}
+
+// New creates an index. weights scales matches per field; fields without a weight count as 1.
+func New(weights map[string]float64) *Index {
+	return &Index{
+		weights:  weights,
+		docs:     make(map[string]map[string]int),
+		postings: make(map[string]map[string]struct{}),
+	}
+}
+
+// Put adds or replaces a document
+func (ix *Index) Put(id string, doc Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+
+	terms := make(map[string]int)
+	for field, text := range doc {
+		weight := ix.fieldWeight(field)
+		if weight <= 0 {
+			continue
+		}
+		for _, term := range Tokenize(text) {
+			// Frequencies are stored in tenths so fractional field weights survive
+			terms[term] += int(math.Round(weight * 10))
+		}
+	}
+
+	ix.docs[id] = terms
+	for term := range terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[string]struct{})
+		}
+		ix.postings[term][id] = struct{}{}
+	}
+}
+
+// Delete removes a document; unknown IDs are ignored
+func (ix *Index) Delete(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+// Len returns the number of indexed documents
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+func (ix *Index) remove(id string) {
+	for term := range ix.docs[id] {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+func (ix *Index) fieldWeight(field string) float64 {
+	if w, ok := ix.weights[field]; ok {
+		return w
+	}
+	return 1
+}
+
+// Search returns documents matching every query word, best first. A word
+// matches a term exactly, as a prefix, or within an edit distance of 1 (words
+// of 4+ letters) or 2 (8+ letters), with weaker matches scoring lower.
+func (ix *Index) Search(query string, limit int) []Result {
+	words := Tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var scores map[string]float64
+	for _, word := range words {
+		wordScores := ix.scoreWord(word)
+		if scores == nil {
+			scores = wordScores
+			continue
+		}
+		// Every word has to match for a document to stay in the results
+		for id, score := range scores {
+			if s, ok := wordScores[id]; ok {
+				scores[id] = score + s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{ID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// scoreWord gives each document the score of its best-matching term for word
+func (ix *Index) scoreWord(word string) map[string]float64 {
+	scores := make(map[string]float64)
+	maxDistance := typoAllowance(word)
+
+	for term, docIDs := range ix.postings {
+		var quality float64
+		switch {
+		case term == word:
+			quality = 1
+		case strings.HasPrefix(term, word) && len(word) >= 2:
+			quality = 0.7
+		case maxDistance > 0 && abs(len(term)-len(word)) <= maxDistance:
+			d := editDistance(word, term, maxDistance)
+			if d > maxDistance {
+				continue
+			}
+			quality = 0.6 / float64(d)
+		default:
+			continue
+		}
+
+		idf := math.Log(1 + float64(len(ix.docs))/float64(len(docIDs)))
+		for id := range docIDs {
+			tf := float64(ix.docs[id][term]) / 10
+			score := quality * idf * math.Log1p(tf)
+			if score > scores[id] {
+				scores[id] = score
+			}
+		}
+	}
+
+	return scores
+}
+
+func typoAllowance(word string) int {
+	n := len([]rune(word))
+	switch {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// editDistance is the Damerau-Levenshtein (optimal string alignment) distance,
+// giving up early once it exceeds max
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Tokenize lowercases text, folds common accented letters and splits it into words
+func Tokenize(text string) []string {
+	var words []string
+	var b strings.Builder
+
+	flush := func() {
+		if b.Len() > 0 {
+			words = append(words, b.String())
+			b.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if folded, ok := accentFolds[r]; ok {
+				b.WriteString(folded)
+			} else {
+				b.WriteRune(r)
+			}
+		case r == '\'' || r == '’':
+			// "Schindler's" indexes as "schindlers"
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return words
+}
+
+var accentFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'œ': "oe",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
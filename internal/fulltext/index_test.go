@@ -0,0 +1,130 @@
+package fulltext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"matrix", "matrix", 2, 0},
+		{"matirx", "matrix", 2, 1}, // transposition
+		{"matrx", "matrix", 2, 1},  // deletion
+		{"matrixx", "matrix", 2, 1},
+		{"metrix", "matrix", 2, 1},
+		{"mtarix", "matrix", 2, 1},
+		{"alien", "aliens", 1, 1},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 1, 2}, // gives up past max
+		{"", "abc", 5, 3},
+		{"amélie", "amelie", 1, 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.max); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"The Matrix", []string{"the", "matrix"}},
+		{"Schindler's List", []string{"schindlers", "list"}},
+		{"Amélie", []string{"amelie"}},
+		{"Spider-Man: No Way Home", []string{"spider", "man", "no", "way", "home"}},
+		{"2001: A Space Odyssey", []string{"2001", "a", "space", "odyssey"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func newTestIndex() *Index {
+	ix := New(map[string]float64{"title": 3, "overview": 1})
+	ix.Put("movie:603", Document{"title": "The Matrix", "overview": "A hacker learns the truth about reality"})
+	ix.Put("movie:348", Document{"title": "Alien", "overview": "The crew of a spaceship meets a deadly creature"})
+	ix.Put("movie:679", Document{"title": "Aliens", "overview": "Ripley returns to the planet"})
+	ix.Put("tv:1399", Document{"title": "Game of Thrones", "overview": "Noble families fight for the Iron Throne"})
+	return ix
+}
+
+func resultIDs(results []Result) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	ix := newTestIndex()
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"matrix", []string{"movie:603"}},
+		{"matirx", []string{"movie:603"}},             // typo
+		{"thro", []string{"tv:1399"}},                 // prefix
+		{"alien", []string{"movie:348", "movie:679"}}, // exact beats prefix
+		{"aliens", []string{"movie:679", "movie:348"}},
+		{"hacker reality", []string{"movie:603"}},
+		{"hacker thrones", nil}, // every word has to match
+		{"zzz", nil},
+		{"", nil},
+		{"m", nil}, // too short for prefixes or typos
+	}
+	for _, tt := range tests {
+		if got := resultIDs(ix.Search(tt.query, 0)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchWeightsFields(t *testing.T) {
+	ix := New(map[string]float64{"title": 3, "overview": 1})
+	ix.Put("a", Document{"title": "Heat", "overview": "A crew of thieves"})
+	ix.Put("b", Document{"title": "Thieves", "overview": "A heist film"})
+	if got := resultIDs(ix.Search("thieves", 0)); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("Search(thieves) = %v, want the title match first", got)
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	ix := newTestIndex()
+	if got := ix.Search("alien", 1); len(got) != 1 {
+		t.Errorf("Search with limit 1 returned %d results", len(got))
+	}
+}
+
+func TestPutReplacesAndDeleteRemoves(t *testing.T) {
+	ix := newTestIndex()
+	ix.Put("movie:603", Document{"title": "The Matrix Reloaded"})
+	if got := resultIDs(ix.Search("hacker", 0)); got != nil {
+		t.Errorf("old text still matches after Put: %v", got)
+	}
+	if got := resultIDs(ix.Search("reloaded", 0)); !reflect.DeepEqual(got, []string{"movie:603"}) {
+		t.Errorf("Search(reloaded) = %v", got)
+	}
+
+	ix.Delete("movie:603")
+	ix.Delete("movie:unknown")
+	if ix.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", ix.Len())
+	}
+	if got := ix.Search("matrix", 0); len(got) != 0 {
+		t.Errorf("deleted document still found: %v", got)
+	}
+	if _, ok := ix.postings["reloaded"]; ok {
+		t.Error("postings for a deleted document's terms were kept")
+	}
+}
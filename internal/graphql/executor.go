@@ -0,0 +1,544 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+const (
+	defaultMaxConcurrency = 8
+	defaultMaxWorkers     = 16
+	defaultMaxDepth       = 10
+	defaultMaxComplexity  = 5000
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is a GraphQL response. Data is absent when the request failed
+// before execution started (syntax or validation errors).
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is a GraphQL error, with the response path of the field that failed
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Execute parses, validates and runs a request against the schema
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	op, err := doc.selectOperation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	root := s.Query
+	if op.kind == "mutation" {
+		root = s.Mutation
+	}
+	if root == nil {
+		return &Response{Errors: []*Error{{Message: fmt.Sprintf("schema does not support %s operations", op.kind)}}}
+	}
+	if op.kind == "query" {
+		root = s.withIntrospection(root)
+	}
+
+	vars, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{schema: s, doc: doc, vars: vars, validated: map[string]bool{}, measured: map[string]cost{}, nestings: map[string]int{}}
+	if errs := e.validate(root, op.selectionSet, map[string]bool{}); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	if err := e.checkLimits(root, op.selectionSet); err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	workers := s.MaxWorkers
+	if workers <= 0 {
+		workers = defaultMaxWorkers
+	}
+	e.workers = make(chan struct{}, workers)
+
+	maxConcurrency := s.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	ctx = WithLoader(ctx, NewLoader(maxConcurrency))
+
+	// Mutations run their top-level fields one after another, as the spec requires
+	data := e.executeSelection(ctx, root, nil, op.selectionSet, nil, op.kind == "mutation")
+	return &Response{Data: data, Errors: e.errors}
+}
+
+func (d *document) selectOperation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required when the document contains several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func coerceVariables(op *operation, provided map[string]interface{}) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	for _, def := range op.variables {
+		v, ok := provided[def.name]
+		if !ok && def.defaultVal != nil {
+			v, ok = literalValue(def.defaultVal, nil), true
+		}
+		if def.nonNull && (!ok || v == nil) {
+			return nil, fmt.Errorf("variable $%s of type %s! is required", def.name, def.typeName)
+		}
+		if ok {
+			vars[def.name] = v
+		}
+	}
+	return vars, nil
+}
+
+// literalValue converts a parsed value into plain Go values, substituting variables
+func literalValue(v value, vars map[string]interface{}) interface{} {
+	switch v := v.(type) {
+	case variableRef:
+		return vars[string(v)]
+	case enumValue:
+		return string(v)
+	case []value:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = literalValue(item, vars)
+		}
+		return list
+	case map[string]value:
+		obj := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			obj[k] = literalValue(item, vars)
+		}
+		return obj
+	}
+	return v
+}
+
+type executor struct {
+	schema  *Schema
+	doc     *document
+	vars    map[string]interface{}
+	workers chan struct{} // a slot per goroutine resolving fields
+
+	// Fragments are validated and measured once, however often they're spread
+	validated map[string]bool
+	measured  map[string]cost
+	nestings  map[string]int // see typeNesting
+
+	mu     sync.Mutex
+	errors []*Error
+}
+
+func (e *executor) addError(path []interface{}, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.errors = append(e.errors, &Error{Message: err.Error(), Path: path})
+}
+
+// validate checks every selection against the schema so no resolver runs for an invalid document
+func (e *executor) validate(obj *Object, selections []selection, visiting map[string]bool) []*Error {
+	var errs []*Error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, &Error{Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if sel.name == "__typename" {
+				if sel.selectionSet != nil {
+					fail("field \"__typename\" must not have a selection")
+				}
+				continue
+			}
+
+			def, ok := obj.Fields[sel.name]
+			if !ok {
+				fail("cannot query field %q on type %q", sel.name, obj.Name)
+				continue
+			}
+			if def.introspection {
+				if n := e.typeNesting(sel.selectionSet); n > maxTypeNesting {
+					fail("field %q nests fields, inputFields, interfaces or possibleTypes %d levels deep, more than the maximum of %d", sel.name, n, maxTypeNesting)
+				}
+			}
+			for name := range sel.arguments {
+				if _, ok := def.Args[name]; !ok {
+					fail("unknown argument %q on field %q of type %q", name, sel.name, obj.Name)
+				}
+			}
+			for name, arg := range def.Args {
+				if !arg.Required {
+					continue
+				}
+				v, given := sel.arguments[name]
+				if ref, isVar := v.(variableRef); isVar {
+					_, given = e.vars[string(ref)]
+				}
+				if !given || v == nil {
+					fail("field %q of type %q requires argument %q", sel.name, obj.Name, name)
+				}
+			}
+
+			switch {
+			case def.Type == nil && sel.selectionSet != nil:
+				fail("field %q of type %q is a scalar and must not have a selection", sel.name, obj.Name)
+			case def.Type != nil && sel.selectionSet == nil:
+				fail("field %q of type %q must have a selection of subfields", sel.name, obj.Name)
+			case def.Type != nil:
+				errs = append(errs, e.validate(def.Type, sel.selectionSet, visiting)...)
+			}
+
+		case *fragmentSpread:
+			frag, ok := e.doc.fragments[sel.name]
+			if !ok {
+				fail("unknown fragment %q", sel.name)
+				continue
+			}
+			if visiting[sel.name] {
+				fail("fragment %q spreads itself", sel.name)
+				continue
+			}
+			if frag.typeCondition != obj.Name {
+				fail("fragment %q on %q cannot be spread on type %q", sel.name, frag.typeCondition, obj.Name)
+				continue
+			}
+			if e.validated[sel.name] {
+				continue
+			}
+			e.validated[sel.name] = true
+			visiting[sel.name] = true
+			errs = append(errs, e.validate(obj, frag.selectionSet, visiting)...)
+			delete(visiting, sel.name)
+
+		case *inlineFragment:
+			if sel.typeCondition != "" && sel.typeCondition != obj.Name {
+				fail("inline fragment on %q cannot be used on type %q", sel.typeCondition, obj.Name)
+				continue
+			}
+			errs = append(errs, e.validate(obj, sel.selectionSet, visiting)...)
+		}
+	}
+
+	return errs
+}
+
+// cost is the estimated size of a selection set
+type cost struct {
+	complexity int
+	depth      int
+}
+
+// checkLimits rejects documents that nest too deeply or would resolve too
+// many fields, before any resolver runs. Directives are ignored, so the
+// estimate is an upper bound.
+func (e *executor) checkLimits(root *Object, selections []selection) error {
+	maxDepth := e.schema.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	maxComplexity := e.schema.MaxComplexity
+	if maxComplexity <= 0 {
+		maxComplexity = defaultMaxComplexity
+	}
+
+	c := e.measure(root, selections, maxComplexity)
+	if c.depth > maxDepth {
+		return fmt.Errorf("query depth %d exceeds the maximum of %d", c.depth, maxDepth)
+	}
+	if c.complexity > maxComplexity {
+		return fmt.Errorf("query complexity exceeds the maximum of %d", maxComplexity)
+	}
+	return nil
+}
+
+// measure estimates the cost of a validated selection set. It stops adding
+// once complexity passes limit, so huge documents fail fast.
+func (e *executor) measure(obj *Object, selections []selection, limit int) cost {
+	var total cost
+	add := func(c cost) {
+		total.complexity = min(total.complexity+c.complexity, limit+1)
+		total.depth = max(total.depth, c.depth)
+	}
+
+	for _, sel := range selections {
+		if total.complexity > limit {
+			break
+		}
+		switch sel := sel.(type) {
+		case *field:
+			def, ok := obj.Fields[sel.name]
+			if !ok || def.Type == nil {
+				// Scalars and __typename
+				add(cost{complexity: 1, depth: 1})
+				continue
+			}
+			nested := e.measure(def.Type, sel.selectionSet, limit)
+			if def.introspection {
+				// The schema description is bounded by maxTypeNesting instead
+				nested.depth = 0
+			}
+			size := max(def.ListSize, 1)
+			add(cost{complexity: 1 + min(nested.complexity*size, limit+1), depth: 1 + nested.depth})
+		case *fragmentSpread:
+			c, ok := e.measured[sel.name]
+			if !ok {
+				c = e.measure(obj, e.doc.fragments[sel.name].selectionSet, limit)
+				e.measured[sel.name] = c
+			}
+			add(c)
+		case *inlineFragment:
+			add(e.measure(obj, sel.selectionSet, limit))
+		}
+	}
+	return total
+}
+
+// collectedField groups the field nodes that share a response key
+type collectedField struct {
+	key    string
+	fields []*field
+}
+
+func (e *executor) collectFields(selections []selection, collected []*collectedField, index map[string]*collectedField) []*collectedField {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if !e.included(sel.directives) {
+				continue
+			}
+			key := sel.responseKey()
+			if cf, ok := index[key]; ok {
+				cf.fields = append(cf.fields, sel)
+				continue
+			}
+			cf := &collectedField{key: key, fields: []*field{sel}}
+			index[key] = cf
+			collected = append(collected, cf)
+		case *fragmentSpread:
+			if e.included(sel.directives) {
+				frag := e.doc.fragments[sel.name]
+				if e.included(frag.directives) {
+					collected = e.collectFields(frag.selectionSet, collected, index)
+				}
+			}
+		case *inlineFragment:
+			if e.included(sel.directives) {
+				collected = e.collectFields(sel.selectionSet, collected, index)
+			}
+		}
+	}
+	return collected
+}
+
+// included evaluates @skip and @include
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		cond, _ := literalValue(d.arguments["if"], e.vars).(bool)
+		switch d.name {
+		case "skip":
+			if cond {
+				return false
+			}
+		case "include":
+			if !cond {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (e *executor) executeSelection(ctx context.Context, obj *Object, source interface{}, selections []selection, path []interface{}, serial bool) *orderedMap {
+	collected := e.collectFields(selections, nil, map[string]*collectedField{})
+	result := &orderedMap{
+		keys:   make([]string, len(collected)),
+		values: make([]interface{}, len(collected)),
+	}
+
+	run := func(i int, cf *collectedField) {
+		result.keys[i] = cf.key
+		result.values[i] = e.executeField(ctx, obj, source, cf, appendPath(path, cf.key))
+	}
+
+	if serial {
+		for i, cf := range collected {
+			run(i, cf)
+		}
+		return result
+	}
+
+	var wg sync.WaitGroup
+	for i, cf := range collected {
+		e.spawn(&wg, func() { run(i, cf) })
+	}
+	wg.Wait()
+	return result
+}
+
+// spawn runs fn on a new goroutine while the request has a free worker slot,
+// and on the caller's goroutine otherwise. Running inline rather than waiting
+// for a slot means nested selections can't deadlock on a full pool.
+func (e *executor) spawn(wg *sync.WaitGroup, fn func()) {
+	wg.Add(1)
+	select {
+	case e.workers <- struct{}{}:
+		go func() {
+			defer func() {
+				<-e.workers
+				wg.Done()
+			}()
+			fn()
+		}()
+	default:
+		defer wg.Done()
+		fn()
+	}
+}
+
+func (e *executor) executeField(ctx context.Context, obj *Object, source interface{}, cf *collectedField, path []interface{}) (result interface{}) {
+	f := cf.fields[0]
+	if f.name == "__typename" {
+		return obj.Name
+	}
+	def := obj.Fields[f.name]
+
+	args := Args{}
+	for name, arg := range def.Args {
+		if arg.Default != nil {
+			args[name] = arg.Default
+		}
+	}
+	for name, v := range f.arguments {
+		if ref, isVar := v.(variableRef); isVar {
+			if _, given := e.vars[string(ref)]; !given {
+				continue
+			}
+		}
+		args[name] = literalValue(v, e.vars)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			e.addError(path, fmt.Errorf("internal error resolving %q: %v", f.name, r))
+			result = nil
+		}
+	}()
+
+	value, err := def.Resolve(ResolveParams{Context: ctx, Source: source, Args: args})
+	if err != nil {
+		e.addError(path, err)
+		return nil
+	}
+
+	var selections []selection
+	for _, f := range cf.fields {
+		selections = append(selections, f.selectionSet...)
+	}
+	return e.completeValue(ctx, def.Type, selections, value, path)
+}
+
+func (e *executor) completeValue(ctx context.Context, obj *Object, selections []selection, value interface{}, path []interface{}) interface{} {
+	if isNil(value) {
+		return nil
+	}
+	if obj == nil {
+		return value
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice {
+		items := make([]interface{}, rv.Len())
+		var wg sync.WaitGroup
+		for i := range rv.Len() {
+			e.spawn(&wg, func() {
+				items[i] = e.completeValue(ctx, obj, selections, rv.Index(i).Interface(), appendPath(path, i))
+			})
+		}
+		wg.Wait()
+		return items
+	}
+
+	return e.executeSelection(ctx, obj, value, selections, path, false)
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func appendPath(path []interface{}, elem interface{}) []interface{} {
+	p := make([]interface{}, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, elem)
+}
+
+// orderedMap keeps response keys in selection order when encoded as JSON
+type orderedMap struct {
+	keys   []string
+	values []interface{}
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
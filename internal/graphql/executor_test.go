@@ -0,0 +1,336 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type testMovie struct {
+	ID    int
+	Title string
+}
+
+// newTestSchema serves a movie list, a movie by ID and a mutation that
+// records the order its calls run in
+func newTestSchema(calls *[]string) *Schema {
+	movies := []*testMovie{{1, "Alien"}, {2, "Aliens"}, {3, "Heat"}}
+
+	movieType := &Object{Name: "Movie"}
+	movieType.Fields = Fields{
+		"id":    {Scalar: "Int", Resolve: func(p ResolveParams) (interface{}, error) { return p.Source.(*testMovie).ID, nil }},
+		"title": {Resolve: func(p ResolveParams) (interface{}, error) { return p.Source.(*testMovie).Title, nil }},
+		"similar": {Type: movieType, List: true, ListSize: 10, Resolve: func(p ResolveParams) (interface{}, error) {
+			return movies, nil
+		}},
+		"sequel": {Type: movieType, Resolve: func(p ResolveParams) (interface{}, error) {
+			return nil, nil
+		}},
+		"broken": {Resolve: func(p ResolveParams) (interface{}, error) {
+			return nil, errors.New("upstream unavailable")
+		}},
+		"panics": {Resolve: func(p ResolveParams) (interface{}, error) {
+			panic("boom")
+		}},
+	}
+
+	return &Schema{
+		Query: &Object{Name: "Query", Fields: Fields{
+			"movies": {Type: movieType, List: true, ListSize: 20, Resolve: func(p ResolveParams) (interface{}, error) {
+				return movies, nil
+			}},
+			"movie": {
+				Type: movieType,
+				Args: map[string]*Argument{"id": {Type: "Int", Required: true}},
+				Resolve: func(p ResolveParams) (interface{}, error) {
+					id, err := p.Args.RequireInt("id")
+					if err != nil {
+						return nil, err
+					}
+					for _, m := range movies {
+						if m.ID == id {
+							return m, nil
+						}
+					}
+					return nil, nil
+				},
+			},
+			"greeting": {
+				Args: map[string]*Argument{"name": {Default: "world"}},
+				Resolve: func(p ResolveParams) (interface{}, error) {
+					name, _ := p.Args.String("name")
+					return "hello " + name, nil
+				},
+			},
+		}},
+		Mutation: &Object{Name: "Mutation", Fields: Fields{
+			"record": {
+				Args: map[string]*Argument{"name": {Required: true}},
+				Resolve: func(p ResolveParams) (interface{}, error) {
+					name, _ := p.Args.String("name")
+					*calls = append(*calls, name)
+					return name, nil
+				},
+			},
+		}},
+	}
+}
+
+// execute runs a request and returns the response as JSON
+func execute(t *testing.T, s *Schema, req Request) string {
+	t.Helper()
+	data, err := json.Marshal(s.Execute(context.Background(), req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExecute(t *testing.T) {
+	s := newTestSchema(nil)
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			"aliases keep selection order",
+			Request{Query: `{ b: movie(id: 2) { title id } a: movie(id: 1) { title } }`},
+			`{"data":{"b":{"title":"Aliens","id":2},"a":{"title":"Alien"}}}`,
+		},
+		{
+			"lists and nulls",
+			Request{Query: `{ movies { id sequel { id } } }`},
+			`{"data":{"movies":[{"id":1,"sequel":null},{"id":2,"sequel":null},{"id":3,"sequel":null}]}}`,
+		},
+		{
+			"variables and argument defaults",
+			Request{Query: `query ($id: Int!, $name: String) { movie(id: $id) { title } greeting(name: $name) }`, Variables: map[string]interface{}{"id": 3.0}},
+			`{"data":{"movie":{"title":"Heat"},"greeting":"hello world"}}`,
+		},
+		{
+			"variable defaults",
+			Request{Query: `query ($name: String = "you") { greeting(name: $name) }`},
+			`{"data":{"greeting":"hello you"}}`,
+		},
+		{
+			"fragments merge fields",
+			Request{Query: `{ movie(id: 1) { ...Title ... on Movie { id } title } } fragment Title on Movie { title }`},
+			`{"data":{"movie":{"title":"Alien","id":1}}}`,
+		},
+		{
+			"skip and include",
+			Request{Query: `query ($full: Boolean!) { movie(id: 1) { id title @include(if: $full) ... @skip(if: true) { similar { id } } } }`, Variables: map[string]interface{}{"full": false}},
+			`{"data":{"movie":{"id":1}}}`,
+		},
+		{
+			"typename",
+			Request{Query: `{ __typename movie(id: 1) { __typename } }`},
+			`{"data":{"__typename":"Query","movie":{"__typename":"Movie"}}}`,
+		},
+		{
+			"operation name picks the operation",
+			Request{Query: `query A { greeting } query B { movie(id: 2) { id } }`, OperationName: "B"},
+			`{"data":{"movie":{"id":2}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execute(t, s, tt.req); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteRequestErrors(t *testing.T) {
+	s := newTestSchema(nil)
+	tests := []struct {
+		req  Request
+		want string
+	}{
+		{Request{Query: `{ movie(id: 1) { title }`}, "syntax error"},
+		{Request{Query: `{ nope }`}, `cannot query field \"nope\" on type \"Query\"`},
+		{Request{Query: `{ movie(id: 1, lang: "en") { id } }`}, `unknown argument \"lang\"`},
+		{Request{Query: `{ movie { id } }`}, `requires argument \"id\"`},
+		{Request{Query: `query ($id: Int) { movie(id: $id) { id } }`}, `requires argument \"id\"`},
+		{Request{Query: `query ($id: Int!) { movie(id: $id) { id } }`}, `variable $id of type Int! is required`},
+		{Request{Query: `{ greeting { x } }`}, `is a scalar and must not have a selection`},
+		{Request{Query: `{ movie(id: 1) }`}, `must have a selection of subfields`},
+		{Request{Query: `{ __typename { x } }`}, `must not have a selection`},
+		{Request{Query: `{ movie(id: 1) { ...Missing } }`}, `unknown fragment \"Missing\"`},
+		{Request{Query: `{ movie(id: 1) { ...A } } fragment A on Movie { ...B } fragment B on Movie { ...A }`}, `spreads itself`},
+		{Request{Query: `{ ...F } fragment F on Movie { id }`}, `cannot be spread on type \"Query\"`},
+		{Request{Query: `{ ... on Movie { id } }`}, `cannot be used on type \"Query\"`},
+		{Request{Query: `query A { greeting } query B { greeting }`}, `operationName is required`},
+		{Request{Query: `{ greeting }`, OperationName: "C"}, `unknown operation \"C\"`},
+	}
+	for _, tt := range tests {
+		got := execute(t, s, tt.req)
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s\ngot  %s\nwant it to mention %s", tt.req.Query, got, tt.want)
+		}
+		if strings.Contains(got, `"data"`) {
+			t.Errorf("%s\ngot data for a request that failed validation: %s", tt.req.Query, got)
+		}
+	}
+
+	noMutations := &Schema{Query: s.Query}
+	if got := execute(t, noMutations, Request{Query: `mutation { record(name: "a") }`}); !strings.Contains(got, "does not support mutation operations") {
+		t.Errorf("mutation without a Mutation type: got %s", got)
+	}
+}
+
+func TestExecuteFieldErrors(t *testing.T) {
+	got := execute(t, newTestSchema(nil), Request{Query: `{ movie(id: 1) { title broken } movies { panics } }`})
+
+	var resp struct {
+		Data   map[string]interface{} `json:"data"`
+		Errors []*Error               `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(got), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failing field is null and reported with its path; its siblings still resolve
+	movie := resp.Data["movie"].(map[string]interface{})
+	if movie["title"] != "Alien" || movie["broken"] != nil {
+		t.Errorf("movie = %v, want title with a null broken field", movie)
+	}
+	if len(resp.Errors) != 4 {
+		t.Fatalf("got %d errors, want 4: %s", len(resp.Errors), got)
+	}
+	var paths []string
+	for _, e := range resp.Errors {
+		data, _ := json.Marshal(e.Path)
+		paths = append(paths, string(data))
+		if e.Path[0] == "movie" && e.Message != "upstream unavailable" {
+			t.Errorf("resolver error message = %q", e.Message)
+		}
+		if e.Path[0] == "movies" && !strings.Contains(e.Message, `internal error resolving "panics": boom`) {
+			t.Errorf("panic error message = %q", e.Message)
+		}
+	}
+	for _, want := range []string{`["movie","broken"]`, `["movies",0,"panics"]`, `["movies",2,"panics"]`} {
+		if !strings.Contains(strings.Join(paths, " "), want) {
+			t.Errorf("error paths %v are missing %s", paths, want)
+		}
+	}
+}
+
+func TestExecuteMutationsRunInOrder(t *testing.T) {
+	var calls []string
+	s := newTestSchema(&calls)
+	for range 20 {
+		calls = calls[:0]
+		execute(t, s, Request{Query: `mutation { a: record(name: "a") b: record(name: "b") c: record(name: "c") d: record(name: "d") }`})
+		if got := strings.Join(calls, ""); got != "abcd" {
+			t.Fatalf("mutations ran in order %q, want abcd", got)
+		}
+	}
+}
+
+func TestExecuteDepthLimit(t *testing.T) {
+	s := newTestSchema(nil)
+	s.MaxDepth = 3
+
+	ok := execute(t, s, Request{Query: `{ movie(id: 1) { similar { id } } }`})
+	if strings.Contains(ok, "errors") {
+		t.Errorf("depth 3 was rejected: %s", ok)
+	}
+
+	for _, query := range []string{
+		`{ movie(id: 1) { similar { similar { id } } } }`,
+		// Fragments count towards the depth of where they're spread
+		`{ movie(id: 1) { ...Deep } } fragment Deep on Movie { similar { sequel { id } } }`,
+	} {
+		got := execute(t, s, Request{Query: query})
+		if !strings.Contains(got, "query depth 4 exceeds the maximum of 3") || strings.Contains(got, `"data"`) {
+			t.Errorf("%s\ngot %s, want a depth error", query, got)
+		}
+	}
+
+	// The default allows ordinary nesting but not unbounded recursion
+	s.MaxDepth = 0
+	deep := "{ movie(id: 1) { " + strings.Repeat("similar { ", 12) + "id" + strings.Repeat(" }", 12) + " } }"
+	if got := execute(t, s, Request{Query: deep}); !strings.Contains(got, "exceeds the maximum of 10") {
+		t.Errorf("depth 14 with the default limit: got %s", got)
+	}
+}
+
+func TestExecuteComplexityLimit(t *testing.T) {
+	s := newTestSchema(nil)
+	s.MaxComplexity = 100
+
+	// movies: 1 + 20 × (id + title) = 41
+	if got := execute(t, s, Request{Query: `{ movies { id title } }`}); strings.Contains(got, "errors") {
+		t.Errorf("complexity 41 was rejected: %s", got)
+	}
+
+	// movies: 1 + 20 × (id + similar: 1 + 10 × id) = 241
+	got := execute(t, s, Request{Query: `{ movies { id similar { id } } }`})
+	if !strings.Contains(got, "query complexity exceeds the maximum of 100") || strings.Contains(got, `"data"`) {
+		t.Errorf("complexity 241: got %s, want a complexity error", got)
+	}
+
+	// Aliases multiply work too
+	got = execute(t, s, Request{Query: `{ a: movies { id title } b: movies { id title } c: movies { id title } }`})
+	if !strings.Contains(got, "query complexity exceeds") {
+		t.Errorf("three aliased lists: got %s, want a complexity error", got)
+	}
+}
+
+func TestExecuteFragmentFanOutIsRejectedQuickly(t *testing.T) {
+	// Each fragment spreads the next twice, so expanding F0 naively would
+	// visit 2^40 fields
+	var sb strings.Builder
+	sb.WriteString("{ movie(id: 1) { ...F0 } }")
+	for i := range 40 {
+		sb.WriteString(fmt.Sprintf(" fragment F%d on Movie { ...F%d a%d: similar { ...F%d } }", i, i+1, i, i+1))
+	}
+	sb.WriteString(" fragment F40 on Movie { id }")
+
+	got := execute(t, newTestSchema(nil), Request{Query: sb.String()})
+	if !strings.Contains(got, "exceeds the maximum") {
+		t.Errorf("got %s, want a limit error", got)
+	}
+}
+
+func TestExecuteBoundsWorkers(t *testing.T) {
+	var active, peak atomic.Int32
+	items := make([]int, 200)
+
+	itemType := &Object{Name: "Item", Fields: Fields{
+		"value": {Resolve: func(p ResolveParams) (interface{}, error) {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			return p.Source, nil
+		}},
+	}}
+	s := &Schema{
+		MaxWorkers: 4,
+		Query: &Object{Name: "Query", Fields: Fields{
+			"items": {Type: itemType, Resolve: func(p ResolveParams) (interface{}, error) { return items, nil }},
+		}},
+	}
+
+	resp := s.Execute(context.Background(), Request{Query: `{ items { value } }`})
+	if len(resp.Errors) > 0 || len(resp.Data.(*orderedMap).values[0].([]interface{})) != len(items) {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	// The workers, plus the request's own goroutine once they're all busy
+	if got := peak.Load(); got > 4+1 {
+		t.Errorf("%d resolvers ran at once, want at most 5", got)
+	}
+}
@@ -0,0 +1,340 @@
+package graphql
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxTypeNesting bounds how deeply fields, inputFields, interfaces and
+// possibleTypes may nest in an introspection query. Each of them returns a
+// list of types or fields, so nesting multiplies the response, while the
+// standard introspection query nests them once.
+const maxTypeNesting = 2
+
+// typeRef is an introspection __Type: a named type, or a list or non-null
+// wrapper of one
+type typeRef struct {
+	kind       string // SCALAR, OBJECT, ENUM, LIST or NON_NULL
+	name       string
+	fields     []*fieldRef
+	enumValues []string
+	ofType     *typeRef
+}
+
+type fieldRef struct {
+	name string
+	args []*inputValue
+	typ  *typeRef
+}
+
+type inputValue struct {
+	name         string
+	typ          *typeRef
+	defaultValue *string // as a GraphQL literal
+}
+
+type directiveRef struct {
+	name      string
+	locations []string
+	args      []*inputValue
+}
+
+// schemaRef is an introspection __Schema
+type schemaRef struct {
+	types           []*typeRef // named types, by name
+	byName          map[string]*typeRef
+	query, mutation *typeRef
+	directives      []*directiveRef
+}
+
+var builtinScalars = []string{"Boolean", "Float", "ID", "Int", "String"}
+
+// metaEnums are the enum types introspection itself uses
+var metaEnums = map[string][]string{
+	"__TypeKind": {"SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL"},
+	"__DirectiveLocation": {
+		"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD",
+		"INLINE_FRAGMENT", "VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION",
+		"ARGUMENT_DEFINITION", "INTERFACE", "UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION",
+	},
+}
+
+var schemaMeta, typeMeta = newMetaTypes()
+
+// newMetaTypes defines __Schema, __Type and the types they refer to as
+// ordinary objects over schemaRef and its parts
+func newMetaTypes() (*Object, *Object) {
+	noArgs := map[string]*Argument{"includeDeprecated": {Type: "Boolean", Default: false}}
+	none := func(ResolveParams) (interface{}, error) { return nil, nil }
+	no := func(ResolveParams) (interface{}, error) { return false, nil }
+
+	schemaType := &Object{Name: "__Schema"}
+	typeType := &Object{Name: "__Type"}
+	fieldType := &Object{Name: "__Field"}
+	inputValueType := &Object{Name: "__InputValue"}
+	enumValueType := &Object{Name: "__EnumValue"}
+	directiveType := &Object{Name: "__Directive"}
+
+	schemaType.Fields = Fields{
+		"description":      {Resolve: none},
+		"types":            {Type: typeType, List: true, Resolve: metaProp(func(s *schemaRef) interface{} { return s.types })},
+		"queryType":        {Type: typeType, Resolve: metaProp(func(s *schemaRef) interface{} { return s.query })},
+		"mutationType":     {Type: typeType, Resolve: metaProp(func(s *schemaRef) interface{} { return s.mutation })},
+		"subscriptionType": {Type: typeType, Resolve: none},
+		"directives":       {Type: directiveType, List: true, Resolve: metaProp(func(s *schemaRef) interface{} { return s.directives })},
+	}
+
+	typeType.Fields = Fields{
+		"kind": {Scalar: "__TypeKind", Resolve: metaProp(func(t *typeRef) interface{} { return t.kind })},
+		"name": {Resolve: metaProp(func(t *typeRef) interface{} {
+			if t.name == "" {
+				return nil
+			}
+			return t.name
+		})},
+		"description":    {Resolve: none},
+		"specifiedByURL": {Resolve: none},
+		"fields": {Type: fieldType, List: true, Args: noArgs, Resolve: metaProp(func(t *typeRef) interface{} {
+			if t.kind != "OBJECT" {
+				return nil
+			}
+			return t.fields
+		})},
+		"interfaces": {Type: typeType, List: true, Resolve: metaProp(func(t *typeRef) interface{} {
+			if t.kind != "OBJECT" {
+				return nil
+			}
+			return []*typeRef{}
+		})},
+		"possibleTypes": {Type: typeType, List: true, Resolve: none},
+		"enumValues": {Type: enumValueType, List: true, Args: noArgs, Resolve: metaProp(func(t *typeRef) interface{} {
+			if t.kind != "ENUM" {
+				return nil
+			}
+			return t.enumValues
+		})},
+		"inputFields": {Type: inputValueType, List: true, Args: noArgs, Resolve: none},
+		"ofType":      {Type: typeType, Resolve: metaProp(func(t *typeRef) interface{} { return t.ofType })},
+		"isOneOf":     {Scalar: "Boolean", Resolve: no},
+	}
+
+	fieldType.Fields = Fields{
+		"name":              {Resolve: metaProp(func(f *fieldRef) interface{} { return f.name })},
+		"description":       {Resolve: none},
+		"args":              {Type: inputValueType, List: true, Args: noArgs, Resolve: metaProp(func(f *fieldRef) interface{} { return f.args })},
+		"type":              {Type: typeType, Resolve: metaProp(func(f *fieldRef) interface{} { return f.typ })},
+		"isDeprecated":      {Scalar: "Boolean", Resolve: no},
+		"deprecationReason": {Resolve: none},
+	}
+
+	inputValueType.Fields = Fields{
+		"name":              {Resolve: metaProp(func(v *inputValue) interface{} { return v.name })},
+		"description":       {Resolve: none},
+		"type":              {Type: typeType, Resolve: metaProp(func(v *inputValue) interface{} { return v.typ })},
+		"defaultValue":      {Resolve: metaProp(func(v *inputValue) interface{} { return v.defaultValue })},
+		"isDeprecated":      {Scalar: "Boolean", Resolve: no},
+		"deprecationReason": {Resolve: none},
+	}
+
+	enumValueType.Fields = Fields{
+		"name":              {Resolve: metaProp(func(name string) interface{} { return name })},
+		"description":       {Resolve: none},
+		"isDeprecated":      {Scalar: "Boolean", Resolve: no},
+		"deprecationReason": {Resolve: none},
+	}
+
+	directiveType.Fields = Fields{
+		"name":         {Resolve: metaProp(func(d *directiveRef) interface{} { return d.name })},
+		"description":  {Resolve: none},
+		"locations":    {Scalar: "__DirectiveLocation", List: true, Resolve: metaProp(func(d *directiveRef) interface{} { return d.locations })},
+		"args":         {Type: inputValueType, List: true, Args: noArgs, Resolve: metaProp(func(d *directiveRef) interface{} { return d.args })},
+		"isRepeatable": {Scalar: "Boolean", Resolve: no},
+	}
+
+	return schemaType, typeType
+}
+
+func metaProp[T any](get func(T) interface{}) ResolveFunc {
+	return func(p ResolveParams) (interface{}, error) {
+		return get(p.Source.(T)), nil
+	}
+}
+
+// withIntrospection returns root with the __schema and __type fields added.
+// The schema is described on first use, once per request.
+func (s *Schema) withIntrospection(root *Object) *Object {
+	describe := sync.OnceValue(s.describe)
+
+	fields := make(Fields, len(root.Fields)+2)
+	maps.Copy(fields, root.Fields)
+	fields["__schema"] = &Field{
+		Type:          schemaMeta,
+		introspection: true,
+		Resolve: func(p ResolveParams) (interface{}, error) {
+			return describe(), nil
+		},
+	}
+	fields["__type"] = &Field{
+		Type:          typeMeta,
+		Args:          map[string]*Argument{"name": {Type: "String", Required: true}},
+		introspection: true,
+		Resolve: func(p ResolveParams) (interface{}, error) {
+			name, err := p.Args.RequireString("name")
+			if err != nil {
+				return nil, err
+			}
+			return describe().byName[name], nil
+		},
+	}
+	return &Object{Name: root.Name, Fields: fields}
+}
+
+// describe builds the introspection view of every type reachable from the
+// schema's roots. Fields are all reported as nullable, and required
+// arguments as non-null.
+func (s *Schema) describe() *schemaRef {
+	d := &schemaRef{byName: map[string]*typeRef{}}
+	for _, name := range builtinScalars {
+		d.named(name)
+	}
+	for _, obj := range []*Object{schemaMeta, typeMeta} {
+		d.object(obj)
+	}
+	if s.Query != nil {
+		d.query = d.object(s.Query)
+	}
+	if s.Mutation != nil {
+		d.mutation = d.object(s.Mutation)
+	}
+
+	ifArg := []*inputValue{{name: "if", typ: &typeRef{kind: "NON_NULL", ofType: d.named("Boolean")}}}
+	locations := []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"}
+	d.directives = []*directiveRef{
+		{name: "include", locations: locations, args: ifArg},
+		{name: "skip", locations: locations, args: ifArg},
+	}
+
+	for _, t := range d.byName {
+		d.types = append(d.types, t)
+	}
+	sort.Slice(d.types, func(i, j int) bool { return d.types[i].name < d.types[j].name })
+	return d
+}
+
+// object describes obj and, through its fields, every object it refers to
+func (d *schemaRef) object(obj *Object) *typeRef {
+	if t, ok := d.byName[obj.Name]; ok {
+		return t
+	}
+	t := &typeRef{kind: "OBJECT", name: obj.Name}
+	d.byName[obj.Name] = t
+
+	for name, f := range obj.Fields {
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		typ := d.named(f.Scalar)
+		if f.Type != nil {
+			typ = d.object(f.Type)
+		}
+		if f.List {
+			typ = &typeRef{kind: "LIST", ofType: typ}
+		}
+
+		ref := &fieldRef{name: name, typ: typ}
+		for argName, arg := range f.Args {
+			argType := d.named(arg.typeName())
+			if arg.Required {
+				argType = &typeRef{kind: "NON_NULL", ofType: argType}
+			}
+			ref.args = append(ref.args, &inputValue{name: argName, typ: argType, defaultValue: graphQLLiteral(arg.Default)})
+		}
+		sort.Slice(ref.args, func(i, j int) bool { return ref.args[i].name < ref.args[j].name })
+		t.fields = append(t.fields, ref)
+	}
+	sort.Slice(t.fields, func(i, j int) bool { return t.fields[i].name < t.fields[j].name })
+	return t
+}
+
+// named returns the scalar or enum type called name, String if it's empty
+func (d *schemaRef) named(name string) *typeRef {
+	if name == "" {
+		name = "String"
+	}
+	if t, ok := d.byName[name]; ok {
+		return t
+	}
+	t := &typeRef{kind: "SCALAR", name: name}
+	if values, ok := metaEnums[name]; ok {
+		t.kind = "ENUM"
+		t.enumValues = values
+	}
+	d.byName[name] = t
+	return t
+}
+
+func (a *Argument) typeName() string {
+	if a.Type != "" {
+		return a.Type
+	}
+	switch a.Default.(type) {
+	case int:
+		return "Int"
+	case float64:
+		return "Float"
+	case bool:
+		return "Boolean"
+	}
+	return "String"
+}
+
+// graphQLLiteral prints a default value as it would be written in a document
+func graphQLLiteral(v interface{}) *string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		s = strconv.Quote(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+	return &s
+}
+
+// typeNesting counts how deeply fields, inputFields, interfaces and
+// possibleTypes selections nest inside one another
+func (e *executor) typeNesting(selections []selection) int {
+	n := 0
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			inner := e.typeNesting(sel.selectionSet)
+			switch sel.name {
+			case "fields", "inputFields", "interfaces", "possibleTypes":
+				inner++
+			}
+			n = max(n, inner)
+		case *fragmentSpread:
+			frag, ok := e.doc.fragments[sel.name]
+			if !ok {
+				continue
+			}
+			nesting, seen := e.nestings[sel.name]
+			if !seen {
+				// A fragment that spreads itself is reported by validate
+				e.nestings[sel.name] = 0
+				nesting = e.typeNesting(frag.selectionSet)
+				e.nestings[sel.name] = nesting
+			}
+			n = max(n, nesting)
+		case *inlineFragment:
+			n = max(n, e.typeNesting(sel.selectionSet))
+		}
+	}
+	return n
+}
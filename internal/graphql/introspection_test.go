@@ -0,0 +1,189 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// introspectionQuery is the query GraphiQL and code generators send
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) {
+    name description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name
+    ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+type introspectedType struct {
+	Kind   string  `json:"kind"`
+	Name   *string `json:"name"`
+	Fields []struct {
+		Name string `json:"name"`
+		Args []struct {
+			Name         string           `json:"name"`
+			Type         introspectedType `json:"type"`
+			DefaultValue *string          `json:"defaultValue"`
+		} `json:"args"`
+		Type introspectedType `json:"type"`
+	} `json:"fields"`
+	EnumValues []struct {
+		Name string `json:"name"`
+	} `json:"enumValues"`
+	OfType *introspectedType `json:"ofType"`
+}
+
+// String prints a type reference as it's written in a document
+func (t introspectedType) String() string {
+	switch t.Kind {
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	}
+	return *t.Name
+}
+
+func TestIntrospectionQuery(t *testing.T) {
+	got := execute(t, newTestSchema(nil), Request{Query: introspectionQuery})
+	var resp struct {
+		Data struct {
+			Schema struct {
+				QueryType    struct{ Name string } `json:"queryType"`
+				MutationType struct{ Name string } `json:"mutationType"`
+				Types        []introspectedType    `json:"types"`
+				Directives   []struct {
+					Name      string   `json:"name"`
+					Locations []string `json:"locations"`
+				} `json:"directives"`
+			} `json:"__schema"`
+		} `json:"data"`
+		Errors []*Error `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(got), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("errors: %s", got)
+	}
+	schema := resp.Data.Schema
+	if schema.QueryType.Name != "Query" || schema.MutationType.Name != "Mutation" {
+		t.Errorf("roots = %+v %+v, want Query and Mutation", schema.QueryType, schema.MutationType)
+	}
+	if len(schema.Directives) != 2 || schema.Directives[0].Name != "include" || schema.Directives[1].Name != "skip" {
+		t.Errorf("directives = %+v, want include and skip", schema.Directives)
+	}
+
+	types := map[string]introspectedType{}
+	for _, typ := range schema.Types {
+		types[*typ.Name] = typ
+	}
+	for _, name := range []string{"Boolean", "Int", "String", "Movie", "Query", "Mutation", "__Schema", "__Type", "__Field", "__TypeKind"} {
+		if _, ok := types[name]; !ok {
+			t.Errorf("types are missing %s", name)
+		}
+	}
+	if kind := types["__TypeKind"]; kind.Kind != "ENUM" || len(kind.EnumValues) != 8 {
+		t.Errorf("__TypeKind = %+v, want an enum of 8 kinds", kind)
+	}
+
+	// Fields are sorted by name, with their types and arguments; the
+	// introspection fields themselves aren't listed
+	var fields []string
+	for _, f := range types["Query"].Fields {
+		var args []string
+		for _, a := range f.Args {
+			arg := a.Name + ": " + a.Type.String()
+			if a.DefaultValue != nil {
+				arg += " = " + *a.DefaultValue
+			}
+			args = append(args, arg)
+		}
+		fields = append(fields, f.Name+"("+strings.Join(args, ", ")+"): "+f.Type.String())
+	}
+	want := `greeting(name: String = "world"): String movie(id: Int!): Movie movies(): [Movie]`
+	if got := strings.Join(fields, " "); got != want {
+		t.Errorf("Query fields = %s\nwant %s", got, want)
+	}
+}
+
+func TestIntrospectionType(t *testing.T) {
+	s := newTestSchema(nil)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			`{ __type(name: "Movie") { kind name fields { name type { kind name ofType { name } } } } }`,
+			`{"data":{"__type":{"kind":"OBJECT","name":"Movie","fields":[` +
+				`{"name":"broken","type":{"kind":"SCALAR","name":"String","ofType":null}},` +
+				`{"name":"id","type":{"kind":"SCALAR","name":"Int","ofType":null}},` +
+				`{"name":"panics","type":{"kind":"SCALAR","name":"String","ofType":null}},` +
+				`{"name":"sequel","type":{"kind":"OBJECT","name":"Movie","ofType":null}},` +
+				`{"name":"similar","type":{"kind":"LIST","name":null,"ofType":{"name":"Movie"}}},` +
+				`{"name":"title","type":{"kind":"SCALAR","name":"String","ofType":null}}]}}}`,
+		},
+		{
+			`{ __type(name: "Nope") { name } }`,
+			`{"data":{"__type":null}}`,
+		},
+		{
+			`{ __type(name: "Int") { kind fields { name } } }`,
+			`{"data":{"__type":{"kind":"SCALAR","fields":null}}}`,
+		},
+	}
+	for _, tt := range tests {
+		if got := execute(t, s, Request{Query: tt.query}); got != tt.want {
+			t.Errorf("%s\ngot  %s\nwant %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIntrospectionLimits(t *testing.T) {
+	s := newTestSchema(nil)
+
+	// Type references nest beyond MaxDepth, since each level is one value
+	s.MaxDepth = 3
+	deep := `{ __type(name: "Query") { fields { type { ofType { ofType { ofType { name } } } } } } }`
+	if got := execute(t, s, Request{Query: deep}); strings.Contains(got, "errors") {
+		t.Errorf("nested ofType was rejected: %s", got)
+	}
+
+	for _, query := range []string{
+		`{ __schema { types { fields { type { fields { type { fields { name } } } } } } } }`,
+		`{ __type(name: "Query") { ...F } } fragment F on __Type { fields { type { interfaces { fields { name } } } } }`,
+	} {
+		got := execute(t, s, Request{Query: query})
+		if !strings.Contains(got, "more than the maximum of 2") || strings.Contains(got, `"data"`) {
+			t.Errorf("%s\ngot %s, want a nesting error", query, got)
+		}
+	}
+
+	// Mutations can't introspect
+	got := execute(t, s, Request{Query: `mutation { __schema { types { name } } }`})
+	if !strings.Contains(got, `cannot query field \"__schema\" on type \"Mutation\"`) {
+		t.Errorf("mutation introspection: got %s", got)
+	}
+}
@@ -0,0 +1,220 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// batchWait is how long a batch stays open for more keys after its first
+	batchWait = 2 * time.Millisecond
+
+	// maxBatch dispatches a batch early once it holds this many keys
+	maxBatch = 50
+)
+
+// Loader deduplicates, batches and throttles upstream fetches for the
+// lifetime of one request. Resolvers for sibling list elements run
+// concurrently, so N movies asking for the same credits or OMDB record
+// trigger a single fetch; keys asked for under the same batch name within a
+// few milliseconds are handed to one BatchFunc call; and no more than the
+// configured number of loads, single fetches or batches, are in flight at once.
+type Loader struct {
+	mu      sync.Mutex
+	calls   map[string]*loaderCall
+	batches map[string]interface{} // open *loaderBatch[K] by name
+	sem     chan struct{}
+	wait    time.Duration
+}
+
+type loaderCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// loaderBatch collects keys until it is dispatched
+type loaderBatch[K comparable] struct {
+	keys     []K
+	calls    []*loaderCall
+	dispatch func()
+	timer    *time.Timer
+}
+
+type loaderKey struct{}
+
+func NewLoader(maxConcurrent int) *Loader {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &Loader{
+		calls:   make(map[string]*loaderCall),
+		batches: make(map[string]interface{}),
+		sem:     make(chan struct{}, maxConcurrent),
+		wait:    batchWait,
+	}
+}
+
+// WithLoader attaches a request-scoped loader to ctx
+func WithLoader(ctx context.Context, l *Loader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+// Load returns the value for key, calling fetch at most once per request.
+// Without a loader in ctx it simply calls fetch.
+func Load[T any](ctx context.Context, key string, fetch func() (T, error)) (T, error) {
+	l, ok := ctx.Value(loaderKey{}).(*Loader)
+	if !ok {
+		return fetch()
+	}
+
+	l.mu.Lock()
+	if call, exists := l.calls[key]; exists {
+		l.mu.Unlock()
+		return loaderResult[T](call)
+	}
+	call := &loaderCall{done: make(chan struct{})}
+	l.calls[key] = call
+	l.mu.Unlock()
+
+	l.run(key, []*loaderCall{call}, func() {
+		call.value, call.err = fetch()
+	})
+	return loaderResult[T](call)
+}
+
+// BatchFunc fetches the values for several keys at once. Both slices are in
+// the order of keys; errs may be nil when every key succeeded.
+type BatchFunc[K comparable, V any] func(keys []K) (values []V, errs []error)
+
+// LoadBatch returns the value for key, fetching it together with the other
+// keys asked for under name while the batch is open. Each key is fetched at
+// most once per request. Without a loader in ctx it calls fetch for key alone.
+func LoadBatch[K comparable, V any](ctx context.Context, name string, key K, fetch BatchFunc[K, V]) (V, error) {
+	l, ok := ctx.Value(loaderKey{}).(*Loader)
+	if !ok {
+		values, errs := fetch([]K{key})
+		return batchResult(values, errs, 0)
+	}
+
+	cacheKey := fmt.Sprintf("%s\x00%v", name, key)
+	l.mu.Lock()
+	if call, exists := l.calls[cacheKey]; exists {
+		l.mu.Unlock()
+		return loaderResult[V](call)
+	}
+	call := &loaderCall{done: make(chan struct{})}
+	l.calls[cacheKey] = call
+
+	b, open := l.batches[name].(*loaderBatch[K])
+	if !open {
+		b = &loaderBatch[K]{}
+		b.dispatch = sync.OnceFunc(func() {
+			l.mu.Lock()
+			if l.batches[name] == b {
+				delete(l.batches, name)
+			}
+			l.mu.Unlock()
+			l.run(name, b.calls, func() { fetchBatch(fetch, b.keys, b.calls) })
+		})
+		l.batches[name] = b
+		b.timer = time.AfterFunc(l.wait, b.dispatch)
+	}
+	b.keys = append(b.keys, key)
+	b.calls = append(b.calls, call)
+	full := len(b.keys) >= maxBatch
+	if full {
+		// Later keys start a new batch
+		delete(l.batches, name)
+	}
+	l.mu.Unlock()
+
+	if full {
+		b.timer.Stop()
+		b.dispatch()
+	}
+	return loaderResult[V](call)
+}
+
+// fetchBatch calls fetch for a dispatched batch and hands each call its result
+func fetchBatch[K comparable, V any](fetch BatchFunc[K, V], keys []K, calls []*loaderCall) {
+	values, errs := fetch(keys)
+	for i, call := range calls {
+		call.value, call.err = batchResult(values, errs, i)
+	}
+}
+
+func batchResult[V any](values []V, errs []error, i int) (V, error) {
+	var v V
+	if i < len(errs) && errs[i] != nil {
+		return v, errs[i]
+	}
+	if i >= len(values) {
+		return v, fmt.Errorf("batch returned %d values for key %d", len(values), i)
+	}
+	return values[i], nil
+}
+
+// run calls fetch in a throttle slot and releases the calls' waiters, even if
+// fetch panics
+func (l *Loader) run(name string, calls []*loaderCall, fetch func()) {
+	l.sem <- struct{}{}
+	defer func() {
+		<-l.sem
+		if r := recover(); r != nil {
+			for _, call := range calls {
+				call.value, call.err = nil, fmt.Errorf("loading %s: %v", name, r)
+			}
+		}
+		for _, call := range calls {
+			close(call.done)
+		}
+	}()
+	fetch()
+}
+
+func loaderResult[T any](call *loaderCall) (T, error) {
+	<-call.done
+	v, _ := call.value.(T)
+	return v, call.err
+}
+
+// Each adapts a single-key fetch to a BatchFunc, for upstreams without a
+// multi-key endpoint. A batch's keys are fetched by up to workers goroutines.
+func Each[K comparable, V any](workers int, fetch func(K) (V, error)) BatchFunc[K, V] {
+	return func(keys []K) ([]V, []error) {
+		values := make([]V, len(keys))
+		errs := make([]error, len(keys))
+
+		jobs := make(chan int)
+		var wg sync.WaitGroup
+		for range min(max(workers, 1), len(keys)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					values[i], errs[i] = fetchOne(keys[i], fetch)
+				}
+			}()
+		}
+		for i := range keys {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		return values, errs
+	}
+}
+
+// fetchOne calls fetch, turning a panic into an error; it runs on a worker
+// goroutine, where Loader.run can't recover it
+func fetchOne[K comparable, V any](key K, fetch func(K) (V, error)) (v V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("loading %v: %v", key, r)
+		}
+	}()
+	return fetch(key)
+}
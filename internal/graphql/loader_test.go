@@ -0,0 +1,232 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadDeduplicates(t *testing.T) {
+	ctx := WithLoader(context.Background(), NewLoader(4))
+	var calls atomic.Int32
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := Load(ctx, "credits:603", func() (string, error) {
+				calls.Add(1)
+				time.Sleep(5 * time.Millisecond)
+				return "cast", nil
+			})
+			if v != "cast" || err != nil {
+				t.Errorf("Load = %q, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fetch ran %d times, want 1", n)
+	}
+}
+
+func TestLoadWithoutLoader(t *testing.T) {
+	v, err := Load(context.Background(), "k", func() (int, error) { return 7, nil })
+	if v != 7 || err != nil {
+		t.Errorf("Load = %d, %v, want 7", v, err)
+	}
+
+	double := func(keys []int) ([]int, []error) {
+		values := make([]int, len(keys))
+		for i, k := range keys {
+			values[i] = k * 2
+		}
+		return values, nil
+	}
+	if v, err := LoadBatch(context.Background(), "double", 4, double); v != 8 || err != nil {
+		t.Errorf("LoadBatch = %d, %v, want 8", v, err)
+	}
+}
+
+func TestLoadRecoversPanics(t *testing.T) {
+	ctx := WithLoader(context.Background(), NewLoader(1))
+	_, err := Load(ctx, "k", func() (int, error) { panic("boom") })
+	if err == nil || err.Error() != "loading k: boom" {
+		t.Errorf("err = %v, want the panic as an error", err)
+	}
+
+	// The throttle slot was released
+	if v, err := Load(ctx, "other", func() (int, error) { return 1, nil }); v != 1 || err != nil {
+		t.Errorf("Load after a panic = %d, %v", v, err)
+	}
+}
+
+func TestLoadBatchCollectsKeys(t *testing.T) {
+	l := NewLoader(4)
+	l.wait = time.Hour // only a full batch dispatches
+	ctx := WithLoader(context.Background(), l)
+
+	var mu sync.Mutex
+	var batches [][]int
+	fetch := func(keys []int) ([]string, []error) {
+		mu.Lock()
+		batches = append(batches, slices.Clone(keys))
+		mu.Unlock()
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			values[i] = fmt.Sprintf("movie %d", k)
+		}
+		return values, nil
+	}
+
+	// Every key is asked for twice; duplicates wait on the first request
+	var wg sync.WaitGroup
+	for i := range 2 * maxBatch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := i % maxBatch
+			v, err := LoadBatch(ctx, "movie", id, fetch)
+			if want := fmt.Sprintf("movie %d", id); v != want || err != nil {
+				t.Errorf("LoadBatch(%d) = %q, %v, want %q", id, v, err, want)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(batches) != 1 || len(batches[0]) != maxBatch {
+		t.Fatalf("got batches %v, want one batch of %d keys", batches, maxBatch)
+	}
+	slices.Sort(batches[0])
+	for i, k := range batches[0] {
+		if k != i {
+			t.Fatalf("batch keys = %v, want 0 to %d once each", batches[0], maxBatch-1)
+		}
+	}
+}
+
+func TestLoadBatchDispatchesAfterWait(t *testing.T) {
+	ctx := WithLoader(context.Background(), NewLoader(4))
+	var calls atomic.Int32
+	fetch := func(keys []int) ([]int, []error) {
+		calls.Add(1)
+		return keys, nil
+	}
+
+	// A lone key isn't held up waiting for a full batch
+	start := time.Now()
+	if v, err := LoadBatch(ctx, "ids", 42, fetch); v != 42 || err != nil {
+		t.Errorf("LoadBatch = %d, %v, want 42", v, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("LoadBatch took %s", elapsed)
+	}
+
+	// A new batch opens for keys asked for after dispatch; cached keys aren't refetched
+	if v, _ := LoadBatch(ctx, "ids", 43, fetch); v != 43 {
+		t.Errorf("LoadBatch(43) = %d", v)
+	}
+	if v, _ := LoadBatch(ctx, "ids", 42, fetch); v != 42 {
+		t.Errorf("LoadBatch(42) again = %d", v)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fetch ran %d times, want 2", n)
+	}
+}
+
+func TestLoadBatchErrors(t *testing.T) {
+	l := NewLoader(4)
+	l.wait = 20 * time.Millisecond
+	ctx := WithLoader(context.Background(), l)
+
+	errNotFound := errors.New("not found")
+	fetch := func(keys []int) ([]string, []error) {
+		values := make([]string, len(keys))
+		errs := make([]error, len(keys))
+		for i, k := range keys {
+			if k == 0 {
+				panic("zero")
+			}
+			if k < 0 {
+				errs[i] = errNotFound
+				continue
+			}
+			values[i] = "ok"
+		}
+		return values, errs
+	}
+	short := func(keys []int) ([]string, []error) { return nil, nil }
+
+	// A failing key doesn't fail the rest of its batch
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	for i, id := range []int{1, -1} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, results[i] = LoadBatch(ctx, "ids", id, fetch)
+		}()
+	}
+	wg.Wait()
+	if results[0] != nil || !errors.Is(results[1], errNotFound) {
+		t.Errorf("errors = %v, want nil and %v", results, errNotFound)
+	}
+
+	if _, err := LoadBatch(ctx, "panics", 0, fetch); err == nil || err.Error() != "loading panics: zero" {
+		t.Errorf("panicking batch: err = %v", err)
+	}
+	if _, err := LoadBatch(ctx, "short", 1, short); err == nil {
+		t.Error("a batch returning too few values succeeded")
+	}
+}
+
+func TestEach(t *testing.T) {
+	var active, peak atomic.Int32
+	fetch := Each(3, func(id int) (int, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		switch id {
+		case 5:
+			return 0, errors.New("five")
+		case 6:
+			panic("six")
+		}
+		return id * 10, nil
+	})
+
+	keys := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	values, errs := fetch(keys)
+	for i, k := range keys {
+		switch k {
+		case 5:
+			if errs[i] == nil || errs[i].Error() != "five" {
+				t.Errorf("key 5: err = %v", errs[i])
+			}
+		case 6:
+			if errs[i] == nil || errs[i].Error() != "loading 6: six" {
+				t.Errorf("key 6: err = %v", errs[i])
+			}
+		default:
+			if values[i] != k*10 || errs[i] != nil {
+				t.Errorf("key %d = %d, %v", k, values[i], errs[i])
+			}
+		}
+	}
+	if n := peak.Load(); n > 3 {
+		t.Errorf("%d fetches ran at once, want at most 3", n)
+	}
+}
@@ -0,0 +1,552 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// document is a parsed GraphQL request document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind         string // "query" or "mutation"
+	name         string
+	variables    []*variableDef
+	directives   []*directive
+	selectionSet []selection
+}
+
+type variableDef struct {
+	name       string
+	typeName   string
+	nonNull    bool
+	defaultVal value
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selectionSet  []selection
+}
+
+// selection is one of *field, *fragmentSpread or *inlineFragment
+type selection interface{}
+
+type field struct {
+	alias        string
+	name         string
+	arguments    map[string]value
+	directives   []*directive
+	selectionSet []selection
+}
+
+// responseKey is the key the field's value is reported under
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selectionSet  []selection
+}
+
+type directive struct {
+	name      string
+	arguments map[string]value
+}
+
+// value is a literal from the document; variable references are resolved during execution
+type value interface{}
+
+type variableRef string
+
+type enumValue string
+
+// maxNesting bounds how deeply selection sets, list types and list or
+// object values may nest. The parser is recursive, so without it a long run
+// of "{" could exhaust the stack before Schema.MaxDepth is ever checked.
+const maxNesting = 64
+
+type parser struct {
+	lex   *lexer
+	tok   token
+	depth int // of the selection set, type or value being parsed
+}
+
+func parse(src string) (*document, error) {
+	p := &parser{lex: &lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunct, "{"):
+			sel, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selectionSet: sel})
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peek(tokenName, "fragment"):
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := doc.fragments[frag.name]; exists {
+				return nil, fmt.Errorf("fragment %q is defined more than once", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document contains no operations")
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// enter descends one nesting level, failing past maxNesting. Callers that
+// succeed defer p.leave().
+func (p *parser) enter() error {
+	if p.depth >= maxNesting {
+		return fmt.Errorf("syntax error: document nests deeper than %d levels at position %d", maxNesting, p.tok.pos)
+	}
+	p.depth++
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) peek(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) unexpected() error {
+	return fmt.Errorf("syntax error: unexpected %s at position %d", p.tok, p.tok.pos)
+}
+
+func (p *parser) expect(kind tokenKind, value string) error {
+	if !p.peek(kind, value) {
+		return fmt.Errorf("syntax error: expected %q, found %s at position %d", value, p.tok, p.tok.pos)
+	}
+	return p.advance()
+}
+
+// skip consumes the token if it matches and reports whether it did
+func (p *parser) skip(kind tokenKind, value string) (bool, error) {
+	if !p.peek(kind, value) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) parseName() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", fmt.Errorf("syntax error: expected name, found %s at position %d", p.tok, p.tok.pos)
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek(tokenPunct, "(") {
+		vars, err := p.parseVariableDefs()
+		if err != nil {
+			return nil, err
+		}
+		op.variables = vars
+	}
+
+	directives, err := p.parseDirectives()
+	if err != nil {
+		return nil, err
+	}
+	op.directives = directives
+
+	op.selectionSet, err = p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *parser) parseVariableDefs() ([]*variableDef, error) {
+	if err := p.expect(tokenPunct, "("); err != nil {
+		return nil, err
+	}
+
+	var defs []*variableDef
+	for !p.peek(tokenPunct, ")") {
+		if err := p.expect(tokenPunct, "$"); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return nil, err
+		}
+
+		def := &variableDef{name: name}
+		if def.typeName, def.nonNull, err = p.parseType(); err != nil {
+			return nil, err
+		}
+
+		if ok, err := p.skip(tokenPunct, "="); err != nil {
+			return nil, err
+		} else if ok {
+			if def.defaultVal, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+
+		defs = append(defs, def)
+	}
+
+	return defs, p.advance()
+}
+
+// parseType reads a type reference, returning its printed form and whether it is non-null
+func (p *parser) parseType() (string, bool, error) {
+	var typeName string
+
+	if err := p.enter(); err != nil {
+		return "", false, err
+	}
+	defer p.leave()
+
+	if ok, err := p.skip(tokenPunct, "["); err != nil {
+		return "", false, err
+	} else if ok {
+		inner, nonNull, err := p.parseType()
+		if err != nil {
+			return "", false, err
+		}
+		if nonNull {
+			inner += "!"
+		}
+		if err := p.expect(tokenPunct, "]"); err != nil {
+			return "", false, err
+		}
+		typeName = "[" + inner + "]"
+	} else {
+		name, err := p.parseName()
+		if err != nil {
+			return "", false, err
+		}
+		typeName = name
+	}
+
+	nonNull, err := p.skip(tokenPunct, "!")
+	return typeName, nonNull, err
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenName, "on"); err != nil {
+		return nil, err
+	}
+	typeCondition, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+	directives, err := p.parseDirectives()
+	if err != nil {
+		return nil, err
+	}
+	sel, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fragment{name: name, typeCondition: typeCondition, directives: directives, selectionSet: sel}, nil
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+
+	var selections []selection
+	for !p.peek(tokenPunct, "}") {
+		if p.tok.kind == tokenEOF {
+			return nil, p.unexpected()
+		}
+
+		if ok, err := p.skip(tokenPunct, "..."); err != nil {
+			return nil, err
+		} else if ok {
+			sel, err := p.parseFragmentSelection()
+			if err != nil {
+				return nil, err
+			}
+			selections = append(selections, sel)
+			continue
+		}
+
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, f)
+	}
+
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("syntax error: empty selection set at position %d", p.tok.pos)
+	}
+	return selections, p.advance()
+}
+
+// parseFragmentSelection parses what follows "..." in a selection set
+func (p *parser) parseFragmentSelection() (selection, error) {
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		directives, err := p.parseDirectives()
+		if err != nil {
+			return nil, err
+		}
+		return &fragmentSpread{name: name, directives: directives}, nil
+	}
+
+	inline := &inlineFragment{}
+	if ok, err := p.skip(tokenName, "on"); err != nil {
+		return nil, err
+	} else if ok {
+		if inline.typeCondition, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if inline.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if inline.selectionSet, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return inline, nil
+}
+
+func (p *parser) parseField() (*field, error) {
+	name, err := p.parseName()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &field{name: name}
+	if ok, err := p.skip(tokenPunct, ":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = name
+		if f.name, err = p.parseName(); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.arguments, err = p.parseArguments(); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunct, "{") {
+		if f.selectionSet, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func (p *parser) parseArguments() (map[string]value, error) {
+	if !p.peek(tokenPunct, "(") {
+		return nil, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	args := map[string]value{}
+	for !p.peek(tokenPunct, ")") {
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return nil, err
+		}
+		if _, exists := args[name]; exists {
+			return nil, fmt.Errorf("argument %q is given more than once", name)
+		}
+		if args[name], err = p.parseValue(false); err != nil {
+			return nil, err
+		}
+	}
+
+	return args, p.advance()
+}
+
+func (p *parser) parseDirectives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek(tokenPunct, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, &directive{name: name, arguments: args})
+	}
+	return directives, nil
+}
+
+// parseValue reads a literal; constant values (variable defaults) may not reference variables
+func (p *parser) parseValue(constant bool) (value, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	tok := p.tok
+
+	switch tok.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s at position %d", tok, tok.pos)
+		}
+		return int(n), p.advance()
+	case tokenFloat:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s at position %d", tok, tok.pos)
+		}
+		return f, p.advance()
+	case tokenString:
+		return tok.value, p.advance()
+	case tokenName:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		switch tok.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enumValue(tok.value), nil
+	}
+
+	switch {
+	case p.peek(tokenPunct, "$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.parseName()
+		if err != nil {
+			return nil, err
+		}
+		return variableRef(name), nil
+	case p.peek(tokenPunct, "["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []value{}
+		for !p.peek(tokenPunct, "]") {
+			v, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, p.advance()
+	case p.peek(tokenPunct, "{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		obj := map[string]value{}
+		for !p.peek(tokenPunct, "}") {
+			name, err := p.parseName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(tokenPunct, ":"); err != nil {
+				return nil, err
+			}
+			if obj[name], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		return obj, p.advance()
+	}
+
+	return nil, p.unexpected()
+}
@@ -0,0 +1,154 @@
+package graphql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOperations(t *testing.T) {
+	doc, err := parse(`
+		# Shorthand and named operations, with variables and defaults
+		query Movie($id: Int!, $lang: String = "en") @cached {
+			film: movie(id: $id) { title ...Info }
+		}
+		mutation { addToWatchlist(type: "movie", id: 603) { id } }
+		fragment Info on Movie { runtime, genres { name } }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.operations) != 2 {
+		t.Fatalf("got %d operations, want 2", len(doc.operations))
+	}
+	q := doc.operations[0]
+	if q.kind != "query" || q.name != "Movie" {
+		t.Errorf("first operation = %s %q, want query \"Movie\"", q.kind, q.name)
+	}
+	if len(q.directives) != 1 || q.directives[0].name != "cached" {
+		t.Errorf("operation directives = %v, want @cached", q.directives)
+	}
+
+	if len(q.variables) != 2 {
+		t.Fatalf("got %d variables, want 2", len(q.variables))
+	}
+	if v := q.variables[0]; v.name != "id" || v.typeName != "Int" || !v.nonNull {
+		t.Errorf("$id = %+v, want Int!", v)
+	}
+	if v := q.variables[1]; v.name != "lang" || v.nonNull || v.defaultVal != "en" {
+		t.Errorf("$lang = %+v, want String defaulting to \"en\"", v)
+	}
+
+	f := q.selectionSet[0].(*field)
+	if f.alias != "film" || f.name != "movie" || f.responseKey() != "film" {
+		t.Errorf("field = %s: %s, want film: movie", f.alias, f.name)
+	}
+	if ref := f.arguments["id"]; ref != variableRef("id") {
+		t.Errorf("id argument = %#v, want $id", ref)
+	}
+	if spread, ok := f.selectionSet[1].(*fragmentSpread); !ok || spread.name != "Info" {
+		t.Errorf("second selection = %#v, want ...Info", f.selectionSet[1])
+	}
+
+	if m := doc.operations[1]; m.kind != "mutation" || m.name != "" {
+		t.Errorf("second operation = %s %q, want anonymous mutation", m.kind, m.name)
+	}
+
+	frag := doc.fragments["Info"]
+	if frag == nil || frag.typeCondition != "Movie" || len(frag.selectionSet) != 2 {
+		t.Errorf("fragment Info = %+v, want two selections on Movie", frag)
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	doc, err := parse(`{ f(
+		int: -42, float: 1.5e3, str: "tab\there \u00e9", block: """  raw "quoted"  """,
+		yes: true, no: false, null: null, enum: POPULAR,
+		list: [1 "two" [3]], obj: {a: 1, b: {c: $v}}
+	) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := doc.operations[0].selectionSet[0].(*field).arguments
+	want := map[string]value{
+		"int":   -42,
+		"float": 1500.0,
+		"str":   "tab\there é",
+		"block": `raw "quoted"`,
+		"yes":   true,
+		"no":    false,
+		"null":  nil,
+		"enum":  enumValue("POPULAR"),
+		"list":  []value{1, "two", []value{3}},
+		"obj":   map[string]value{"a": 1, "b": map[string]value{"c": variableRef("v")}},
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("arguments = %#v\nwant %#v", args, want)
+	}
+}
+
+func TestParseInlineFragmentsAndDirectives(t *testing.T) {
+	doc, err := parse(`{ movie(id: 1) { ... on Movie { title } ... @include(if: $full) { overview } id @skip(if: true) } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sels := doc.operations[0].selectionSet[0].(*field).selectionSet
+	if frag, ok := sels[0].(*inlineFragment); !ok || frag.typeCondition != "Movie" {
+		t.Errorf("first selection = %#v, want inline fragment on Movie", sels[0])
+	}
+	if frag, ok := sels[1].(*inlineFragment); !ok || frag.typeCondition != "" || frag.directives[0].name != "include" {
+		t.Errorf("second selection = %#v, want untyped inline fragment with @include", sels[1])
+	}
+	if f := sels[2].(*field); f.directives[0].name != "skip" || f.directives[0].arguments["if"] != true {
+		t.Errorf("id directives = %#v, want @skip(if: true)", f.directives)
+	}
+}
+
+func TestParseNestingLimit(t *testing.T) {
+	query := strings.Repeat("{ a ", maxNesting) + strings.Repeat("}", maxNesting)
+	if _, err := parse(query); err != nil {
+		t.Errorf("%d levels: %v", maxNesting, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{``, "document contains no operations"},
+		{`# only a comment`, "document contains no operations"},
+		{`{ }`, "empty selection set"},
+		{`{ movie(id: 1) { title }`, "unexpected"},
+		{`{ movie(id: ) { title } }`, "unexpected"},
+		{`{ movie(id 1) { title } }`, `expected ":"`},
+		{`subscription { x }`, "unexpected"},
+		{`{ f(s: "unterminated) }`, "unterminated string"},
+		{`{ f(s: "bad \q escape") }`, "invalid escape sequence"},
+		{`{ f(s: "\u00zz") }`, "invalid unicode escape"},
+		{`{ f(s: """never closed) }`, "unterminated block string"},
+		{`{ f(n: -) }`, "invalid number"},
+		{`{ f(n: 99999999999999999999) }`, "invalid integer"},
+		{`query ($id: Int = $other) { f }`, "unexpected"},
+		{`{ a } fragment F on T { a } fragment F on T { b }`, `fragment "F" is defined more than once`},
+		{`{ a ?}`, ""},
+		// Deep nesting fails with an error instead of overflowing the stack
+		{strings.Repeat("{ a ", 1_000_000), "document nests deeper than 64 levels"},
+		{"{ f(v: " + strings.Repeat("[", 1_000_000) + ") }", "document nests deeper than 64 levels"},
+		{"{ f(v: " + strings.Repeat("{ k: ", 1_000_000) + ") }", "document nests deeper than 64 levels"},
+		{"query ($v: " + strings.Repeat("[", 1_000_000) + "Int) { a }", "document nests deeper than 64 levels"},
+	}
+	for _, tt := range tests {
+		_, err := parse(tt.query)
+		if err == nil {
+			t.Errorf("parse(%q) succeeded, want error", tt.query)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parse(%q) error = %q, want it to mention %q", tt.query, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package graphql is a small GraphQL engine: it parses query documents and
+// executes them against a schema of Go resolver functions. It supports
+// queries, mutations, variables, aliases, fragments, @skip/@include and
+// introspection through __schema, __type and __typename, but not
+// subscriptions, interfaces, unions or input object types.
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// Schema is the root of an executable GraphQL schema
+type Schema struct {
+	Query    *Object
+	Mutation *Object
+
+	// MaxConcurrency bounds how many loader fetches run at once per request (default 8)
+	MaxConcurrency int
+
+	// MaxWorkers bounds how many goroutines resolve fields at once per
+	// request (default 16); beyond it fields resolve on the caller's goroutine
+	MaxWorkers int
+
+	// MaxDepth rejects documents whose selections nest deeper than this (default 10)
+	MaxDepth int
+
+	// MaxComplexity rejects documents whose estimated cost is higher than
+	// this (default 5000). Every field costs 1, and the nested selection of a
+	// list field counts ListSize times.
+	MaxComplexity int
+}
+
+// Object is a GraphQL object type
+type Object struct {
+	Name   string
+	Fields Fields
+}
+
+// Fields maps field names to their definitions
+type Fields map[string]*Field
+
+// Field defines one field of an object type. Type is nil for scalar fields
+// (and lists of scalars); otherwise the resolved value, or each element of a
+// resolved slice, becomes the Source of the nested selection.
+type Field struct {
+	Type *Object
+
+	// Scalar is the type of a scalar field as introspection reports it: Int,
+	// Float, String, Boolean or ID (default String)
+	Scalar string
+
+	// List reports the field to introspection as a list of Type or Scalar
+	List bool
+
+	Args    map[string]*Argument
+	Resolve ResolveFunc
+
+	// ListSize is the expected length of a list field, used to weigh its
+	// nested selection in the complexity limit (default 1)
+	ListSize int
+
+	// introspection marks __schema and __type, whose selections are
+	// limited by maxTypeNesting rather than MaxDepth
+	introspection bool
+}
+
+// Argument declares a field argument
+type Argument struct {
+	// Type is the argument's scalar type as introspection reports it. It
+	// defaults to the type of Default, or String.
+	Type string
+
+	Required bool
+	Default  interface{}
+}
+
+// ResolveFunc produces a field's value from its parent's value and arguments
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+// ResolveParams is passed to every resolver
+type ResolveParams struct {
+	Context context.Context
+	Source  interface{}
+	Args    Args
+}
+
+// Args holds coerced argument values: int, float64, string, bool, nil, []interface{} or map[string]interface{}
+type Args map[string]interface{}
+
+// Int returns an integer argument; JSON variables arrive as float64 and are accepted when integral
+func (a Args) Int(name string) (int, bool) {
+	switch v := a[name].(type) {
+	case int:
+		return v, true
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), true
+		}
+	}
+	return 0, false
+}
+
+func (a Args) Float(name string) (float64, bool) {
+	switch v := a[name].(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func (a Args) String(name string) (string, bool) {
+	v, ok := a[name].(string)
+	return v, ok
+}
+
+func (a Args) Bool(name string) (bool, bool) {
+	v, ok := a[name].(bool)
+	return v, ok
+}
+
+// RequireInt is Int for required arguments, with an error naming the argument
+func (a Args) RequireInt(name string) (int, error) {
+	v, ok := a.Int(name)
+	if !ok {
+		return 0, fmt.Errorf("argument %q must be an Int", name)
+	}
+	return v, nil
+}
+
+// RequireString is String for required arguments, with an error naming the argument
+func (a Args) RequireString(name string) (string, error) {
+	v, ok := a.String(name)
+	if !ok {
+		return "", fmt.Errorf("argument %q must be a String", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,981 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"muvi-discovery-app/internal/models"
+	"muvi-discovery-app/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultAPIPageSize = 20
+	maxAPIPageSize     = 100
+)
+
+// APIResponse is the envelope returned by every /api/v1 endpoint.
+// Exactly one of Data or Error is set; Meta accompanies paginated data.
+type APIResponse struct {
+	Data  interface{} `json:"data,omitempty"`
+	Meta  *APIMeta    `json:"meta,omitempty"`
+	Error *APIError   `json:"error,omitempty"`
+}
+
+// APIMeta carries pagination metadata for list responses
+type APIMeta struct {
+	Page         int `json:"page"`
+	TotalPages   int `json:"total_pages"`
+	TotalResults int `json:"total_results"`
+}
+
+// MultiSearchData is an /api/v1 multi-search page, with its results also
+// grouped by media type as /api/search returns them
+type MultiSearchData struct {
+	Results []models.MultiSearchResult `json:"results"`
+	Groups  models.SearchGroups        `json:"groups"`
+}
+
+// APIError describes a failed /api/v1 request
+type APIError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// MovieDetailsResponse is a movie's details merged with credits, videos and OMDB data
+type MovieDetailsResponse struct {
+	*models.MovieDetails
+	Credits     *models.Credits         `json:"credits,omitempty"`
+	Videos      []models.Video          `json:"videos"`
+	OMDB        *models.OMDBMovie       `json:"omdb,omitempty"`
+	Ratings     *models.AggregateRating `json:"ratings,omitempty"`
+	InWatchlist bool                    `json:"in_watchlist"`
+	// Region is the country Certification and ReleaseDates apply to
+	Region        string               `json:"region"`
+	Certification string               `json:"certification,omitempty"`
+	ReleaseDates  []models.ReleaseDate `json:"release_dates,omitempty"`
+}
+
+// TVShowDetailsResponse is a TV show's details merged with credits, videos and OMDB data
+type TVShowDetailsResponse struct {
+	*models.TVShowDetails
+	Credits     *models.Credits         `json:"credits,omitempty"`
+	Videos      []models.Video          `json:"videos"`
+	OMDB        *models.OMDBSeries      `json:"omdb,omitempty"`
+	Ratings     *models.AggregateRating `json:"ratings,omitempty"`
+	InWatchlist bool                    `json:"in_watchlist"`
+	// Region is the country Certification applies to
+	Region        string `json:"region"`
+	Certification string `json:"certification,omitempty"`
+}
+
+// CollectionResponse is a collection whose parts carry watchlist status
+type CollectionResponse struct {
+	*models.Collection
+	Parts []CollectionPart `json:"parts"`
+}
+
+// CollectionWatchlistResponse reports how many collection parts were added
+type CollectionWatchlistResponse struct {
+	Added int `json:"added"`
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, body APIResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error encoding API response: %v", err)
+	}
+}
+
+func writeAPIData(w http.ResponseWriter, data interface{}, meta *APIMeta) {
+	writeAPIJSON(w, http.StatusOK, APIResponse{Data: data, Meta: meta})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, APIResponse{Error: &APIError{Status: status, Message: message}})
+}
+
+// writeAPIUpstreamError reports a failed TMDB/OMDB call without leaking its
+// details, with the status the HTML pages use for it
+func writeAPIUpstreamError(w http.ResponseWriter, what string, err error) {
+	switch status := upstreamStatus(err); status {
+	case http.StatusForbidden:
+		writeAPIError(w, status, restrictedMessage)
+	case http.StatusNotFound:
+		writeAPIError(w, status, fmt.Sprintf("No %s found", what))
+	default:
+		log.Printf("Error fetching %s: %v", what, err)
+		writeAPIError(w, status, fmt.Sprintf("Failed to load %s", what))
+	}
+}
+
+// APIV1ContentProfile returns the content restrictions this instance applies
+func (h *Handler) APIV1ContentProfile(w http.ResponseWriter, r *http.Request) {
+	writeAPIData(w, h.tmdbService.ContentProfile(), nil)
+}
+
+func pageMeta[T any](resp *models.TMDBResponse[T]) *APIMeta {
+	return &APIMeta{
+		Page:         resp.Page,
+		TotalPages:   resp.TotalPages,
+		TotalResults: resp.TotalResults,
+	}
+}
+
+// parsePage reads the "page" query parameter, defaulting to 1
+func parsePage(r *http.Request) int {
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+	return page
+}
+
+// parseID reads the numeric {id} route variable
+func parseID(r *http.Request) (int, error) {
+	return strconv.Atoi(mux.Vars(r)["id"])
+}
+
+// parseDiscoverFilters reads discover filters from the query string
+func parseDiscoverFilters(r *http.Request) models.SearchFilters {
+	q := r.URL.Query()
+	var filters models.SearchFilters
+
+	if g, err := strconv.Atoi(q.Get("genre")); err == nil {
+		filters.Genre = &g
+	}
+	if y, err := strconv.Atoi(q.Get("year")); err == nil {
+		filters.Year = &y
+	}
+	if rating, err := strconv.ParseFloat(q.Get("rating"), 64); err == nil {
+		filters.Rating = &rating
+	}
+	filters.SortBy = q.Get("sort_by")
+	filters.SortOrder = q.Get("sort_order")
+	filters.Certification = q.Get("certification")
+
+	return filters
+}
+
+func (h *Handler) APIV1Movies(w http.ResponseWriter, r *http.Request) {
+	category := r.URL.Query().Get("category")
+	if category == "" {
+		category = "popular"
+	}
+
+	movies, err := h.fetchMovies(category, parsePage(r))
+	if err == errUnknownCategory {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("Unknown category %q", category))
+		return
+	}
+	if err != nil {
+		writeAPIUpstreamError(w, "movies", err)
+		return
+	}
+
+	writeAPIData(w, movies.Results, pageMeta(movies))
+}
+
+func (h *Handler) APIV1MovieDetails(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	details, err := h.tmdbService.GetMovieDetails(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "movie details", err)
+		return
+	}
+
+	resp := MovieDetailsResponse{
+		MovieDetails: details,
+		Videos:       []models.Video{},
+		InWatchlist:  h.watchlistService.IsInWatchlist("movie", id),
+	}
+
+	if credits, err := h.tmdbService.GetMovieCredits(id); err != nil {
+		log.Printf("Error fetching movie credits: %v", err)
+	} else {
+		resp.Credits = credits
+	}
+
+	if videos, err := h.tmdbService.GetMovieVideos(id); err != nil {
+		log.Printf("Error fetching movie videos: %v", err)
+	} else {
+		resp.Videos = videos.Results
+	}
+
+	omdbOK := true
+	if details.IMDBId != "" {
+		if omdbData, err := h.omdbService.GetMovieByIMDBID(details.IMDBId); err != nil {
+			log.Printf("Error fetching OMDB data: %v", err)
+			omdbOK = errors.Is(err, services.ErrOMDBNotFound)
+		} else {
+			resp.OMDB = omdbData
+		}
+	}
+
+	resp.Ratings = h.ratingService.Aggregate(details.VoteAverage, details.VoteCount, resp.OMDB)
+	if omdbOK {
+		h.ratingService.Record("movie", id, resp.Ratings)
+	}
+
+	resp.Region = h.tmdbService.Region()
+	if releases, err := h.tmdbService.GetMovieReleaseDates(id); err != nil {
+		log.Printf("Error fetching movie release dates: %v", err)
+	} else {
+		resp.Certification = releases.Certification(resp.Region)
+		resp.ReleaseDates = releases.ForCountry(resp.Region)
+	}
+
+	writeAPIData(w, resp, nil)
+}
+
+func (h *Handler) APIV1MovieCredits(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	if err := h.checkContent("movie", id); err != nil {
+		writeAPIUpstreamError(w, "movie", err)
+		return
+	}
+	credits, err := h.tmdbService.GetMovieCredits(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "movie credits", err)
+		return
+	}
+
+	writeAPIData(w, credits, nil)
+}
+
+func (h *Handler) APIV1MovieReleaseDates(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	if err := h.checkContent("movie", id); err != nil {
+		writeAPIUpstreamError(w, "movie", err)
+		return
+	}
+	releases, err := h.tmdbService.GetMovieReleaseDates(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "movie release dates", err)
+		return
+	}
+
+	writeAPIData(w, releases.Results, nil)
+}
+
+func (h *Handler) APIV1MovieVideos(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	if err := h.checkContent("movie", id); err != nil {
+		writeAPIUpstreamError(w, "movie", err)
+		return
+	}
+	videos, err := h.tmdbService.GetMovieVideos(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "movie videos", err)
+		return
+	}
+
+	writeAPIData(w, videos.Results, nil)
+}
+
+// APIV1MovieImages lists a movie's posters, backdrops and logos; see imageLanguage
+func (h *Handler) APIV1MovieImages(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	images, err := h.getImages("movie", id, imageLanguage(r))
+	if err != nil {
+		writeAPIUpstreamError(w, "movie images", err)
+		return
+	}
+
+	writeAPIData(w, images, nil)
+}
+
+func (h *Handler) APIV1Collection(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+
+	collection, err := h.tmdbService.GetCollection(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "collection", err)
+		return
+	}
+
+	writeAPIData(w, CollectionResponse{Collection: collection, Parts: h.collectionParts(collection)}, nil)
+}
+
+func (h *Handler) APIV1CollectionWatchlistAdd(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid collection ID")
+		return
+	}
+
+	collection, err := h.tmdbService.GetCollection(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "collection", err)
+		return
+	}
+
+	added, err := h.addCollectionToWatchlist(collection)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "Failed to update watchlist")
+		return
+	}
+
+	writeAPIData(w, CollectionWatchlistResponse{Added: added}, nil)
+}
+
+func (h *Handler) APIV1TVShows(w http.ResponseWriter, r *http.Request) {
+	category := r.URL.Query().Get("category")
+	if category == "" {
+		category = "popular"
+	}
+
+	shows, err := h.fetchTVShows(category, parsePage(r))
+	if err == errUnknownCategory {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("Unknown category %q", category))
+		return
+	}
+	if err != nil {
+		writeAPIUpstreamError(w, "TV shows", err)
+		return
+	}
+
+	writeAPIData(w, shows.Results, pageMeta(shows))
+}
+
+func (h *Handler) APIV1TVShowDetails(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid TV show ID")
+		return
+	}
+
+	details, err := h.tmdbService.GetTVShowDetails(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "TV show details", err)
+		return
+	}
+
+	resp := TVShowDetailsResponse{
+		TVShowDetails: details,
+		Videos:        []models.Video{},
+		InWatchlist:   h.watchlistService.IsInWatchlist("tv", id),
+	}
+
+	if credits, err := h.tmdbService.GetTVShowCredits(id); err != nil {
+		log.Printf("Error fetching TV show credits: %v", err)
+	} else {
+		resp.Credits = credits
+	}
+
+	if videos, err := h.tmdbService.GetTVShowVideos(id); err != nil {
+		log.Printf("Error fetching TV show videos: %v", err)
+	} else {
+		resp.Videos = videos.Results
+	}
+
+	omdbOK := true
+	if details.ExternalIDs.IMDBID != "" {
+		if series, err := h.omdbService.GetSeriesByIMDBID(details.ExternalIDs.IMDBID); err != nil {
+			log.Printf("Error fetching OMDB data: %v", err)
+			omdbOK = errors.Is(err, services.ErrOMDBNotFound)
+		} else {
+			resp.OMDB = series
+		}
+	}
+
+	var omdb *models.OMDBMovie
+	if resp.OMDB != nil {
+		omdb = &resp.OMDB.OMDBMovie
+	}
+	resp.Ratings = h.ratingService.Aggregate(details.VoteAverage, details.VoteCount, omdb)
+	if omdbOK {
+		h.ratingService.Record("tv", id, resp.Ratings)
+	}
+
+	resp.Region = h.tmdbService.Region()
+	if ratings, err := h.tmdbService.GetTVContentRatings(id); err != nil {
+		log.Printf("Error fetching TV content ratings: %v", err)
+	} else {
+		resp.Certification = ratings.ForCountry(resp.Region)
+	}
+
+	writeAPIData(w, resp, nil)
+}
+
+func (h *Handler) APIV1TVContentRatings(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid TV show ID")
+		return
+	}
+
+	if err := h.checkContent("tv", id); err != nil {
+		writeAPIUpstreamError(w, "TV show", err)
+		return
+	}
+	ratings, err := h.tmdbService.GetTVContentRatings(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "TV content ratings", err)
+		return
+	}
+
+	writeAPIData(w, ratings.Results, nil)
+}
+
+func (h *Handler) APIV1TVSeason(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid TV show ID")
+		return
+	}
+	seasonNumber, err := strconv.Atoi(mux.Vars(r)["season"])
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid season number")
+		return
+	}
+
+	_, season, err := h.fetchSeason(id, seasonNumber)
+	if err != nil {
+		writeAPIUpstreamError(w, "season", err)
+		return
+	}
+	writeAPIData(w, season, nil)
+}
+
+func (h *Handler) APIV1TVShowCredits(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid TV show ID")
+		return
+	}
+
+	if err := h.checkContent("tv", id); err != nil {
+		writeAPIUpstreamError(w, "TV show", err)
+		return
+	}
+	credits, err := h.tmdbService.GetTVShowCredits(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "TV show credits", err)
+		return
+	}
+
+	writeAPIData(w, credits, nil)
+}
+
+func (h *Handler) APIV1TVShowVideos(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid TV show ID")
+		return
+	}
+
+	if err := h.checkContent("tv", id); err != nil {
+		writeAPIUpstreamError(w, "TV show", err)
+		return
+	}
+	videos, err := h.tmdbService.GetTVShowVideos(id)
+	if err != nil {
+		writeAPIUpstreamError(w, "TV show videos", err)
+		return
+	}
+
+	writeAPIData(w, videos.Results, nil)
+}
+
+// APIV1TVImages lists a TV show's posters, backdrops and logos; see imageLanguage
+func (h *Handler) APIV1TVImages(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid TV show ID")
+		return
+	}
+
+	images, err := h.getImages("tv", id, imageLanguage(r))
+	if err != nil {
+		writeAPIUpstreamError(w, "TV show images", err)
+		return
+	}
+
+	writeAPIData(w, images, nil)
+}
+
+func (h *Handler) APIV1Genres(w http.ResponseWriter, r *http.Request) {
+	var genres []models.Genre
+	if mux.Vars(r)["type"] == "tv" {
+		resp, err := h.tmdbService.GetTVGenres()
+		if err != nil {
+			writeAPIUpstreamError(w, "genres", err)
+			return
+		}
+		genres = resp.Genres
+	} else {
+		resp, err := h.tmdbService.GetMovieGenres()
+		if err != nil {
+			writeAPIUpstreamError(w, "genres", err)
+			return
+		}
+		genres = resp.Genres
+	}
+
+	writeAPIData(w, genres, nil)
+}
+
+func (h *Handler) APIV1Search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeAPIError(w, http.StatusBadRequest, "Query parameter q is required")
+		return
+	}
+	page := parsePage(r)
+
+	switch r.URL.Query().Get("type") {
+	case "tv":
+		resp, err := h.tmdbService.SearchTVShows(query, page)
+		if err != nil {
+			writeAPIUpstreamError(w, "search results", err)
+			return
+		}
+		writeAPIData(w, resp.Results, pageMeta(resp))
+	case "movie":
+		resp, err := h.tmdbService.SearchMovies(query, page)
+		if err != nil {
+			writeAPIUpstreamError(w, "search results", err)
+			return
+		}
+		writeAPIData(w, resp.Results, pageMeta(resp))
+	case "person":
+		resp, err := h.tmdbService.SearchPeople(query, page)
+		if err != nil {
+			writeAPIUpstreamError(w, "search results", err)
+			return
+		}
+		writeAPIData(w, resp.Results, pageMeta(resp))
+	case "", "all":
+		resp, err := h.tmdbService.SearchMulti(query, page)
+		if err != nil {
+			writeAPIUpstreamError(w, "search results", err)
+			return
+		}
+		writeAPIData(w, MultiSearchData{
+			Results: resp.Results,
+			Groups:  models.GroupSearchResults(resp.Results),
+		}, pageMeta(resp))
+	default:
+		writeAPIError(w, http.StatusBadRequest, "Parameter type must be all, movie, tv or person")
+	}
+}
+
+// scorePoolPages is how many pages of popular titles a discover sorted by
+// aggregate score ranks. TMDB can't sort by the score, so the pool is sorted
+// here and then paginated.
+const scorePoolPages = 5
+
+// APIV1Discover passes sorting through to TMDB, except sort_by=aggregate_score
+func (h *Handler) APIV1Discover(w http.ResponseWriter, r *http.Request) {
+	filters := parseDiscoverFilters(r)
+	page := parsePage(r)
+
+	if filters.SortBy == "aggregate_score" {
+		h.discoverByScore(w, r, filters, page)
+		return
+	}
+
+	if mux.Vars(r)["type"] == "tv" {
+		resp, err := h.tmdbService.DiscoverTVShows(filters, page)
+		if err != nil {
+			writeAPIUpstreamError(w, "TV shows", err)
+			return
+		}
+		h.ratingService.AnnotateTVShows(resp.Results, h.config.OMDBEnrichment)
+		writeAPIData(w, resp.Results, pageMeta(resp))
+		return
+	}
+
+	resp, err := h.tmdbService.DiscoverMovies(filters, page)
+	if err != nil {
+		writeAPIUpstreamError(w, "movies", err)
+		return
+	}
+	h.ratingService.AnnotateMovies(resp.Results, h.config.OMDBEnrichment)
+	writeAPIData(w, resp.Results, pageMeta(resp))
+}
+
+// discoverByScore ranks the most popular matches by their cached aggregate
+// scores, or TMDB's score for titles not looked up yet, which are then looked
+// up in the background for later requests
+func (h *Handler) discoverByScore(w http.ResponseWriter, r *http.Request, filters models.SearchFilters, page int) {
+	ascending := filters.SortOrder == "asc"
+	filters.SortBy, filters.SortOrder = "popularity", ""
+
+	if mux.Vars(r)["type"] == "tv" {
+		shows, err := poolPages(func(page int) (*models.TMDBResponse[models.TVShow], error) {
+			return h.tmdbService.DiscoverTVShows(filters, page)
+		})
+		if err != nil {
+			writeAPIUpstreamError(w, "TV shows", err)
+			return
+		}
+		h.ratingService.AnnotateTVShows(shows, false)
+		sort.SliceStable(shows, func(i, j int) bool {
+			return scoreLess(shows[i].AggregateScore, shows[j].AggregateScore, ascending)
+		})
+		h.ratingService.Prefetch(nil, shows, nil)
+		results, meta := paginate(shows, page)
+		writeAPIData(w, results, meta)
+		return
+	}
+
+	movies, err := poolPages(func(page int) (*models.TMDBResponse[models.Movie], error) {
+		return h.tmdbService.DiscoverMovies(filters, page)
+	})
+	if err != nil {
+		writeAPIUpstreamError(w, "movies", err)
+		return
+	}
+	h.ratingService.AnnotateMovies(movies, false)
+	sort.SliceStable(movies, func(i, j int) bool {
+		return scoreLess(movies[i].AggregateScore, movies[j].AggregateScore, ascending)
+	})
+	h.ratingService.Prefetch(movies, nil, nil)
+	results, meta := paginate(movies, page)
+	writeAPIData(w, results, meta)
+}
+
+// poolPages collects the first scorePoolPages pages of results
+func poolPages[T any](fetch func(page int) (*models.TMDBResponse[T], error)) ([]T, error) {
+	pool := []T{}
+	for p := 1; p <= scorePoolPages; p++ {
+		resp, err := fetch(p)
+		if err != nil {
+			return nil, err
+		}
+		pool = append(pool, resp.Results...)
+		if p >= resp.TotalPages {
+			break
+		}
+	}
+	return pool, nil
+}
+
+// paginate returns one page of a locally sorted list, in TMDB's page size
+func paginate[T any](items []T, page int) ([]T, *APIMeta) {
+	meta := &APIMeta{
+		Page:         page,
+		TotalPages:   (len(items) + searchPageSize - 1) / searchPageSize,
+		TotalResults: len(items),
+	}
+	start, end := pageBounds(len(items), page, searchPageSize)
+	return items[start:end], meta
+}
+
+// pageBounds returns the slice bounds of a 1-based page of n items, or an
+// empty range past the last page. The page is checked before it's
+// multiplied, so a huge page number can't overflow.
+func pageBounds(n, page, size int) (start, end int) {
+	if page < 1 || page-1 >= (n+size-1)/size {
+		return n, n
+	}
+	start = (page - 1) * size
+	return start, min(start+size, n)
+}
+
+// scoreLess orders aggregate scores best first (or worst first when
+// ascending), with unscored titles always last
+func scoreLess(a, b *float64, ascending bool) bool {
+	if a == nil || b == nil {
+		return a != nil
+	}
+	if ascending {
+		return *a < *b
+	}
+	return *a > *b
+}
+
+func (h *Handler) APIV1Watchlist(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var items []models.WatchlistItem
+	switch q.Get("filter") {
+	case "watched":
+		items = h.watchlistService.GetWatchedItems()
+	case "unwatched":
+		items = h.watchlistService.GetUnwatchedItems()
+	case "":
+		items = h.watchlistService.GetAllItems()
+	default:
+		writeAPIError(w, http.StatusBadRequest, "Parameter filter must be watched or unwatched")
+		return
+	}
+
+	query := strings.TrimSpace(q.Get("q"))
+	if query != "" {
+		// Search results replace the list but still honour the watched filter
+		filter := q.Get("filter")
+		items = items[:0]
+		for _, item := range h.searchService.Search(query) {
+			if (filter == "watched" && !item.Watched) || (filter == "unwatched" && item.Watched) {
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+
+	if itemType := q.Get("type"); itemType != "" {
+		filtered := items[:0]
+		for _, item := range items {
+			if item.Type == itemType {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	// Newest first by default, so pages are stable between requests; searches
+	// stay in relevance order unless a sort is asked for
+	sortBy := q.Get("sort")
+	if sortBy == "" && query == "" {
+		sortBy = "added"
+	}
+	h.ratingService.AnnotateWatchlist(items, h.config.OMDBEnrichment)
+	if err := sortWatchlist(items, sortBy); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Parameter sort must be added, score or title")
+		return
+	}
+	if sortBy == "score" {
+		h.ratingService.Prefetch(nil, nil, items)
+	}
+
+	perPage := defaultAPIPageSize
+	if pp, err := strconv.Atoi(q.Get("per_page")); err == nil && pp > 0 {
+		perPage = min(pp, maxAPIPageSize)
+	}
+	page := parsePage(r)
+
+	meta := &APIMeta{
+		Page:         page,
+		TotalPages:   (len(items) + perPage - 1) / perPage,
+		TotalResults: len(items),
+	}
+
+	start, end := pageBounds(len(items), page, perPage)
+	pageItems := append([]models.WatchlistItem{}, items[start:end]...)
+	writeAPIData(w, pageItems, meta)
+}
+
+// APIV1WatchlistChanges lists the release date, season and status changes the
+// background refresh found, newest first
+func (h *Handler) APIV1WatchlistChanges(w http.ResponseWriter, r *http.Request) {
+	limit := defaultAPIPageSize
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	writeAPIData(w, h.refreshService.Changes(limit), nil)
+}
+
+func (h *Handler) APIV1WatchlistAdd(w http.ResponseWriter, r *http.Request) {
+	var item models.WatchlistItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if item.Type != "movie" && item.Type != "tv" {
+		writeAPIError(w, http.StatusBadRequest, "Field type must be movie or tv")
+		return
+	}
+
+	if err := h.watchlistService.AddItem(item); errors.Is(err, services.ErrAlreadyInWatchlist) {
+		writeAPIError(w, http.StatusConflict, "Already in watchlist")
+		return
+	} else if err != nil {
+		log.Printf("Error adding %s %d to the watchlist: %v", item.Type, item.ID, err)
+		writeAPIError(w, http.StatusInternalServerError, "Failed to save the watchlist")
+		return
+	}
+
+	added, _ := h.watchlistService.GetItem(item.Type, item.ID)
+	writeAPIJSON(w, http.StatusCreated, APIResponse{Data: added})
+}
+
+func (h *Handler) APIV1WatchlistRemove(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := h.watchlistService.RemoveItem(mux.Vars(r)["type"], id); err != nil {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) APIV1WatchlistToggle(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+	itemType := mux.Vars(r)["type"]
+
+	if err := h.watchlistService.ToggleWatched(itemType, id); err != nil {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	item, _ := h.watchlistService.GetItem(itemType, id)
+	writeAPIData(w, item, nil)
+}
+
+func (h *Handler) APIV1WatchlistRating(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+	itemType := mux.Vars(r)["type"]
+
+	var body struct {
+		Rating *float64 `json:"rating"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if _, exists := h.watchlistService.GetItem(itemType, id); !exists {
+		writeAPIError(w, http.StatusNotFound, "item not found in watchlist")
+		return
+	}
+	if err := h.watchlistService.SetRating(itemType, id, body.Rating); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	item, _ := h.watchlistService.GetItem(itemType, id)
+	writeAPIData(w, item, nil)
+}
+
+func (h *Handler) APIV1WatchlistTags(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+	itemType := mux.Vars(r)["type"]
+
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if err := h.watchlistService.SetTags(itemType, id, body.Tags); err != nil {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	item, _ := h.watchlistService.GetItem(itemType, id)
+	writeAPIData(w, item, nil)
+}
+
+func (h *Handler) APIV1WatchlistPoster(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+	itemType := mux.Vars(r)["type"]
+
+	var body struct {
+		PosterPath *string `json:"poster_path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if _, exists := h.watchlistService.GetItem(itemType, id); !exists {
+		writeAPIError(w, http.StatusNotFound, "item not found in watchlist")
+		return
+	}
+	if err := h.setWatchlistPoster(itemType, id, body.PosterPath); err != nil {
+		if errors.Is(err, errUnknownPoster) {
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeAPIUpstreamError(w, "images", err)
+		return
+	}
+
+	item, _ := h.watchlistService.GetItem(itemType, id)
+	writeAPIData(w, item, nil)
+}
+
+func (h *Handler) APIV1Stats(w http.ResponseWriter, r *http.Request) {
+	writeAPIData(w, h.statsService.Compute(), nil)
+}
+
+func (h *Handler) APIV1Calendar(w http.ResponseWriter, r *http.Request) {
+	scope, days, err := parseCalendarQuery(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Parameter scope must be all or watchlist")
+		return
+	}
+
+	writeAPIData(w, h.calendarService.Entries(days, scope == "watchlist"), nil)
+}
+
+// APIV1Certifications lists the content ratings of the configured region,
+// least restrictive first; any of them can be passed to discover's certification filter
+func (h *Handler) APIV1Certifications(w http.ResponseWriter, r *http.Request) {
+	certs, err := h.tmdbService.GetCertifications(mux.Vars(r)["type"])
+	if err != nil {
+		writeAPIUpstreamError(w, "certifications", err)
+		return
+	}
+
+	writeAPIData(w, certs, nil)
+}
+
+func (h *Handler) APIV1NotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, "Not found")
+}
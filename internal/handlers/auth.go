@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// requireAdmin guards routes that change where the app sends requests or that
+// show secrets. The ADMIN_TOKEN is accepted as a bearer token, for scripts, or
+// as the HTTP Basic password, which browsers remember for the page's own
+// requests. Because browsers also send Basic credentials on requests from
+// other sites, changes are refused when their Origin is another host.
+func (h *Handler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.config.AdminToken == "" {
+			h.denyAdmin(w, r, http.StatusForbidden, "Webhooks are disabled until ADMIN_TOKEN is set")
+			return
+		}
+		if !h.isAdmin(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Muvi Discovery admin", charset="UTF-8"`)
+			h.denyAdmin(w, r, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !sameOrigin(r) {
+			h.denyAdmin(w, r, http.StatusForbidden, "Cross-origin request refused")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *Handler) isAdmin(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, token, ok = r.BasicAuth()
+	}
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.config.AdminToken)) == 1
+}
+
+// sameOrigin reports whether a request's Origin, when it has one, is this host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func (h *Handler) denyAdmin(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		writeAPIError(w, status, message)
+		return
+	}
+	h.renderError(w, r, status, message)
+}
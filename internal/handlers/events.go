@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"muvi-discovery-app/internal/services"
+)
+
+const (
+	// eventBuffer is how many events a slow stream may fall behind by before
+	// it is closed; the browser then reconnects and catches up
+	eventBuffer = 64
+
+	// heartbeatInterval keeps idle streams from being closed by proxies
+	heartbeatInterval = 25 * time.Second
+
+	// reconnectDelay is how long browsers wait before reconnecting, in ms
+	reconnectDelay = 3000
+)
+
+// watchlistSync is the first event on every stream, and is sent again when
+// missed events can't be replayed
+type watchlistSync struct {
+	Count int    `json:"count"`
+	Seq   uint64 `json:"seq"`
+	// Reload is set when the client missed changes and should refresh
+	// whatever watchlist state it shows
+	Reload bool `json:"reload"`
+}
+
+// WatchlistEvents streams watchlist changes as server-sent events, so every
+// open tab and device stays in sync. A reconnecting client sends the id of
+// the last event it saw in Last-Event-ID and gets the ones it missed. A new
+// connection can do the same with ?since=, as browsers can't set the header
+// on the first request.
+func (h *Handler) WatchlistEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	// Subscribe before reading the state, so no change falls in between
+	events := make(chan services.WatchlistEvent, eventBuffer)
+	overflow := make(chan struct{})
+	var closed bool
+	unsubscribe := h.watchlistService.Subscribe(func(event services.WatchlistEvent) {
+		if closed {
+			return
+		}
+		select {
+		case events <- event:
+		default:
+			// Events are delivered on one goroutine, so closed needs no lock
+			closed = true
+			close(overflow)
+		}
+	})
+	defer unsubscribe()
+
+	count, seq := h.watchlistService.State()
+	var missed []services.WatchlistEvent
+	reload := false
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("since")
+	}
+	if lastID != "" {
+		last, err := strconv.ParseUint(lastID, 10, 64)
+		if err == nil {
+			missed, ok = h.watchlistService.EventsSince(last)
+		}
+		if err != nil || !ok {
+			reload = true
+		}
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay)
+	if err := writeEvent(w, 0, "sync", watchlistSync{Count: count, Seq: seq, Reload: reload}); err != nil {
+		return
+	}
+	for _, event := range missed {
+		if event.Seq > seq {
+			break
+		}
+		if err := writeEvent(w, event.Seq, "watchlist", event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.streamsDone:
+			return
+		case <-overflow:
+			return
+		case event := <-events:
+			// Changes before the sync event are already counted in it
+			if event.Seq <= seq {
+				continue
+			}
+			if err := writeEvent(w, event.Seq, "watchlist", event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// CloseStreams ends every open event stream, so a graceful shutdown doesn't
+// wait on them. Browsers reconnect to whichever server comes up next.
+func (h *Handler) CloseStreams() {
+	h.closeStreamsOnce.Do(func() { close(h.streamsDone) })
+}
+
+// writeEvent writes one server-sent event; an id of 0 is left out so it
+// doesn't reset the client's Last-Event-ID
+func writeEvent(w http.ResponseWriter, id uint64, name string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error encoding %s event: %v", name, err)
+		return err
+	}
+	if id > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, body)
+	return err
+}
@@ -0,0 +1,809 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"time"
+
+	"muvi-discovery-app/internal/graphql"
+	"muvi-discovery-app/internal/models"
+)
+
+// gqlMovie is the source value behind the GraphQL Movie type. Movies from
+// lists and searches carry their summary; details, credits, videos and OMDB
+// data are fetched through the request's loader only when selected.
+type gqlMovie struct {
+	id      int
+	summary *models.Movie
+}
+
+// gqlTVShow is the source value behind the GraphQL TVShow type
+type gqlTVShow struct {
+	id      int
+	summary *models.TVShow
+}
+
+// graphqlBatchWorkers is how many titles of one loader batch are fetched at
+// once; TMDB and OMDB have no multi-title endpoints
+const graphqlBatchWorkers = 4
+
+// graphqlListSize is the expected length of list fields, for the complexity limit
+const graphqlListSize = 20
+
+// graphqlMaxBodyBytes and graphqlMaxQueryBytes bound a request before it is
+// parsed; real queries are a few kilobytes
+const (
+	graphqlMaxBodyBytes  = 1 << 20
+	graphqlMaxQueryBytes = 64 << 10
+)
+
+func (h *Handler) loadMovieDetails(ctx context.Context, id int) (*models.MovieDetails, error) {
+	return graphql.LoadBatch(ctx, "movie", id, graphql.Each(graphqlBatchWorkers, h.tmdbService.GetMovieDetails))
+}
+
+func (h *Handler) loadTVShowDetails(ctx context.Context, id int) (*models.TVShowDetails, error) {
+	return graphql.LoadBatch(ctx, "tv", id, graphql.Each(graphqlBatchWorkers, h.tmdbService.GetTVShowDetails))
+}
+
+// loadOMDB loads the OMDB record of a movie, or for mediaType "tv" of a series
+func (h *Handler) loadOMDB(ctx context.Context, mediaType, imdbID string) (*models.OMDBMovie, error) {
+	if imdbID == "" {
+		return nil, nil
+	}
+	get := func(imdbID string) (*models.OMDBMovie, error) { return h.omdbService.Get(mediaType, imdbID) }
+	return graphql.LoadBatch(ctx, "omdb-"+mediaType, imdbID, graphql.Each(graphqlBatchWorkers, get))
+}
+
+func movieSources(movies []models.Movie) []*gqlMovie {
+	sources := make([]*gqlMovie, len(movies))
+	for i := range movies {
+		sources[i] = &gqlMovie{id: movies[i].ID, summary: &movies[i]}
+	}
+	return sources
+}
+
+func tvShowSources(shows []models.TVShow) []*gqlTVShow {
+	sources := make([]*gqlTVShow, len(shows))
+	for i := range shows {
+		sources[i] = &gqlTVShow{id: shows[i].ID, summary: &shows[i]}
+	}
+	return sources
+}
+
+// prop defines a field read directly from a source of type T
+func prop[T, V any](get func(T) V) *graphql.Field {
+	return typed[V](func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(T)), nil
+	})
+}
+
+// typed defines a field resolving to values of type V, which introspection
+// reports as a scalar, or a list when V is a slice. Fields of object types
+// get their Type from object or list.
+func typed[V any](resolve graphql.ResolveFunc) *graphql.Field {
+	f := &graphql.Field{Resolve: resolve}
+	t := reflect.TypeFor[V]()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice {
+		f.List = true
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f.Scalar = "Int"
+	case reflect.Float32, reflect.Float64:
+		f.Scalar = "Float"
+	case reflect.Bool:
+		f.Scalar = "Boolean"
+	}
+	return f
+}
+
+// object sets a field's type, for fields whose value has a nested selection
+func object(obj *graphql.Object, f *graphql.Field) *graphql.Field {
+	f.Type = obj
+	return f
+}
+
+// list is object for fields returning long lists, which weigh more in the
+// complexity limit
+func list(obj *graphql.Object, f *graphql.Field) *graphql.Field {
+	f.List = true
+	f.ListSize = graphqlListSize
+	return object(obj, f)
+}
+
+func movieProp[V any](h *Handler, get func(*models.Movie) V) *graphql.Field {
+	return typed[V](func(p graphql.ResolveParams) (interface{}, error) {
+		m := p.Source.(*gqlMovie)
+		if m.summary != nil {
+			return get(m.summary), nil
+		}
+		details, err := h.loadMovieDetails(p.Context, m.id)
+		if err != nil {
+			return nil, err
+		}
+		return get(&details.Movie), nil
+	})
+}
+
+func movieDetailProp[V any](h *Handler, get func(*models.MovieDetails) V) *graphql.Field {
+	return typed[V](func(p graphql.ResolveParams) (interface{}, error) {
+		details, err := h.loadMovieDetails(p.Context, p.Source.(*gqlMovie).id)
+		if err != nil {
+			return nil, err
+		}
+		return get(details), nil
+	})
+}
+
+func tvShowProp[V any](h *Handler, get func(*models.TVShow) V) *graphql.Field {
+	return typed[V](func(p graphql.ResolveParams) (interface{}, error) {
+		s := p.Source.(*gqlTVShow)
+		if s.summary != nil {
+			return get(s.summary), nil
+		}
+		details, err := h.loadTVShowDetails(p.Context, s.id)
+		if err != nil {
+			return nil, err
+		}
+		return get(&details.TVShow), nil
+	})
+}
+
+func tvShowDetailProp[V any](h *Handler, get func(*models.TVShowDetails) V) *graphql.Field {
+	return typed[V](func(p graphql.ResolveParams) (interface{}, error) {
+		details, err := h.loadTVShowDetails(p.Context, p.Source.(*gqlTVShow).id)
+		if err != nil {
+			return nil, err
+		}
+		return get(details), nil
+	})
+}
+
+func (h *Handler) watchlistItemValue(itemType string, id int) interface{} {
+	item, exists := h.watchlistService.GetItem(itemType, id)
+	if !exists {
+		return nil
+	}
+	return *item
+}
+
+// watchlistArgs reads the type and id arguments shared by watchlist fields
+func watchlistArgs(args graphql.Args) (string, int, error) {
+	itemType, err := args.RequireString("type")
+	if err != nil {
+		return "", 0, err
+	}
+	if itemType != "movie" && itemType != "tv" {
+		return "", 0, fmt.Errorf("argument \"type\" must be movie or tv")
+	}
+	id, err := args.RequireInt("id")
+	return itemType, id, err
+}
+
+func discoverFilters(args graphql.Args) models.SearchFilters {
+	var filters models.SearchFilters
+	if g, ok := args.Int("genre"); ok {
+		filters.Genre = &g
+	}
+	if y, ok := args.Int("year"); ok {
+		filters.Year = &y
+	}
+	if r, ok := args.Float("rating"); ok {
+		filters.Rating = &r
+	}
+	filters.SortBy, _ = args.String("sortBy")
+	filters.SortOrder, _ = args.String("sortOrder")
+	filters.Certification, _ = args.String("certification")
+	return filters
+}
+
+func (h *Handler) newGraphQLSchema() *graphql.Schema {
+	genreType := &graphql.Object{Name: "Genre", Fields: graphql.Fields{
+		"id":   prop(func(g models.Genre) int { return g.ID }),
+		"name": prop(func(g models.Genre) string { return g.Name }),
+	}}
+
+	castType := &graphql.Object{Name: "CastMember", Fields: graphql.Fields{
+		"id":                 prop(func(c models.CastMember) int { return c.ID }),
+		"name":               prop(func(c models.CastMember) string { return c.Name }),
+		"character":          prop(func(c models.CastMember) string { return c.Character }),
+		"order":              prop(func(c models.CastMember) int { return c.Order }),
+		"profilePath":        prop(func(c models.CastMember) *string { return c.ProfilePath }),
+		"knownForDepartment": prop(func(c models.CastMember) string { return c.KnownForDepartment }),
+	}}
+
+	crewType := &graphql.Object{Name: "CrewMember", Fields: graphql.Fields{
+		"id":          prop(func(c models.CrewMember) int { return c.ID }),
+		"name":        prop(func(c models.CrewMember) string { return c.Name }),
+		"job":         prop(func(c models.CrewMember) string { return c.Job }),
+		"department":  prop(func(c models.CrewMember) string { return c.Department }),
+		"profilePath": prop(func(c models.CrewMember) *string { return c.ProfilePath }),
+	}}
+
+	creditsType := &graphql.Object{Name: "Credits", Fields: graphql.Fields{
+		"cast": list(castType, prop(func(c *models.Credits) []models.CastMember { return c.Cast })),
+		"crew": list(crewType, prop(func(c *models.Credits) []models.CrewMember { return c.Crew })),
+	}}
+
+	videoType := &graphql.Object{Name: "Video", Fields: graphql.Fields{
+		"id":          prop(func(v models.Video) string { return v.ID }),
+		"key":         prop(func(v models.Video) string { return v.Key }),
+		"name":        prop(func(v models.Video) string { return v.Name }),
+		"site":        prop(func(v models.Video) string { return v.Site }),
+		"type":        prop(func(v models.Video) string { return v.Type }),
+		"official":    prop(func(v models.Video) bool { return v.Official }),
+		"publishedAt": prop(func(v models.Video) string { return v.PublishedAt }),
+	}}
+
+	ratingType := &graphql.Object{Name: "Rating", Fields: graphql.Fields{
+		"source": prop(func(r models.Rating) string { return r.Source }),
+		"value":  prop(func(r models.Rating) string { return r.Value }),
+	}}
+
+	omdbType := &graphql.Object{Name: "OMDBMovie", Fields: graphql.Fields{
+		"title":      prop(func(o *models.OMDBMovie) string { return o.Title }),
+		"year":       prop(func(o *models.OMDBMovie) string { return o.Year }),
+		"rated":      prop(func(o *models.OMDBMovie) string { return o.Rated }),
+		"released":   prop(func(o *models.OMDBMovie) string { return o.Released }),
+		"runtime":    prop(func(o *models.OMDBMovie) string { return o.Runtime }),
+		"genre":      prop(func(o *models.OMDBMovie) string { return o.Genre }),
+		"director":   prop(func(o *models.OMDBMovie) string { return o.Director }),
+		"writer":     prop(func(o *models.OMDBMovie) string { return o.Writer }),
+		"actors":     prop(func(o *models.OMDBMovie) string { return o.Actors }),
+		"plot":       prop(func(o *models.OMDBMovie) string { return o.Plot }),
+		"awards":     prop(func(o *models.OMDBMovie) string { return o.Awards }),
+		"metascore":  prop(func(o *models.OMDBMovie) string { return o.Metascore }),
+		"imdbRating": prop(func(o *models.OMDBMovie) string { return o.IMDBRating }),
+		"imdbVotes":  prop(func(o *models.OMDBMovie) string { return o.IMDBVotes }),
+		"imdbId":     prop(func(o *models.OMDBMovie) string { return o.IMDBID }),
+		"boxOffice":  prop(func(o *models.OMDBMovie) string { return o.BoxOffice }),
+		"ratings":    object(ratingType, prop(func(o *models.OMDBMovie) []models.Rating { return o.Ratings })),
+	}}
+
+	sourceScoreType := &graphql.Object{Name: "SourceScore", Fields: graphql.Fields{
+		"source": prop(func(r models.SourceScore) string { return r.Source }),
+		"score":  prop(func(r models.SourceScore) float64 { return r.Score }),
+		"raw":    prop(func(r models.SourceScore) string { return r.Raw }),
+		"weight": prop(func(r models.SourceScore) float64 { return r.Weight }),
+	}}
+
+	aggregateRatingType := &graphql.Object{Name: "AggregateRating", Fields: graphql.Fields{
+		"score":   prop(func(r *models.AggregateRating) float64 { return r.Score }),
+		"sources": object(sourceScoreType, prop(func(r *models.AggregateRating) []models.SourceScore { return r.Sources })),
+	}}
+
+	seasonType := &graphql.Object{Name: "Season", Fields: graphql.Fields{
+		"seasonNumber": prop(func(s models.Season) int { return s.SeasonNumber }),
+		"name":         prop(func(s models.Season) string { return s.Name }),
+		"overview":     prop(func(s models.Season) string { return s.Overview }),
+		"airDate":      prop(func(s models.Season) string { return s.AirDate }),
+		"episodeCount": prop(func(s models.Season) int { return s.EpisodeCount }),
+		"posterPath":   prop(func(s models.Season) *string { return s.PosterPath }),
+	}}
+
+	// Movie, TVShow and WatchlistItem refer to each other, so their fields are filled in below
+	movieType := &graphql.Object{Name: "Movie"}
+	tvShowType := &graphql.Object{Name: "TVShow"}
+	watchlistItemType := &graphql.Object{Name: "WatchlistItem"}
+
+	movieType.Fields = graphql.Fields{
+		"id":               prop(func(m *gqlMovie) int { return m.id }),
+		"title":            movieProp(h, func(m *models.Movie) string { return m.Title }),
+		"overview":         movieProp(h, func(m *models.Movie) string { return m.Overview }),
+		"posterPath":       movieProp(h, func(m *models.Movie) *string { return m.PosterPath }),
+		"backdropPath":     movieProp(h, func(m *models.Movie) *string { return m.BackdropPath }),
+		"releaseDate":      movieProp(h, func(m *models.Movie) string { return m.ReleaseDate }),
+		"voteAverage":      movieProp(h, func(m *models.Movie) float64 { return m.VoteAverage }),
+		"voteCount":        movieProp(h, func(m *models.Movie) int { return m.VoteCount }),
+		"popularity":       movieProp(h, func(m *models.Movie) float64 { return m.Popularity }),
+		"originalTitle":    movieProp(h, func(m *models.Movie) string { return m.OriginalTitle }),
+		"originalLanguage": movieProp(h, func(m *models.Movie) string { return m.OriginalLanguage }),
+		"adult":            movieProp(h, func(m *models.Movie) bool { return m.Adult }),
+		"runtime":          movieDetailProp(h, func(d *models.MovieDetails) int { return d.Runtime }),
+		"budget":           movieDetailProp(h, func(d *models.MovieDetails) int { return d.Budget }),
+		"revenue":          movieDetailProp(h, func(d *models.MovieDetails) int { return d.Revenue }),
+		"tagline":          movieDetailProp(h, func(d *models.MovieDetails) string { return d.Tagline }),
+		"status":           movieDetailProp(h, func(d *models.MovieDetails) string { return d.Status }),
+		"homepage":         movieDetailProp(h, func(d *models.MovieDetails) string { return d.Homepage }),
+		"imdbId":           movieDetailProp(h, func(d *models.MovieDetails) string { return d.IMDBId }),
+		"genres":           object(genreType, movieDetailProp(h, func(d *models.MovieDetails) []models.Genre { return d.Genres })),
+		"credits": object(creditsType, &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id := p.Source.(*gqlMovie).id
+			return graphql.LoadBatch(p.Context, "movie-credits", id, graphql.Each(graphqlBatchWorkers, h.tmdbService.GetMovieCredits))
+		}}),
+		"videos": list(videoType, &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id := p.Source.(*gqlMovie).id
+			videos, err := graphql.LoadBatch(p.Context, "movie-videos", id, graphql.Each(graphqlBatchWorkers, h.tmdbService.GetMovieVideos))
+			if err != nil {
+				return nil, err
+			}
+			return videos.Results, nil
+		}}),
+		"omdb": object(omdbType, &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			details, err := h.loadMovieDetails(p.Context, p.Source.(*gqlMovie).id)
+			if err != nil {
+				return nil, err
+			}
+			return h.loadOMDB(p.Context, "movie", details.IMDBId)
+		}}),
+		"aggregateRating": object(aggregateRatingType, &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return h.ratingService.Get("movie", p.Source.(*gqlMovie).id)
+		}}),
+		"certification": &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			releases, err := h.tmdbService.GetMovieReleaseDates(p.Source.(*gqlMovie).id)
+			if err != nil {
+				return nil, err
+			}
+			return releases.Certification(h.tmdbService.Region()), nil
+		}},
+		"inWatchlist": prop(func(m *gqlMovie) bool { return h.watchlistService.IsInWatchlist("movie", m.id) }),
+		"watchlistItem": object(watchlistItemType, prop(func(m *gqlMovie) interface{} {
+			return h.watchlistItemValue("movie", m.id)
+		})),
+	}
+
+	tvShowType.Fields = graphql.Fields{
+		"id":               prop(func(s *gqlTVShow) int { return s.id }),
+		"name":             tvShowProp(h, func(s *models.TVShow) string { return s.Name }),
+		"overview":         tvShowProp(h, func(s *models.TVShow) string { return s.Overview }),
+		"posterPath":       tvShowProp(h, func(s *models.TVShow) *string { return s.PosterPath }),
+		"backdropPath":     tvShowProp(h, func(s *models.TVShow) *string { return s.BackdropPath }),
+		"firstAirDate":     tvShowProp(h, func(s *models.TVShow) string { return s.FirstAirDate }),
+		"voteAverage":      tvShowProp(h, func(s *models.TVShow) float64 { return s.VoteAverage }),
+		"voteCount":        tvShowProp(h, func(s *models.TVShow) int { return s.VoteCount }),
+		"popularity":       tvShowProp(h, func(s *models.TVShow) float64 { return s.Popularity }),
+		"originalName":     tvShowProp(h, func(s *models.TVShow) string { return s.OriginalName }),
+		"originalLanguage": tvShowProp(h, func(s *models.TVShow) string { return s.OriginalLanguage }),
+		"originCountry":    tvShowProp(h, func(s *models.TVShow) []string { return s.OriginCountry }),
+		"numberOfSeasons":  tvShowDetailProp(h, func(d *models.TVShowDetails) int { return d.NumberOfSeasons }),
+		"seasons":          object(seasonType, tvShowDetailProp(h, func(d *models.TVShowDetails) []models.Season { return d.Seasons })),
+		"numberOfEpisodes": tvShowDetailProp(h, func(d *models.TVShowDetails) int { return d.NumberOfEpisodes }),
+		"episodeRunTime":   tvShowDetailProp(h, func(d *models.TVShowDetails) []int { return d.EpisodeRunTime }),
+		"status":           tvShowDetailProp(h, func(d *models.TVShowDetails) string { return d.Status }),
+		"tagline":          tvShowDetailProp(h, func(d *models.TVShowDetails) string { return d.Tagline }),
+		"type":             tvShowDetailProp(h, func(d *models.TVShowDetails) string { return d.Type }),
+		"inProduction":     tvShowDetailProp(h, func(d *models.TVShowDetails) bool { return d.InProduction }),
+		"lastAirDate":      tvShowDetailProp(h, func(d *models.TVShowDetails) string { return d.LastAirDate }),
+		"homepage":         tvShowDetailProp(h, func(d *models.TVShowDetails) string { return d.Homepage }),
+		"imdbId":           tvShowDetailProp(h, func(d *models.TVShowDetails) string { return d.ExternalIDs.IMDBID }),
+		"genres":           object(genreType, tvShowDetailProp(h, func(d *models.TVShowDetails) []models.Genre { return d.Genres })),
+		"creators": tvShowDetailProp(h, func(d *models.TVShowDetails) []string {
+			names := make([]string, len(d.CreatedBy))
+			for i, c := range d.CreatedBy {
+				names[i] = c.Name
+			}
+			return names
+		}),
+		"credits": object(creditsType, &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id := p.Source.(*gqlTVShow).id
+			return graphql.LoadBatch(p.Context, "tv-credits", id, graphql.Each(graphqlBatchWorkers, h.tmdbService.GetTVShowCredits))
+		}}),
+		"videos": list(videoType, &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			id := p.Source.(*gqlTVShow).id
+			videos, err := graphql.LoadBatch(p.Context, "tv-videos", id, graphql.Each(graphqlBatchWorkers, h.tmdbService.GetTVShowVideos))
+			if err != nil {
+				return nil, err
+			}
+			return videos.Results, nil
+		}}),
+		"omdb": object(omdbType, &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			details, err := h.loadTVShowDetails(p.Context, p.Source.(*gqlTVShow).id)
+			if err != nil {
+				return nil, err
+			}
+			return h.loadOMDB(p.Context, "tv", details.ExternalIDs.IMDBID)
+		}}),
+		"aggregateRating": object(aggregateRatingType, &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return h.ratingService.Get("tv", p.Source.(*gqlTVShow).id)
+		}}),
+		"certification": &graphql.Field{Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			ratings, err := h.tmdbService.GetTVContentRatings(p.Source.(*gqlTVShow).id)
+			if err != nil {
+				return nil, err
+			}
+			return ratings.ForCountry(h.tmdbService.Region()), nil
+		}},
+		"inWatchlist": prop(func(s *gqlTVShow) bool { return h.watchlistService.IsInWatchlist("tv", s.id) }),
+		"watchlistItem": object(watchlistItemType, prop(func(s *gqlTVShow) interface{} {
+			return h.watchlistItemValue("tv", s.id)
+		})),
+	}
+
+	watchlistItemType.Fields = graphql.Fields{
+		"id":               prop(func(i models.WatchlistItem) int { return i.ID }),
+		"type":             prop(func(i models.WatchlistItem) string { return i.Type }),
+		"title":            prop(func(i models.WatchlistItem) string { return i.Title }),
+		"posterPath":       prop(func(i models.WatchlistItem) *string { return i.PosterPath }),
+		"customPosterPath": prop(func(i models.WatchlistItem) *string { return i.CustomPosterPath }),
+		"releaseDate":      prop(func(i models.WatchlistItem) string { return i.ReleaseDate }),
+		"voteAverage":      prop(func(i models.WatchlistItem) float64 { return i.VoteAverage }),
+		"watched":          prop(func(i models.WatchlistItem) bool { return i.Watched }),
+		"addedAt":          prop(func(i models.WatchlistItem) time.Time { return i.AddedAt }),
+		"watchedAt":        prop(func(i models.WatchlistItem) *time.Time { return i.WatchedAt }),
+		"rating":           prop(func(i models.WatchlistItem) *float64 { return i.Rating }),
+		"tags":             prop(func(i models.WatchlistItem) []string { return i.Tags }),
+		"status":           prop(func(i models.WatchlistItem) string { return i.Status }),
+		"numberOfSeasons":  prop(func(i models.WatchlistItem) int { return i.NumberOfSeasons }),
+		"refreshedAt":      prop(func(i models.WatchlistItem) *time.Time { return i.RefreshedAt }),
+		"movie": object(movieType, prop(func(i models.WatchlistItem) interface{} {
+			if i.Type != "movie" {
+				return nil
+			}
+			return &gqlMovie{id: i.ID}
+		})),
+		"tvShow": object(tvShowType, prop(func(i models.WatchlistItem) interface{} {
+			if i.Type != "tv" {
+				return nil
+			}
+			return &gqlTVShow{id: i.ID}
+		})),
+	}
+
+	moviePageType := &graphql.Object{Name: "MoviePage", Fields: graphql.Fields{
+		"page":         prop(func(r *models.TMDBResponse[models.Movie]) int { return r.Page }),
+		"totalPages":   prop(func(r *models.TMDBResponse[models.Movie]) int { return r.TotalPages }),
+		"totalResults": prop(func(r *models.TMDBResponse[models.Movie]) int { return r.TotalResults }),
+		"results":      list(movieType, prop(func(r *models.TMDBResponse[models.Movie]) []*gqlMovie { return movieSources(r.Results) })),
+	}}
+
+	tvShowPageType := &graphql.Object{Name: "TVShowPage", Fields: graphql.Fields{
+		"page":         prop(func(r *models.TMDBResponse[models.TVShow]) int { return r.Page }),
+		"totalPages":   prop(func(r *models.TMDBResponse[models.TVShow]) int { return r.TotalPages }),
+		"totalResults": prop(func(r *models.TMDBResponse[models.TVShow]) int { return r.TotalResults }),
+		"results":      list(tvShowType, prop(func(r *models.TMDBResponse[models.TVShow]) []*gqlTVShow { return tvShowSources(r.Results) })),
+	}}
+
+	periodType := &graphql.Object{Name: "PeriodCount", Fields: graphql.Fields{
+		"period": prop(func(c models.PeriodCount) string { return c.Period }),
+		"count":  prop(func(c models.PeriodCount) int { return c.Count }),
+	}}
+
+	nameCountType := &graphql.Object{Name: "NameCount", Fields: graphql.Fields{
+		"name":  prop(func(c models.NameCount) string { return c.Name }),
+		"count": prop(func(c models.NameCount) int { return c.Count }),
+	}}
+
+	statsType := &graphql.Object{Name: "WatchStats", Fields: graphql.Fields{
+		"totalTitles":           prop(func(s *models.WatchStats) int { return s.TotalTitles }),
+		"watchedTitles":         prop(func(s *models.WatchStats) int { return s.WatchedTitles }),
+		"watchedMovies":         prop(func(s *models.WatchStats) int { return s.WatchedMovies }),
+		"watchedShows":          prop(func(s *models.WatchStats) int { return s.WatchedShows }),
+		"minutesWatched":        prop(func(s *models.WatchStats) int { return s.MinutesWatched }),
+		"hoursWatched":          prop(func(s *models.WatchStats) float64 { return s.HoursWatched }),
+		"ratedTitles":           prop(func(s *models.WatchStats) int { return s.RatedTitles }),
+		"averagePersonalRating": prop(func(s *models.WatchStats) float64 { return s.AveragePersonalRating }),
+		"averageTMDBRating":     prop(func(s *models.WatchStats) float64 { return s.AverageTMDBRating }),
+		"perMonth":              object(periodType, prop(func(s *models.WatchStats) []models.PeriodCount { return s.PerMonth })),
+		"perYear":               object(periodType, prop(func(s *models.WatchStats) []models.PeriodCount { return s.PerYear })),
+		"decades":               object(periodType, prop(func(s *models.WatchStats) []models.PeriodCount { return s.Decades })),
+		"topGenres":             object(nameCountType, prop(func(s *models.WatchStats) []models.NameCount { return s.TopGenres })),
+		"topDirectors":          object(nameCountType, prop(func(s *models.WatchStats) []models.NameCount { return s.TopDirectors })),
+		"topActors":             object(nameCountType, prop(func(s *models.WatchStats) []models.NameCount { return s.TopActors })),
+	}}
+
+	pageArgs := func(extra map[string]*graphql.Argument) map[string]*graphql.Argument {
+		args := map[string]*graphql.Argument{"page": {Default: 1}}
+		for name, arg := range extra {
+			args[name] = arg
+		}
+		return args
+	}
+	discoverArgs := pageArgs(map[string]*graphql.Argument{
+		"genre": {Type: "Int"}, "year": {Type: "Int"}, "rating": {Type: "Float"},
+		"sortBy": {}, "sortOrder": {}, "certification": {},
+	})
+	watchlistItemArgs := map[string]*graphql.Argument{"type": {Required: true}, "id": {Type: "Int", Required: true}}
+
+	queryType := &graphql.Object{Name: "Query", Fields: graphql.Fields{
+		"movie": {
+			Type: movieType,
+			Args: map[string]*graphql.Argument{"id": {Type: "Int", Required: true}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, err := p.Args.RequireInt("id")
+				if err != nil {
+					return nil, err
+				}
+				details, err := h.loadMovieDetails(p.Context, id)
+				if err != nil {
+					return nil, err
+				}
+				return &gqlMovie{id: id, summary: &details.Movie}, nil
+			},
+		},
+		"tvShow": {
+			Type: tvShowType,
+			Args: map[string]*graphql.Argument{"id": {Type: "Int", Required: true}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, err := p.Args.RequireInt("id")
+				if err != nil {
+					return nil, err
+				}
+				details, err := h.loadTVShowDetails(p.Context, id)
+				if err != nil {
+					return nil, err
+				}
+				return &gqlTVShow{id: id, summary: &details.TVShow}, nil
+			},
+		},
+		"movies": {
+			Type: moviePageType,
+			Args: pageArgs(map[string]*graphql.Argument{"category": {Default: "popular"}}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				category, _ := p.Args.String("category")
+				page, _ := p.Args.Int("page")
+				return h.fetchMovies(category, page)
+			},
+		},
+		"tvShows": {
+			Type: tvShowPageType,
+			Args: pageArgs(map[string]*graphql.Argument{"category": {Default: "popular"}}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				category, _ := p.Args.String("category")
+				page, _ := p.Args.Int("page")
+				return h.fetchTVShows(category, page)
+			},
+		},
+		"searchMovies": {
+			Type: moviePageType,
+			Args: pageArgs(map[string]*graphql.Argument{"query": {Required: true}}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				query, err := p.Args.RequireString("query")
+				if err != nil {
+					return nil, err
+				}
+				page, _ := p.Args.Int("page")
+				return h.tmdbService.SearchMovies(query, page)
+			},
+		},
+		"searchTVShows": {
+			Type: tvShowPageType,
+			Args: pageArgs(map[string]*graphql.Argument{"query": {Required: true}}),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				query, err := p.Args.RequireString("query")
+				if err != nil {
+					return nil, err
+				}
+				page, _ := p.Args.Int("page")
+				return h.tmdbService.SearchTVShows(query, page)
+			},
+		},
+		"discoverMovies": {
+			Type: moviePageType,
+			Args: discoverArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				page, _ := p.Args.Int("page")
+				return h.tmdbService.DiscoverMovies(discoverFilters(p.Args), page)
+			},
+		},
+		"discoverTVShows": {
+			Type: tvShowPageType,
+			Args: discoverArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				page, _ := p.Args.Int("page")
+				return h.tmdbService.DiscoverTVShows(discoverFilters(p.Args), page)
+			},
+		},
+		"genres": {
+			Type: genreType,
+			List: true,
+			Args: map[string]*graphql.Argument{"type": {Default: "movie"}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if t, _ := p.Args.String("type"); t == "tv" {
+					resp, err := h.tmdbService.GetTVGenres()
+					if err != nil {
+						return nil, err
+					}
+					return resp.Genres, nil
+				}
+				resp, err := h.tmdbService.GetMovieGenres()
+				if err != nil {
+					return nil, err
+				}
+				return resp.Genres, nil
+			},
+		},
+		"watchlist": {
+			Type:     watchlistItemType,
+			List:     true,
+			ListSize: graphqlListSize,
+			Args:     map[string]*graphql.Argument{"filter": {}, "type": {}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				var items []models.WatchlistItem
+				switch filter, _ := p.Args.String("filter"); filter {
+				case "watched":
+					items = h.watchlistService.GetWatchedItems()
+				case "unwatched":
+					items = h.watchlistService.GetUnwatchedItems()
+				default:
+					items = h.watchlistService.GetAllItems()
+				}
+				if itemType, ok := p.Args.String("type"); ok {
+					filtered := items[:0]
+					for _, item := range items {
+						if item.Type == itemType {
+							filtered = append(filtered, item)
+						}
+					}
+					items = filtered
+				}
+				return items, nil
+			},
+		},
+		"watchlistItem": {
+			Type: watchlistItemType,
+			Args: watchlistItemArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				itemType, id, err := watchlistArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return h.watchlistItemValue(itemType, id), nil
+			},
+		},
+		"stats": {
+			Type: statsType,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return h.statsService.Compute(), nil
+			},
+		},
+	}}
+
+	mutationType := &graphql.Object{Name: "Mutation", Fields: graphql.Fields{
+		"addToWatchlist": {
+			Type: watchlistItemType,
+			Args: watchlistItemArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				itemType, id, err := watchlistArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				item, err := h.watchlistSnapshot(p.Context, itemType, id)
+				if err != nil {
+					return nil, err
+				}
+				if err := h.watchlistService.AddItem(item); err != nil {
+					return nil, err
+				}
+				return h.watchlistItemValue(itemType, id), nil
+			},
+		},
+		"removeFromWatchlist": {
+			Scalar: "Boolean",
+			Args:   watchlistItemArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				itemType, id, err := watchlistArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				if err := h.watchlistService.RemoveItem(itemType, id); err != nil {
+					return nil, err
+				}
+				return true, nil
+			},
+		},
+		"toggleWatched": {
+			Type: watchlistItemType,
+			Args: watchlistItemArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				itemType, id, err := watchlistArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				if err := h.watchlistService.ToggleWatched(itemType, id); err != nil {
+					return nil, err
+				}
+				return h.watchlistItemValue(itemType, id), nil
+			},
+		},
+		"setRating": {
+			Type: watchlistItemType,
+			Args: map[string]*graphql.Argument{"type": {Required: true}, "id": {Type: "Int", Required: true}, "rating": {Type: "Float"}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				itemType, id, err := watchlistArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+				var rating *float64
+				if r, ok := p.Args.Float("rating"); ok {
+					rating = &r
+				}
+				if err := h.watchlistService.SetRating(itemType, id, rating); err != nil {
+					return nil, err
+				}
+				return h.watchlistItemValue(itemType, id), nil
+			},
+		},
+	}}
+
+	return &graphql.Schema{Query: queryType, Mutation: mutationType}
+}
+
+// watchlistSnapshot builds a watchlist entry from TMDB details, as the detail pages do
+func (h *Handler) watchlistSnapshot(ctx context.Context, itemType string, id int) (models.WatchlistItem, error) {
+	item := models.WatchlistItem{ID: id, Type: itemType}
+
+	if itemType == "tv" {
+		details, err := h.loadTVShowDetails(ctx, id)
+		if err != nil {
+			return item, err
+		}
+		item.Title = details.Name
+		item.PosterPath = details.PosterPath
+		item.ReleaseDate = details.FirstAirDate
+		item.VoteAverage = details.VoteAverage
+		return item, nil
+	}
+
+	details, err := h.loadMovieDetails(ctx, id)
+	if err != nil {
+		return item, err
+	}
+	item.Title = details.Title
+	item.PosterPath = details.PosterPath
+	item.ReleaseDate = details.ReleaseDate
+	item.VoteAverage = details.VoteAverage
+	return item, nil
+}
+
+func (h *Handler) GraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	schema := h.graphqlSchema
+
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				http.Error(w, "Invalid variables JSON", http.StatusBadRequest)
+				return
+			}
+		}
+		// GET requests may be cached or prefetched, so they can only run queries
+		queryOnly := *schema
+		queryOnly.Mutation = nil
+		schema = &queryOnly
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, graphqlMaxBodyBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Query == "" {
+		http.Error(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Query) > graphqlMaxQueryBytes {
+		http.Error(w, "Query too long", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	resp := schema.Execute(r.Context(), req)
+
+	status := http.StatusOK
+	if resp.Data == nil {
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Error encoding GraphQL response: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLRequestLimits(t *testing.T) {
+	router := NewRouter(newTestHandler(t))
+	nested := func(n int) string { return strings.Repeat("{a", n) + strings.Repeat("}", n) }
+	post := func(query string) *http.Request {
+		body, err := json.Marshal(map[string]string{"query": query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		want   string
+	}{
+		{"oversized body", post(nested(3_000_000)), http.StatusRequestEntityTooLarge, "Request body too large"},
+		{"long query", post(nested(30_000)), http.StatusRequestEntityTooLarge, "Query too long"},
+		{"long GET query", httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(nested(30_000)), nil), http.StatusRequestEntityTooLarge, "Query too long"},
+		{"deep nesting", post(nested(10_000)), http.StatusBadRequest, "document nests deeper than 64 levels"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, tt.req)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: status %d, body %.200s; want %d mentioning %q", tt.name, rec.Code, rec.Body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestGraphQLIntrospection(t *testing.T) {
+	router := NewRouter(newTestHandler(t))
+	query := `{
+		movie: __type(name: "Movie") { fields { name type { kind name ofType { name } } } }
+		query: __type(name: "Query") { fields { name args { name type { kind name ofType { name } } } } }
+	}`
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(query), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+
+	type typeRef struct {
+		Kind   string `json:"kind"`
+		Name   string `json:"name"`
+		OfType *struct {
+			Name string `json:"name"`
+		} `json:"ofType"`
+	}
+	type field struct {
+		Name string  `json:"name"`
+		Type typeRef `json:"type"`
+		Args []struct {
+			Name string  `json:"name"`
+			Type typeRef `json:"type"`
+		} `json:"args"`
+	}
+	var resp struct {
+		Data struct {
+			Movie struct{ Fields []field } `json:"movie"`
+			Query struct{ Fields []field } `json:"query"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	describe := func(t typeRef) string {
+		if t.OfType != nil {
+			return t.Kind + " " + t.OfType.Name
+		}
+		return t.Name
+	}
+	movie := map[string]string{}
+	for _, f := range resp.Data.Movie.Fields {
+		movie[f.Name] = describe(f.Type)
+	}
+	for name, want := range map[string]string{
+		"id": "Int", "title": "String", "posterPath": "String", "voteAverage": "Float", "adult": "Boolean",
+		"runtime": "Int", "genres": "LIST Genre", "videos": "LIST Video", "credits": "Credits", "inWatchlist": "Boolean",
+	} {
+		if movie[name] != want {
+			t.Errorf("Movie.%s is %q, want %q", name, movie[name], want)
+		}
+	}
+
+	var movieArgs string
+	for _, f := range resp.Data.Query.Fields {
+		if f.Name == "movie" {
+			for _, a := range f.Args {
+				movieArgs += a.Name + ": " + describe(a.Type)
+			}
+		}
+	}
+	if movieArgs != "id: NON_NULL Int" {
+		t.Errorf("Query.movie arguments = %q, want a required Int id", movieArgs)
+	}
+}
@@ -1,13 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
 	"muvi-discovery-app/internal/views"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"muvi-discovery-app/internal/graphql"
 	"muvi-discovery-app/internal/models"
 	"muvi-discovery-app/internal/services"
 	"muvi-discovery-app/web"
@@ -15,53 +25,219 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// Config holds optional behaviour, usually switched on from the environment
+type Config struct {
+	// DataDir holds the watchlist, image cache and other saved state; empty means "data"
+	DataDir string
+	// OMDBEnrichment looks up IMDb, Rotten Tomatoes and Metacritic ratings for
+	// every title in a list. It costs OMDB requests, so it's off by default.
+	OMDBEnrichment bool
+	// ImageCacheDir holds cached images; empty means "images" in DataDir
+	ImageCacheDir string
+	// ImageCacheSize bounds the on-disk image cache in bytes; 0 uses the default
+	ImageCacheSize int64
+	// DevMode serves static files and templates from web/ on disk, so edits
+	// show up on reload, and shows template errors in the browser
+	DevMode bool
+	// AdminToken guards the webhook settings, sent as a bearer token or as the
+	// HTTP Basic password. Webhooks are disabled while it's empty.
+	AdminToken string
+	// AllowPrivateWebhooks lets webhooks deliver to loopback and private
+	// network addresses
+	AllowPrivateWebhooks bool
+}
+
 type Handler struct {
+	config           Config
 	tmdbService      *services.TMDBService
 	omdbService      *services.OMDBService
 	watchlistService *services.WatchlistService
+	statsService     *services.StatsService
+	calendarService  *services.CalendarService
+	suggestService   *services.SuggestService
+	searchService    *services.WatchlistSearchService
+	ratingService    *services.RatingService
+	imageService     *services.ImageService
+	webhookService   *services.WebhookService
+	refreshService   *services.WatchlistRefreshService
+	scheduler        *services.Scheduler
+	graphqlSchema    *graphql.Schema
 	templates        views.Template
+	assets           *views.Assets
+
+	// streamsDone is closed on shutdown to end event streams
+	streamsDone      chan struct{}
+	closeStreamsOnce sync.Once
 }
 
-func NewHandler(tmdbService *services.TMDBService, omdbService *services.OMDBService) *Handler {
+func NewHandler(tmdbService *services.TMDBService, omdbService *services.OMDBService, config Config) *Handler {
+	if config.DataDir == "" {
+		config.DataDir = "data"
+	}
+	data := func(name string) string { return filepath.Join(config.DataDir, name) }
+	if config.ImageCacheDir == "" {
+		config.ImageCacheDir = data("images")
+	}
+
 	// Initialize watchlist service
-	watchlistService := services.NewWatchlistService("data/watchlist.json")
+	watchlistService := services.NewWatchlistService(data("watchlist.json"))
+
+	// Initialize static assets and templates
+	staticFS, err := fs.Sub(web.Static, "static")
+	if err != nil {
+		panic(err)
+	}
+	if config.DevMode {
+		staticFS = os.DirFS("web/static")
+	}
+	assets, err := views.NewAssets(staticFS, config.DevMode)
+	if err != nil {
+		panic(err)
+	}
+	var tpl views.Template
+	if config.DevMode {
+		tpl = views.Must(views.ParseDev(os.DirFS("web"), assets, "templates/*.html"))
+	} else {
+		tpl = views.Must(views.ParseFS(web.Templates, assets, "templates/*.html"))
+	}
 
-	// Initialize templates
-	tpl := views.Must(views.ParseFS(web.Templates, "templates/*.html"))
+	calendarService := services.NewCalendarService(tmdbService, watchlistService)
 
-	return &Handler{
+	h := &Handler{
+		config:           config,
 		tmdbService:      tmdbService,
 		omdbService:      omdbService,
 		watchlistService: watchlistService,
+		statsService:     services.NewStatsService(tmdbService, watchlistService),
+		calendarService:  calendarService,
+		suggestService:   services.NewSuggestService(tmdbService),
+		searchService:    services.NewWatchlistSearchService(tmdbService, watchlistService, data("watchlist_search.json")),
+		ratingService:    services.NewRatingService(tmdbService, omdbService),
+		imageService:     services.NewImageService(config.ImageCacheDir, config.ImageCacheSize),
+		webhookService:   services.NewWebhookService(data("webhooks.json"), data("webhook_deliveries.json"), watchlistService, calendarService),
+		refreshService:   services.NewWatchlistRefreshService(tmdbService, watchlistService, data("watchlist_changes.json")),
+		scheduler:        services.NewScheduler(),
 		templates:        tpl,
+		assets:           assets,
+		streamsDone:      make(chan struct{}),
 	}
+	h.graphqlSchema = h.newGraphQLSchema()
+	h.webhookService.SetAllowPrivateNetworks(config.AllowPrivateWebhooks)
+
+	// Background jobs
+	h.scheduler.Every("release webhooks", time.Minute, time.Hour, h.webhookService.NotifyReleases)
+	h.scheduler.Every("watchlist refresh", 2*time.Minute, time.Hour, h.refreshService.Refresh)
+
+	return h
+}
+
+// Close stops background jobs and finishes queued webhook deliveries, giving
+// up when ctx is done. Call it once the server has stopped taking requests.
+func (h *Handler) Close(ctx context.Context) error {
+	h.CloseStreams()
+	return errors.Join(h.scheduler.Stop(ctx), h.webhookService.Close(ctx))
 }
 
 type PageData struct {
-	Title           string
-	ContentTemplate string
-	Movies          []models.Movie
-	TVShows         []models.TVShow
-	MovieDetails    *models.MovieDetails
-	TVShowDetails   *models.TVShowDetails
-	Credits         *models.Credits
-	OMDBData        *models.OMDBMovie
-	WatchlistItems  []models.WatchlistItem
-	Genres          []models.Genre
-	SearchQuery     string
-	CurrentPage     int
-	TotalPages      int
-	Error           string
-	IsInWatchlist   bool
-	WatchlistCount  int
-	Videos          *models.VideosResponse
+	Title            string
+	ContentTemplate  string
+	Movies           []models.Movie
+	TVShows          []models.TVShow
+	MovieDetails     *models.MovieDetails
+	TVShowDetails    *models.TVShowDetails
+	Season           *models.SeasonDetails
+	Collection       *models.Collection
+	CollectionParts  []CollectionPart
+	Credits          *models.Credits
+	OMDBData         *models.OMDBMovie
+	Ratings          *models.AggregateRating
+	Region           string
+	Certification    string
+	ReleaseDates     []models.ReleaseDate
+	WatchlistItems   []models.WatchlistItem
+	Genres           []models.Genre
+	People           []models.Person
+	SearchQuery      string
+	SearchType       string
+	SearchTabQuery   string
+	SearchChips      []SearchChip
+	WatchlistFilter  string
+	WatchlistSort    string
+	WatchlistChanges []models.WatchlistChange
+	CurrentPage      int
+	TotalPages       int
+	PrevPageURL      string
+	NextPageURL      string
+	Error            string
+	Status           int // HTTP status shown on the error page
+	IsInWatchlist    bool
+	WatchlistButton  *WatchlistButton
+	WatchlistCount   int
+	Videos           *models.VideosResponse
+	Gallery          *ImageGallery
+	Stats            *models.WatchStats
+	MovieCerts       []models.Certification
+	TVCerts          []models.Certification
+	Calendar         []models.CalendarDay
+	CalendarScope    string
+	CalendarDays     int
+	Webhooks         []models.Webhook
+	WebhookEvents    []string
+	Deliveries       []models.WebhookDelivery
 }
 
 func (h *Handler) renderTemplate(w http.ResponseWriter, r *http.Request, name string, data PageData) {
+	h.renderStatus(w, r, http.StatusOK, name, data)
+}
+
+// renderStatus renders a page with the given status. If the template fails
+// nothing of it has been sent yet, so the 500 error page goes out instead.
+func (h *Handler) renderStatus(w http.ResponseWriter, r *http.Request, status int, name string, data PageData) {
 	// Add watchlist count to all pages
 	data.WatchlistCount = h.watchlistService.GetItemCount()
 
-	h.templates.Execute(w, r, name, data)
+	err := h.templates.Execute(w, r, status, name, data)
+	if err == nil {
+		return
+	}
+	log.Printf("Error rendering %s: %v", name, err)
+	if data.ContentTemplate == errorTemplate {
+		// The error page itself is broken
+		http.Error(w, "Failed to execute template", http.StatusInternalServerError)
+		return
+	}
+	h.renderError(w, r, http.StatusInternalServerError, "")
+}
+
+// errorTemplate is the content template of the error page
+const errorTemplate = "error-content"
+
+// renderError sends the error page for status, or just its content for a
+// partial request. message replaces the page's default explanation when set.
+func (h *Handler) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	data := PageData{
+		Title:           http.StatusText(status),
+		ContentTemplate: errorTemplate,
+		Status:          status,
+		Error:           message,
+	}
+	h.renderPage(w, r, status, errorTemplate, data)
+}
+
+// NotFound serves the 404 page for URLs no route matches
+func (h *Handler) NotFound(w http.ResponseWriter, r *http.Request) {
+	h.renderError(w, r, http.StatusNotFound, "")
+}
+
+// upstreamStatus picks the page status for a failed TMDB or OMDB lookup
+func upstreamStatus(err error) int {
+	switch {
+	case services.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrContentRestricted):
+		return http.StatusForbidden
+	}
+	return http.StatusBadGateway
 }
 
 func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
@@ -71,36 +247,38 @@ func (h *Handler) Home(w http.ResponseWriter, r *http.Request) {
 
 	// Get trending movies
 	log.Printf("Fetching trending movies...")
-	trendingMovies, err := h.tmdbService.GetTrendingMovies("week")
+	trendingMovies, err := h.tmdbService.GetTrendingMovies("week", 1)
 	if err != nil {
 		log.Printf("Error fetching trending movies: %v", err)
-		data.Error = "Failed to load trending movies"
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load trending movies"))
+		return
+	}
+	log.Printf("Successfully fetched %d trending movies", len(trendingMovies.Results))
+	h.suggestService.RecordMovies(trendingMovies.Results)
+	h.ratingService.AnnotateMovies(trendingMovies.Results, h.config.OMDBEnrichment)
+	// Limit to first 6 movies for homepage
+	if len(trendingMovies.Results) > 6 {
+		data.Movies = trendingMovies.Results[:6]
 	} else {
-		log.Printf("Successfully fetched %d trending movies", len(trendingMovies.Results))
-		// Limit to first 6 movies for homepage
-		if len(trendingMovies.Results) > 6 {
-			data.Movies = trendingMovies.Results[:6]
-		} else {
-			data.Movies = trendingMovies.Results
-		}
+		data.Movies = trendingMovies.Results
 	}
 
 	// Get trending TV shows
 	log.Printf("Fetching trending TV shows...")
-	trendingTV, err := h.tmdbService.GetTrendingTVShows("week")
+	trendingTV, err := h.tmdbService.GetTrendingTVShows("week", 1)
 	if err != nil {
 		log.Printf("Error fetching trending TV shows: %v", err)
-		if data.Error == "" {
-			data.Error = "Failed to load trending TV shows"
-		}
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load trending TV shows"))
+		return
+	}
+	log.Printf("Successfully fetched %d trending TV shows", len(trendingTV.Results))
+	h.suggestService.RecordTVShows(trendingTV.Results)
+	h.ratingService.AnnotateTVShows(trendingTV.Results, h.config.OMDBEnrichment)
+	// Limit to first 6 shows for homepage
+	if len(trendingTV.Results) > 6 {
+		data.TVShows = trendingTV.Results[:6]
 	} else {
-		log.Printf("Successfully fetched %d trending TV shows", len(trendingTV.Results))
-		// Limit to first 6 shows for homepage
-		if len(trendingTV.Results) > 6 {
-			data.TVShows = trendingTV.Results[:6]
-		} else {
-			data.TVShows = trendingTV.Results
-		}
+		data.TVShows = trendingTV.Results
 	}
 
 	log.Printf("Rendering template with %d movies and %d TV shows", len(data.Movies), len(data.TVShows))
@@ -125,28 +303,79 @@ func (h *Handler) Movies(w http.ResponseWriter, r *http.Request) {
 		category = "popular"
 	}
 
-	var moviesResp *models.TMDBResponse[models.Movie]
+	moviesResp, err := h.fetchMovies(category, page)
+	if err == errUnknownCategory {
+		moviesResp, err = h.fetchMovies("popular", page)
+	}
+
+	if err != nil {
+		log.Printf("Error fetching movies: %v", err)
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load movies"))
+		return
+	}
+
+	data.Movies = moviesResp.Results
+	data.CurrentPage = moviesResp.Page
+	data.TotalPages = moviesResp.TotalPages
+	setPageLinks(&data, r)
+
+	h.renderPage(w, r, http.StatusOK, "movie-results", data)
+}
+
+var errUnknownCategory = errors.New("unknown category")
+
+// fetchMovies loads one page of a movie list category such as "popular" or "top_rated"
+func (h *Handler) fetchMovies(category string, page int) (*models.TMDBResponse[models.Movie], error) {
+	var resp *models.TMDBResponse[models.Movie]
 	var err error
 
 	switch category {
+	case "popular":
+		resp, err = h.tmdbService.GetPopularMovies(page)
 	case "top_rated":
-		moviesResp, err = h.tmdbService.GetTopRatedMovies(page)
+		resp, err = h.tmdbService.GetTopRatedMovies(page)
 	case "now_playing":
-		moviesResp, err = h.tmdbService.GetNowPlayingMovies(page)
+		resp, err = h.tmdbService.GetNowPlayingMovies(page)
+	case "upcoming":
+		resp, err = h.tmdbService.GetUpcomingMovies(page)
+	case "trending":
+		resp, err = h.tmdbService.GetTrendingMovies("week", page)
 	default:
-		moviesResp, err = h.tmdbService.GetPopularMovies(page)
+		return nil, errUnknownCategory
 	}
 
-	if err != nil {
-		log.Printf("Error fetching movies: %v", err)
-		data.Error = "Failed to load movies"
-	} else {
-		data.Movies = moviesResp.Results
-		data.CurrentPage = moviesResp.Page
-		data.TotalPages = moviesResp.TotalPages
+	if err == nil {
+		h.suggestService.RecordMovies(resp.Results)
+		h.ratingService.AnnotateMovies(resp.Results, h.config.OMDBEnrichment)
 	}
+	return resp, err
+}
 
-	h.renderTemplate(w, r, "base.html", data)
+// fetchTVShows loads one page of a TV list category such as "popular" or "top_rated"
+func (h *Handler) fetchTVShows(category string, page int) (*models.TMDBResponse[models.TVShow], error) {
+	var resp *models.TMDBResponse[models.TVShow]
+	var err error
+
+	switch category {
+	case "popular":
+		resp, err = h.tmdbService.GetPopularTVShows(page)
+	case "top_rated":
+		resp, err = h.tmdbService.GetTopRatedTVShows(page)
+	case "airing_today":
+		resp, err = h.tmdbService.GetAiringTodayTVShows(page)
+	case "on_the_air":
+		resp, err = h.tmdbService.GetOnTheAirTVShows(page)
+	case "trending":
+		resp, err = h.tmdbService.GetTrendingTVShows("week", page)
+	default:
+		return nil, errUnknownCategory
+	}
+
+	if err == nil {
+		h.suggestService.RecordTVShows(resp.Results)
+		h.ratingService.AnnotateTVShows(resp.Results, h.config.OMDBEnrichment)
+	}
+	return resp, err
 }
 
 func (h *Handler) MovieDetails(w http.ResponseWriter, r *http.Request) {
@@ -155,7 +384,7 @@ func (h *Handler) MovieDetails(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusNotFound, "")
 		return
 	}
 
@@ -168,8 +397,7 @@ func (h *Handler) MovieDetails(w http.ResponseWriter, r *http.Request) {
 	movieDetails, err := h.tmdbService.GetMovieDetails(id)
 	if err != nil {
 		log.Printf("Error fetching movie details: %v", err)
-		data.Error = "Failed to load movie details"
-		h.renderTemplate(w, r, "base.html", data)
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load movie details"))
 		return
 	}
 
@@ -178,6 +406,7 @@ func (h *Handler) MovieDetails(w http.ResponseWriter, r *http.Request) {
 
 	// Check if in watchlist
 	data.IsInWatchlist = h.watchlistService.IsInWatchlist("movie", id)
+	data.WatchlistButton = watchlistButton("movie", id, movieDetails.Title, movieDetails.PosterPath, movieDetails.ReleaseDate, movieDetails.VoteAverage, data.IsInWatchlist)
 
 	// Get credits
 	credits, err := h.tmdbService.GetMovieCredits(id)
@@ -200,15 +429,33 @@ func (h *Handler) MovieDetails(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get OMDB data if IMDB ID is available
+	omdbOK := true
 	if movieDetails.IMDBId != "" {
 		omdbData, err := h.omdbService.GetMovieByIMDBID(movieDetails.IMDBId)
 		if err != nil {
 			log.Printf("Error fetching OMDB data: %v", err)
+			omdbOK = errors.Is(err, services.ErrOMDBNotFound)
 		} else {
 			data.OMDBData = omdbData
 		}
 	}
 
+	data.Ratings = h.ratingService.Aggregate(movieDetails.VoteAverage, movieDetails.VoteCount, data.OMDBData)
+	if omdbOK {
+		h.ratingService.Record("movie", id, data.Ratings)
+	}
+
+	// Release dates and certification for the configured region
+	data.Region = h.tmdbService.Region()
+	if releases, err := h.tmdbService.GetMovieReleaseDates(id); err != nil {
+		log.Printf("Error fetching movie release dates: %v", err)
+	} else {
+		data.Certification = releases.Certification(data.Region)
+		data.ReleaseDates = releases.ForCountry(data.Region)
+	}
+
+	data.Gallery = h.imageGallery("movie", id, imageLanguage(r))
+
 	h.renderTemplate(w, r, "base.html", data)
 }
 
@@ -230,26 +477,23 @@ func (h *Handler) TVShows(w http.ResponseWriter, r *http.Request) {
 		category = "popular"
 	}
 
-	var tvResp *models.TMDBResponse[models.TVShow]
-	var err error
-
-	switch category {
-	case "top_rated":
-		tvResp, err = h.tmdbService.GetTopRatedTVShows(page)
-	default:
-		tvResp, err = h.tmdbService.GetPopularTVShows(page)
+	tvResp, err := h.fetchTVShows(category, page)
+	if err == errUnknownCategory {
+		tvResp, err = h.fetchTVShows("popular", page)
 	}
 
 	if err != nil {
 		log.Printf("Error fetching TV shows: %v", err)
-		data.Error = "Failed to load TV shows"
-	} else {
-		data.TVShows = tvResp.Results
-		data.CurrentPage = tvResp.Page
-		data.TotalPages = tvResp.TotalPages
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load TV shows"))
+		return
 	}
 
-	h.renderTemplate(w, r, "base.html", data)
+	data.TVShows = tvResp.Results
+	data.CurrentPage = tvResp.Page
+	data.TotalPages = tvResp.TotalPages
+	setPageLinks(&data, r)
+
+	h.renderPage(w, r, http.StatusOK, "tv-results", data)
 }
 
 func (h *Handler) TVShowDetails(w http.ResponseWriter, r *http.Request) {
@@ -258,7 +502,7 @@ func (h *Handler) TVShowDetails(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
+		h.renderError(w, r, http.StatusNotFound, "")
 		return
 	}
 
@@ -271,8 +515,7 @@ func (h *Handler) TVShowDetails(w http.ResponseWriter, r *http.Request) {
 	tvDetails, err := h.tmdbService.GetTVShowDetails(id)
 	if err != nil {
 		log.Printf("Error fetching TV show details: %v", err)
-		data.Error = "Failed to load TV show details"
-		h.renderTemplate(w, r, "base.html", data)
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load TV show details"))
 		return
 	}
 
@@ -281,6 +524,7 @@ func (h *Handler) TVShowDetails(w http.ResponseWriter, r *http.Request) {
 
 	// Check if in watchlist
 	data.IsInWatchlist = h.watchlistService.IsInWatchlist("tv", id)
+	data.WatchlistButton = watchlistButton("tv", id, tvDetails.Name, tvDetails.PosterPath, tvDetails.FirstAirDate, tvDetails.VoteAverage, data.IsInWatchlist)
 
 	// Get videos (trailers, teasers, etc.)
 	videos, err := h.tmdbService.GetTVShowVideos(id)
@@ -295,18 +539,277 @@ func (h *Handler) TVShowDetails(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get OMDB data if IMDB ID is available
+	omdbOK := true
 	if tvDetails.ExternalIDs.IMDBID != "" {
-		omdbData, err := h.omdbService.GetMovieByIMDBID(tvDetails.ExternalIDs.IMDBID)
+		series, err := h.omdbService.GetSeriesByIMDBID(tvDetails.ExternalIDs.IMDBID)
 		if err != nil {
 			log.Printf("Error fetching OMDB data: %v", err)
+			omdbOK = errors.Is(err, services.ErrOMDBNotFound)
 		} else {
-			data.OMDBData = omdbData
+			data.OMDBData = &series.OMDBMovie
 		}
 	}
 
+	data.Ratings = h.ratingService.Aggregate(tvDetails.VoteAverage, tvDetails.VoteCount, data.OMDBData)
+	if omdbOK {
+		h.ratingService.Record("tv", id, data.Ratings)
+	}
+
+	data.Region = h.tmdbService.Region()
+	if ratings, err := h.tmdbService.GetTVContentRatings(id); err != nil {
+		log.Printf("Error fetching TV content ratings: %v", err)
+	} else {
+		data.Certification = ratings.ForCountry(data.Region)
+	}
+
+	data.Gallery = h.imageGallery("tv", id, imageLanguage(r))
+
 	h.renderTemplate(w, r, "base.html", data)
 }
 
+// defaultImageLanguage limits galleries to English and textless images unless
+// the image_language parameter asks for another language or "all"
+const defaultImageLanguage = "en"
+
+// ImageGallery is a title's posters, backdrops and logos on its detail page
+type ImageGallery struct {
+	Type     string
+	ID       int
+	Images   *models.ImagesResponse
+	Language string // "" when showing every language
+	// InWatchlist enables picking a poster; CustomPoster is the one picked
+	InWatchlist  bool
+	CustomPoster string
+}
+
+// imageLanguage reads the image_language query parameter: an ISO 639-1 code,
+// or "all" for no filtering
+func imageLanguage(r *http.Request) string {
+	lang := strings.ToLower(r.URL.Query().Get("image_language"))
+	switch {
+	case lang == "all":
+		return ""
+	case len(lang) == 2 && lang[0] >= 'a' && lang[0] <= 'z' && lang[1] >= 'a' && lang[1] <= 'z':
+		return lang
+	}
+	return defaultImageLanguage
+}
+
+// checkContent returns ErrContentRestricted for a title the content profile
+// hides, so its credits, videos and other parts can't be loaded on their own.
+// The check goes through the cached details; without a profile it's free.
+func (h *Handler) checkContent(itemType string, id int) error {
+	if !h.tmdbService.ContentProfile().Restricted() {
+		return nil
+	}
+	var err error
+	if itemType == "tv" {
+		_, err = h.tmdbService.GetTVShowDetails(id)
+	} else {
+		_, err = h.tmdbService.GetMovieDetails(id)
+	}
+	return err
+}
+
+// getImages loads a movie's or TV show's images
+func (h *Handler) getImages(itemType string, id int, language string) (*models.ImagesResponse, error) {
+	if err := h.checkContent(itemType, id); err != nil {
+		return nil, err
+	}
+	if itemType == "tv" {
+		return h.tmdbService.GetTVImages(id, language)
+	}
+	return h.tmdbService.GetMovieImages(id, language)
+}
+
+// imageGallery builds a detail page's gallery; it is nil if the images can't be loaded
+func (h *Handler) imageGallery(itemType string, id int, language string) *ImageGallery {
+	images, err := h.getImages(itemType, id, language)
+	if err != nil {
+		log.Printf("Error fetching images for %s %d: %v", itemType, id, err)
+		return nil
+	}
+
+	gallery := &ImageGallery{Type: itemType, ID: id, Images: images, Language: language}
+	if item, ok := h.watchlistService.GetItem(itemType, id); ok {
+		gallery.InWatchlist = true
+		if item.CustomPosterPath != nil {
+			gallery.CustomPoster = *item.CustomPosterPath
+		}
+	}
+	return gallery
+}
+
+var errUnknownPoster = errors.New("poster is not one of the title's images")
+
+// setWatchlistPoster picks the poster a watchlist item shows. The path must
+// be one of the title's TMDB posters; nil or "" restores the default.
+func (h *Handler) setWatchlistPoster(itemType string, id int, posterPath *string) error {
+	if posterPath != nil && *posterPath == "" {
+		posterPath = nil
+	}
+	if posterPath != nil {
+		images, err := h.getImages(itemType, id, "")
+		if err != nil {
+			return err
+		}
+		if !images.HasPoster(*posterPath) {
+			return errUnknownPoster
+		}
+	}
+	return h.watchlistService.SetPoster(itemType, id, posterPath)
+}
+
+func (h *Handler) TVSeason(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.renderError(w, r, http.StatusNotFound, "")
+		return
+	}
+	seasonNumber, err := strconv.Atoi(vars["season"])
+	if err != nil {
+		h.renderError(w, r, http.StatusNotFound, "")
+		return
+	}
+
+	data := PageData{
+		Title:           "Season",
+		ContentTemplate: "tv-season-content",
+	}
+
+	tvDetails, season, err := h.fetchSeason(id, seasonNumber)
+	if err != nil {
+		log.Printf("Error fetching season %d of TV show %d: %v", seasonNumber, id, err)
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load season"))
+		return
+	}
+
+	data.TVShowDetails = tvDetails
+	data.Season = season
+	data.Title = fmt.Sprintf("%s - %s", tvDetails.Name, season.Name)
+
+	h.renderTemplate(w, r, "base.html", data)
+}
+
+// restrictedMessage is shown for titles hidden by the content profile
+const restrictedMessage = "This title isn't available with the current content settings"
+
+// loadErrorMessage explains a failed details lookup, telling a title hidden
+// by the content profile apart from an upstream failure. Unknown titles get
+// the 404 page's own wording.
+func loadErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, services.ErrContentRestricted):
+		return restrictedMessage
+	case services.IsNotFound(err):
+		return ""
+	}
+	return fallback
+}
+
+// fetchSeason loads a season from TMDB and adds each episode's IMDb rating
+// from OMDB. Without OMDB data the season is returned as TMDB has it.
+func (h *Handler) fetchSeason(tvID, seasonNumber int) (*models.TVShowDetails, *models.SeasonDetails, error) {
+	tvDetails, err := h.tmdbService.GetTVShowDetails(tvID)
+	if err != nil {
+		return nil, nil, err
+	}
+	season, err := h.tmdbService.GetTVSeason(tvID, seasonNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if imdbID := tvDetails.ExternalIDs.IMDBID; imdbID != "" {
+		omdbSeason, err := h.omdbService.GetSeason(imdbID, seasonNumber)
+		if err != nil {
+			if !errors.Is(err, services.ErrOMDBQuotaExceeded) {
+				log.Printf("Error fetching OMDB season %d of %s: %v", seasonNumber, imdbID, err)
+			}
+			return tvDetails, season, nil
+		}
+
+		byNumber := make(map[int]models.OMDBSeasonEpisode, len(omdbSeason.Episodes))
+		for _, e := range omdbSeason.Episodes {
+			byNumber[int(e.Episode)] = e
+		}
+		for i := range season.Episodes {
+			if e, ok := byNumber[season.Episodes[i].EpisodeNumber]; ok {
+				season.Episodes[i].IMDBID = e.IMDBID
+				if e.IMDBRating != "N/A" {
+					season.Episodes[i].IMDBRating = e.IMDBRating
+				}
+			}
+		}
+	}
+
+	return tvDetails, season, nil
+}
+
+// CollectionPart is a movie in a collection with its watchlist status
+type CollectionPart struct {
+	models.Movie
+	InWatchlist bool `json:"in_watchlist"`
+	Watched     bool `json:"watched"`
+}
+
+func (h *Handler) Collection(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.renderError(w, r, http.StatusNotFound, "")
+		return
+	}
+
+	data := PageData{
+		Title:           "Collection",
+		ContentTemplate: "collection-content",
+	}
+
+	collection, err := h.tmdbService.GetCollection(id)
+	if err != nil {
+		log.Printf("Error fetching collection %d: %v", id, err)
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load collection"))
+		return
+	}
+
+	data.Collection = collection
+	data.CollectionParts = h.collectionParts(collection)
+	data.Title = collection.Name
+
+	h.renderTemplate(w, r, "base.html", data)
+}
+
+// collectionParts pairs each movie in a collection with its watchlist status
+func (h *Handler) collectionParts(collection *models.Collection) []CollectionPart {
+	parts := make([]CollectionPart, len(collection.Parts))
+	for i, movie := range collection.Parts {
+		parts[i] = CollectionPart{Movie: movie}
+		if item, ok := h.watchlistService.GetItem("movie", movie.ID); ok {
+			parts[i].InWatchlist = true
+			parts[i].Watched = item.Watched
+		}
+	}
+	return parts
+}
+
+// addCollectionToWatchlist adds every movie in a collection that isn't in
+// the watchlist yet, returning how many were added
+func (h *Handler) addCollectionToWatchlist(collection *models.Collection) (int, error) {
+	items := make([]models.WatchlistItem, len(collection.Parts))
+	for i, movie := range collection.Parts {
+		items[i] = models.WatchlistItem{
+			ID:          movie.ID,
+			Type:        "movie",
+			Title:       movie.Title,
+			PosterPath:  movie.PosterPath,
+			ReleaseDate: movie.ReleaseDate,
+			VoteAverage: movie.VoteAverage,
+		}
+	}
+	return h.watchlistService.AddItems(items)
+}
+
 func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 	data := PageData{
 		Title:           "Search",
@@ -320,42 +823,39 @@ func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data.SearchQuery = query
-
-	page := 1
-	if p := r.URL.Query().Get("page"); p != "" {
-		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
-			page = parsed
-		}
+	page := parsePage(r)
+
+	// A type: filter in the query wins over the selected tab
+	tabType := r.URL.Query().Get("type")
+	parsed := parseSearchQuery(query)
+	data.SearchType = tabType
+	if parsed.Type != "" {
+		data.SearchType = parsed.Type
 	}
-
-	mediaType := r.URL.Query().Get("type")
-	if mediaType == "" {
-		mediaType = "movie"
+	switch data.SearchType {
+	case "movie", "tv", "person":
+	default:
+		data.SearchType = "all"
 	}
+	data.SearchTabQuery = parsed.TabQuery()
+	data.SearchChips = parsed.Chips(tabType)
 
-	if mediaType == "tv" {
-		tvResp, err := h.tmdbService.SearchTVShows(query, page)
-		if err != nil {
-			log.Printf("Error searching TV shows: %v", err)
-			data.Error = "Failed to search TV shows"
-		} else {
-			data.TVShows = tvResp.Results
-			data.CurrentPage = tvResp.Page
-			data.TotalPages = tvResp.TotalPages
-		}
+	var err error
+	if parsed.Text == "" {
+		err = h.discoverFromQuery(&data, parsed, page)
 	} else {
-		moviesResp, err := h.tmdbService.SearchMovies(query, page)
-		if err != nil {
-			log.Printf("Error searching movies: %v", err)
-			data.Error = "Failed to search movies"
-		} else {
-			data.Movies = moviesResp.Results
-			data.CurrentPage = moviesResp.Page
-			data.TotalPages = moviesResp.TotalPages
-		}
+		err = h.searchWithQuery(&data, parsed, page)
+	}
+	if err != nil {
+		log.Printf("Error searching for %q: %v", query, err)
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to search"))
+		return
 	}
+	h.ratingService.AnnotateMovies(data.Movies, h.config.OMDBEnrichment)
+	h.ratingService.AnnotateTVShows(data.TVShows, h.config.OMDBEnrichment)
+	setPageLinks(&data, r)
 
-	h.renderTemplate(w, r, "base.html", data)
+	h.renderPage(w, r, http.StatusOK, "search-results", data)
 }
 
 func (h *Handler) Discover(w http.ResponseWriter, r *http.Request) {
@@ -368,8 +868,18 @@ func (h *Handler) Discover(w http.ResponseWriter, r *http.Request) {
 	movieGenres, err := h.tmdbService.GetMovieGenres()
 	if err != nil {
 		log.Printf("Error fetching genres: %v", err)
-	} else {
-		data.Genres = movieGenres.Genres
+		h.renderError(w, r, upstreamStatus(err), loadErrorMessage(err, "Failed to load genres"))
+		return
+	}
+	data.Genres = movieGenres.Genres
+
+	// Content ratings for the configured region
+	data.Region = h.tmdbService.Region()
+	if data.MovieCerts, err = h.tmdbService.GetCertifications("movie"); err != nil {
+		log.Printf("Error fetching movie certifications: %v", err)
+	}
+	if data.TVCerts, err = h.tmdbService.GetCertifications("tv"); err != nil {
+		log.Printf("Error fetching TV certifications: %v", err)
 	}
 
 	h.renderTemplate(w, r, "base.html", data)
@@ -382,19 +892,143 @@ func (h *Handler) Watchlist(w http.ResponseWriter, r *http.Request) {
 	}
 
 	filter := r.URL.Query().Get("filter")
+	data.WatchlistFilter = filter
+	data.WatchlistSort = r.URL.Query().Get("sort")
+
+	// A search keeps its relevance order unless a sort is picked; the watched filter narrows it
+	if query := strings.TrimSpace(r.URL.Query().Get("q")); query != "" {
+		data.SearchQuery = query
+		for _, item := range h.searchService.Search(query) {
+			if (filter == "watched" && !item.Watched) || (filter == "unwatched" && item.Watched) {
+				continue
+			}
+			data.WatchlistItems = append(data.WatchlistItems, item)
+		}
+	} else {
+		switch filter {
+		case "watched":
+			data.WatchlistItems = h.watchlistService.GetWatchedItems()
+		case "unwatched":
+			data.WatchlistItems = h.watchlistService.GetUnwatchedItems()
+		default:
+			data.WatchlistItems = h.watchlistService.GetAllItems()
+		}
+		if data.WatchlistSort == "" {
+			data.WatchlistSort = "added"
+		}
+	}
+
+	data.WatchlistChanges = h.refreshService.Changes(watchlistPageChanges)
+
+	h.ratingService.AnnotateWatchlist(data.WatchlistItems, h.config.OMDBEnrichment)
+	if err := sortWatchlist(data.WatchlistItems, data.WatchlistSort); err != nil {
+		data.WatchlistSort = ""
+	}
+	if data.WatchlistSort == "score" {
+		// Sorts by what is cached; the rest is looked up for the next load
+		h.ratingService.Prefetch(nil, nil, data.WatchlistItems)
+	}
 
-	switch filter {
-	case "watched":
-		data.WatchlistItems = h.watchlistService.GetWatchedItems()
-	case "unwatched":
-		data.WatchlistItems = h.watchlistService.GetUnwatchedItems()
+	h.renderTemplate(w, r, "base.html", data)
+}
+
+// watchlistPageChanges is how many recent title changes the watchlist page shows
+const watchlistPageChanges = 5
+
+var errUnknownSort = errors.New("unknown sort")
+
+// sortWatchlist orders items by "added" (newest first), "score" (aggregate
+// score, best first, unscored last) or "title". An empty sort leaves the order alone.
+func sortWatchlist(items []models.WatchlistItem, by string) error {
+	switch by {
+	case "":
+	case "added":
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].AddedAt.After(items[j].AddedAt)
+		})
+	case "score":
+		sort.SliceStable(items, func(i, j int) bool {
+			return scoreLess(items[i].AggregateScore, items[j].AggregateScore, false)
+		})
+	case "title":
+		sort.SliceStable(items, func(i, j int) bool {
+			return strings.ToLower(items[i].Title) < strings.ToLower(items[j].Title)
+		})
 	default:
-		data.WatchlistItems = h.watchlistService.GetAllItems()
+		return errUnknownSort
+	}
+	return nil
+}
+
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		Title:           "My Stats",
+		ContentTemplate: "stats-content",
+		Stats:           h.statsService.Compute(),
+	}
+
+	h.renderTemplate(w, r, "base.html", data)
+}
+
+func (h *Handler) Calendar(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		Title:           "Release Calendar",
+		ContentTemplate: "calendar-content",
 	}
 
+	scope, days, err := parseCalendarQuery(r)
+	if err != nil {
+		scope = "all"
+	}
+	data.CalendarScope = scope
+	data.CalendarDays = days
+	data.Calendar = services.GroupByDay(h.calendarService.Entries(days, scope == "watchlist"))
+
 	h.renderTemplate(w, r, "base.html", data)
 }
 
+// CalendarICS serves the calendar as an iCalendar feed for calendar apps to subscribe to
+func (h *Handler) CalendarICS(w http.ResponseWriter, r *http.Request) {
+	scope, days, err := parseCalendarQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="muvi-calendar.ics"`)
+	entries := h.calendarService.Entries(days, scope == "watchlist")
+	if err := services.WriteICS(w, entries, scheme+"://"+r.Host); err != nil {
+		log.Printf("Error writing calendar feed: %v", err)
+	}
+}
+
+var errUnknownScope = errors.New("scope must be all or watchlist")
+
+// parseCalendarQuery reads the calendar's "scope" (all or watchlist, default
+// all) and "days" (1 to services.MaxCalendarDays) query parameters
+func parseCalendarQuery(r *http.Request) (string, int, error) {
+	days := services.DefaultCalendarDays
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+		days = min(d, services.MaxCalendarDays)
+	}
+
+	scope := r.URL.Query().Get("scope")
+	switch scope {
+	case "":
+		scope = "all"
+	case "all", "watchlist":
+	default:
+		return "", days, errUnknownScope
+	}
+	return scope, days, nil
+}
+
 // API Handlers
 func (h *Handler) APISearch(w http.ResponseWriter, r *http.Request) {
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
@@ -413,21 +1047,66 @@ func (h *Handler) APISearch(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	if mediaType == "tv" {
+	switch mediaType {
+	case "movie":
+		moviesResp, err := h.tmdbService.SearchMovies(query, page)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(moviesResp)
+	case "tv":
 		tvResp, err := h.tmdbService.SearchTVShows(query, page)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		json.NewEncoder(w).Encode(tvResp)
-	} else {
-		moviesResp, err := h.tmdbService.SearchMovies(query, page)
+	case "person":
+		peopleResp, err := h.tmdbService.SearchPeople(query, page)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		json.NewEncoder(w).Encode(moviesResp)
+		json.NewEncoder(w).Encode(peopleResp)
+	default:
+		multiResp, err := h.tmdbService.SearchMulti(query, page)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(MultiSearchResponse{
+			TMDBResponse: multiResp,
+			Groups:       models.GroupSearchResults(multiResp.Results),
+		})
+	}
+}
+
+func (h *Handler) APISuggest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+
+	limit := 8
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 20 {
+			limit = parsed
+		}
+	}
+
+	suggestions, err := h.suggestService.Suggest(query, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// MultiSearchResponse is a multi-search page with its results also grouped by media type
+type MultiSearchResponse struct {
+	*models.TMDBResponse[models.MultiSearchResult]
+	Groups models.SearchGroups `json:"groups"`
 }
 
 func (h *Handler) APIWatchlistAdd(w http.ResponseWriter, r *http.Request) {
@@ -437,15 +1116,47 @@ func (h *Handler) APIWatchlistAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.watchlistService.AddItem(item); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := h.watchlistService.AddItem(item); errors.Is(err, services.ErrAlreadyInWatchlist) {
+		http.Error(w, "Already in watchlist", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("Error adding %s %d to the watchlist: %v", item.Type, item.ID, err)
+		http.Error(w, "Failed to save the watchlist", http.StatusInternalServerError)
 		return
 	}
 
+	if isPartial(r) {
+		h.renderFragment(w, r, "watchlist-button", itemButton(item, true))
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+func (h *Handler) APICollectionWatchlistAdd(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid collection ID", http.StatusBadRequest)
+		return
+	}
+
+	collection, err := h.tmdbService.GetCollection(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	added, err := h.addCollectionToWatchlist(collection)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "added": added, "count": h.watchlistService.GetItemCount()})
+}
+
 func (h *Handler) APIMovieVideos(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -455,6 +1166,10 @@ func (h *Handler) APIMovieVideos(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid movie ID", http.StatusBadRequest)
 		return
 	}
+	if err := h.checkContent("movie", id); errors.Is(err, services.ErrContentRestricted) {
+		http.Error(w, restrictedMessage, http.StatusForbidden)
+		return
+	}
 
 	videos, err := h.tmdbService.GetMovieVideos(id)
 	if err != nil {
@@ -475,6 +1190,10 @@ func (h *Handler) APITVShowVideos(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid TV show ID", http.StatusBadRequest)
 		return
 	}
+	if err := h.checkContent("tv", id); errors.Is(err, services.ErrContentRestricted) {
+		http.Error(w, restrictedMessage, http.StatusForbidden)
+		return
+	}
 
 	videos, err := h.tmdbService.GetTVShowVideos(id)
 	if err != nil {
@@ -502,11 +1221,22 @@ func (h *Handler) APIWatchlistRemove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Kept so the add button that replaces this one can re-add the title
+	item, _ := h.watchlistService.GetItem(itemType, id)
+
 	if err := h.watchlistService.RemoveItem(itemType, id); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if isPartial(r) {
+		button := &WatchlistButton{ID: id, Type: itemType}
+		if item != nil {
+			button = itemButton(*item, false)
+		}
+		h.renderFragment(w, r, "watchlist-button", button)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
@@ -532,6 +1262,149 @@ func (h *Handler) APIWatchlistToggle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isPartial(r) {
+		if item, ok := h.watchlistService.GetItem(itemType, id); ok {
+			items := []models.WatchlistItem{*item}
+			h.ratingService.AnnotateWatchlist(items, h.config.OMDBEnrichment)
+			h.renderFragment(w, r, "watchlist-item", items[0])
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+func (h *Handler) APIWatchlistRating(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	itemType := r.URL.Query().Get("type")
+	if itemType == "" {
+		http.Error(w, "Type parameter required", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Rating *float64 `json:"rating"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.watchlistService.SetRating(itemType, id, body.Rating); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
+
+func (h *Handler) APIWatchlistTags(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	itemType := r.URL.Query().Get("type")
+	if itemType == "" {
+		http.Error(w, "Type parameter required", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.watchlistService.SetTags(itemType, id, body.Tags); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+func (h *Handler) APIWatchlistPoster(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	itemType := r.URL.Query().Get("type")
+	if itemType == "" {
+		http.Error(w, "Type parameter required", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		PosterPath *string `json:"poster_path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if !h.watchlistService.IsInWatchlist(itemType, id) {
+		http.Error(w, "item not found in watchlist", http.StatusBadRequest)
+		return
+	}
+	if err := h.setWatchlistPoster(itemType, id, body.PosterPath); err != nil {
+		if errors.Is(err, errUnknownPoster) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Error setting poster for %s %d: %v", itemType, id, err)
+		http.Error(w, "Failed to set poster", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// Image serves a TMDB image through the disk cache. Image URLs never change
+// content, so browsers may keep them for a year.
+func (h *Handler) Image(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	img, err := h.imageService.Open(vars["size"], "/"+vars["file"])
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidImage), errors.Is(err, services.ErrImageNotFound):
+			http.NotFound(w, r)
+		default:
+			log.Printf("Error fetching image %s/%s: %v", vars["size"], vars["file"], err)
+			http.Error(w, "Failed to load image", http.StatusBadGateway)
+		}
+		return
+	}
+	defer img.File.Close()
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", img.ETag)
+	http.ServeContent(w, r, img.Name, img.ModTime, img.File)
+}
+
+func (h *Handler) APIStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.statsService.Compute())
+}
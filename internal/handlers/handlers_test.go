@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"muvi-discovery-app/internal/models"
+	"muvi-discovery-app/internal/services"
+)
+
+// newTestHandler builds a Handler with its data in a temporary directory and
+// stops its background jobs when the test ends
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	h := NewHandler(services.NewTMDBService("test", "US"), services.NewOMDBService("test"), Config{DataDir: t.TempDir()})
+	t.Cleanup(func() {
+		if err := h.Close(context.Background()); err != nil {
+			t.Errorf("closing handler: %v", err)
+		}
+	})
+	return h
+}
+
+// downTransport answers every request as an unavailable upstream would
+type downTransport struct{}
+
+func (downTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"status_message":"unavailable"}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestPagesReportUpstreamFailures(t *testing.T) {
+	transport := http.DefaultTransport
+	http.DefaultTransport = downTransport{}
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	router := NewRouter(newTestHandler(t))
+
+	for _, path := range []string{
+		"/",
+		"/movies",
+		"/movies?category=top_rated&page=2",
+		"/tv",
+		"/search?q=alien",
+		"/search?q=alien&type=movie",
+		"/search?q=alien+year:1979",
+		"/search?q=year:1979",
+		"/discover",
+		"/movies/603",
+		"/tv/1399",
+	} {
+		for _, partial := range []bool{false, true} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if partial {
+				req.Header.Set(partialHeader, "true")
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadGateway {
+				t.Errorf("GET %s (partial %v): status %d, want %d", path, partial, rec.Code, http.StatusBadGateway)
+			}
+			if !strings.Contains(rec.Body.String(), `class="error-page"`) {
+				t.Errorf("GET %s (partial %v): the error page wasn't rendered", path, partial)
+			}
+		}
+	}
+
+	// A bad query is the user's to fix, not an upstream failure
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?q=type:person", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /search?q=type:person: status %d, want 200", rec.Code)
+	}
+}
+
+func TestWatchlistEventsReplaysSince(t *testing.T) {
+	h := newTestHandler(t)
+	for id := 1; id <= 3; id++ {
+		if err := h.watchlistService.AddItem(models.WatchlistItem{Type: "movie", ID: id, Title: "Movie"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		since, lastEventID string
+		replayed           int
+		want               string
+	}{
+		{"", "", 0, `"seq":3`},
+		{"1", "", 2, "id: 2\n"},
+		{"1", "2", 1, "id: 3\n"}, // the header wins
+		{"bogus", "", 0, `"reload":true`},
+	}
+	for _, tt := range tests {
+		// A cancelled request sends what it has and returns
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/watchlist/events?since="+tt.since, nil).WithContext(ctx)
+		if tt.lastEventID != "" {
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+		}
+		rec := httptest.NewRecorder()
+		h.WatchlistEvents(rec, req)
+
+		body := rec.Body.String()
+		if n := strings.Count(body, "event: watchlist"); n != tt.replayed || !strings.Contains(body, tt.want) {
+			t.Errorf("since %q, Last-Event-ID %q: %d events, want %d and %s:\n%s", tt.since, tt.lastEventID, n, tt.replayed, tt.want, body)
+		}
+	}
+}
+
+func TestAPIV1WatchlistAddStatus(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		path   string // of the watchlist file
+		want   []int  // for adding the same item twice
+		legacy []int  // from the unversioned endpoint
+	}{
+		{"saved", filepath.Join(dir, "watchlist.json"), []int{http.StatusCreated, http.StatusConflict}, []int{http.StatusOK, http.StatusConflict}},
+		{"unsaveable", filepath.Join(dir, "missing", "watchlist.json"), []int{http.StatusInternalServerError}, []int{http.StatusInternalServerError}},
+	}
+	for _, tt := range tests {
+		for i, endpoint := range []struct {
+			add  func(*Handler) http.HandlerFunc
+			want []int
+		}{
+			{func(h *Handler) http.HandlerFunc { return h.APIV1WatchlistAdd }, tt.want},
+			{func(h *Handler) http.HandlerFunc { return h.APIWatchlistAdd }, tt.legacy},
+		} {
+			h := &Handler{watchlistService: services.NewWatchlistService(tt.path)}
+			for _, want := range endpoint.want {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/watchlist", strings.NewReader(fmt.Sprintf(`{"type":"movie","id":%d,"title":"The Matrix"}`, 600+i)))
+				rec := httptest.NewRecorder()
+				endpoint.add(h)(rec, req)
+				if rec.Code != want {
+					t.Errorf("%s (endpoint %d): status %d, want %d: %s", tt.name, i, rec.Code, want, rec.Body)
+				}
+			}
+		}
+	}
+}
+
+func TestAPIV1UpstreamStatus(t *testing.T) {
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		status, body := http.StatusServiceUnavailable, `{"status_message":"unavailable"}`
+		if strings.HasSuffix(r.URL.Path, "/movie/999999999") {
+			status, body = http.StatusNotFound, `{"status_code":34,"status_message":"The resource you requested could not be found."}`
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	router := NewRouter(newTestHandler(t))
+
+	for path, want := range map[string]int{
+		"/api/v1/movies/999999999": http.StatusNotFound,
+		"/api/v1/movies/603":       http.StatusBadGateway,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s: status %d, want %d: %s", path, rec.Code, want, rec.Body)
+		}
+	}
+}
+
+func TestPageBounds(t *testing.T) {
+	tests := []struct {
+		n, page    int
+		start, end int
+	}{
+		{45, 1, 0, 20},
+		{45, 3, 40, 45},
+		{45, 4, 45, 45},
+		{40, 3, 40, 40},
+		{0, 1, 0, 0},
+		{45, math.MaxInt, 45, 45},
+		{45, 0, 45, 45},
+	}
+	for _, tt := range tests {
+		if start, end := pageBounds(tt.n, tt.page, searchPageSize); start != tt.start || end != tt.end {
+			t.Errorf("pageBounds(%d, %d) = %d, %d; want %d, %d", tt.n, tt.page, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestAPIV1WatchlistHugePage(t *testing.T) {
+	h := newTestHandler(t)
+	if err := h.watchlistService.AddItem(models.WatchlistItem{Type: "movie", ID: 603, Title: "The Matrix"}); err != nil {
+		t.Fatal(err)
+	}
+	router := NewRouter(h)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/watchlist?page=9223372036854775807", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"data":[]`) {
+		t.Errorf("status %d: %s, want an empty page", rec.Code, rec.Body)
+	}
+}
+
+func TestTrendingCategoryPages(t *testing.T) {
+	var mu sync.Mutex
+	var pages []string
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.Contains(r.URL.Path, "/trending/") {
+			mu.Lock()
+			pages = append(pages, r.URL.Path+"?page="+r.URL.Query().Get("page"))
+			mu.Unlock()
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"page":3,"total_pages":5,"results":[]}`)),
+			Request:    r,
+		}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	router := NewRouter(newTestHandler(t))
+
+	for _, path := range []string{"/api/v1/movies?category=trending&page=3", "/api/v1/tv?category=trending&page=3"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d", path, rec.Code)
+		}
+	}
+	want := "/3/trending/movie/week?page=3 /3/trending/tv/week?page=3"
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(pages, " "); got != want {
+		t.Errorf("TMDB requests = %s, want %s", got, want)
+	}
+}
+
+func TestAPIV1SearchGroupsMulti(t *testing.T) {
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"page":1,"total_pages":1,"total_results":0,"results":[]}`
+		if strings.HasSuffix(r.URL.Path, "/search/multi") {
+			body = `{"page":1,"total_pages":1,"total_results":3,"results":[
+				{"media_type":"movie","id":603,"title":"The Matrix"},
+				{"media_type":"person","id":6384,"name":"Keanu Reeves"},
+				{"media_type":"tv","id":1,"name":"Matrix"}]}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	router := NewRouter(newTestHandler(t))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/search?q=matrix", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data MultiSearchData `json:"data"`
+		Meta APIMeta         `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	groups := resp.Data.Groups
+	if len(resp.Data.Results) != 3 || len(groups.Movies) != 1 || len(groups.TVShows) != 1 || len(groups.People) != 1 {
+		t.Errorf("data = %+v, want three results in three groups", resp.Data)
+	}
+	if resp.Meta.TotalResults != 3 {
+		t.Errorf("meta = %+v, want 3 results", resp.Meta)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
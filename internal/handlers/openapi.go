@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes every route under /api. openapi_test.go fails when a
+// registered API route is missing from it, so update both together.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (h *Handler) APIOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+func (h *Handler) APIDocs(w http.ResponseWriter, r *http.Request) {
+	data := PageData{
+		Title:           "API Documentation",
+		ContentTemplate: "api-docs-content",
+	}
+
+	h.renderTemplate(w, r, "base.html", data)
+}
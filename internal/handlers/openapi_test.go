@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// routeVarPattern matches a mux path variable with a regexp, e.g. {id:[0-9]+}
+var routeVarPattern = regexp.MustCompile(`\{([^}:]+):[^}]*\}`)
+
+// apiRoutes returns the "METHOD path" pairs registered under /api, with
+// path variables normalized to OpenAPI's {name} form
+func apiRoutes(t *testing.T) map[string]bool {
+	t.Helper()
+
+	routes := map[string]bool{}
+	err := NewRouter(newTestHandler(t)).Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, "/api/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Subrouter prefixes carry no methods
+			return nil
+		}
+		path = routeVarPattern.ReplaceAllString(path, "{$1}")
+		for _, method := range methods {
+			routes[strings.ToLower(method)+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking routes: %v", err)
+	}
+
+	return routes
+}
+
+func specOperations(t *testing.T) map[string]bool {
+	t.Helper()
+
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("parsing openapi.json: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Fatalf("openapi version = %q, want 3.x", spec.OpenAPI)
+	}
+
+	ops := map[string]bool{}
+	for path, methods := range spec.Paths {
+		for method := range methods {
+			ops[method+" "+path] = true
+		}
+	}
+	return ops
+}
+
+func TestOpenAPISpecDescribesEveryAPIRoute(t *testing.T) {
+	routes := apiRoutes(t)
+	ops := specOperations(t)
+
+	if len(routes) == 0 {
+		t.Fatal("no /api routes found on the router")
+	}
+
+	for route := range routes {
+		if !ops[route] {
+			t.Errorf("route %q is registered but not described in openapi.json", route)
+		}
+	}
+	for op := range ops {
+		if !routes[op] {
+			t.Errorf("openapi.json describes %q but no such route is registered", op)
+		}
+	}
+}
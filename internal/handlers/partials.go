@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"muvi-discovery-app/internal/models"
+)
+
+// partialHeader marks requests from main.js that want one block of a page
+// instead of the whole page. The name follows htmx's convention.
+const partialHeader = "HX-Request"
+
+// watchlistCountHeader carries the watchlist size on partial responses, so
+// the nav badge can be updated without reloading the page
+const watchlistCountHeader = "X-Watchlist-Count"
+
+func isPartial(r *http.Request) bool {
+	return r.Header.Get(partialHeader) == "true"
+}
+
+// renderPage renders the whole page, or only the named block for a partial
+// request, such as the next page of a list for infinite scroll
+func (h *Handler) renderPage(w http.ResponseWriter, r *http.Request, status int, block string, data PageData) {
+	w.Header().Add("Vary", partialHeader)
+	name := "base.html"
+	if isPartial(r) {
+		name = block
+	}
+	h.renderStatus(w, r, status, name, data)
+}
+
+// renderFragment renders a block with its own data, as the watchlist API does
+// for partial requests
+func (h *Handler) renderFragment(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
+	w.Header().Set(watchlistCountHeader, strconv.Itoa(h.watchlistService.GetItemCount()))
+	if err := h.templates.Execute(w, r, http.StatusOK, name, data); err != nil {
+		log.Printf("Error rendering %s: %v", name, err)
+		http.Error(w, "Failed to execute template", http.StatusInternalServerError)
+	}
+}
+
+// setPageLinks fills in the previous and next page URLs of a list, keeping
+// the rest of the query such as the category or search
+func setPageLinks(data *PageData, r *http.Request) {
+	if data.CurrentPage > 1 {
+		data.PrevPageURL = pageURL(r, data.CurrentPage-1)
+	}
+	if data.CurrentPage < data.TotalPages {
+		data.NextPageURL = pageURL(r, data.CurrentPage+1)
+	}
+}
+
+func pageURL(r *http.Request, page int) string {
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	return r.URL.Path + "?" + q.Encode()
+}
+
+// WatchlistButton is the add or remove button on a detail page
+type WatchlistButton struct {
+	ID          int
+	Type        string
+	Title       string
+	PosterPath  string
+	ReleaseDate string
+	VoteAverage float64
+	InWatchlist bool
+}
+
+// watchlistButton builds the button for a title, adding it with the details
+// it is shown with
+func watchlistButton(itemType string, id int, title string, posterPath *string, releaseDate string, voteAverage float64, inWatchlist bool) *WatchlistButton {
+	b := &WatchlistButton{
+		ID:          id,
+		Type:        itemType,
+		Title:       title,
+		ReleaseDate: releaseDate,
+		VoteAverage: voteAverage,
+		InWatchlist: inWatchlist,
+	}
+	if posterPath != nil {
+		b.PosterPath = *posterPath
+	}
+	return b
+}
+
+// itemButton builds the button for a watchlist item
+func itemButton(item models.WatchlistItem, inWatchlist bool) *WatchlistButton {
+	return watchlistButton(item.Type, item.ID, item.Title, item.PosterPath, item.ReleaseDate, item.VoteAverage, inWatchlist)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// NewRouter registers every page, API and static route served by the app
+func NewRouter(h *Handler) *mux.Router {
+	r := mux.NewRouter()
+
+	// Static files
+	r.PathPrefix("/static/").Handler(h.assets)
+
+	// TMDB images, cached on disk
+	r.HandleFunc("/img/{size}/{file}", h.Image).Methods("GET", "HEAD")
+
+	// Routes
+	r.HandleFunc("/", h.Home).Methods("GET")
+	r.HandleFunc("/movies", h.Movies).Methods("GET")
+	r.HandleFunc("/movies/{id}", h.MovieDetails).Methods("GET")
+	r.HandleFunc("/tv", h.TVShows).Methods("GET")
+	r.HandleFunc("/tv/{id}", h.TVShowDetails).Methods("GET")
+	r.HandleFunc("/tv/{id}/season/{season:[0-9]+}", h.TVSeason).Methods("GET")
+	r.HandleFunc("/collections/{id}", h.Collection).Methods("GET")
+	r.HandleFunc("/search", h.Search).Methods("GET")
+	r.HandleFunc("/discover", h.Discover).Methods("GET")
+	r.HandleFunc("/watchlist", h.Watchlist).Methods("GET")
+	r.HandleFunc("/stats", h.Stats).Methods("GET")
+	r.HandleFunc("/calendar", h.Calendar).Methods("GET")
+	r.HandleFunc("/calendar.ics", h.CalendarICS).Methods("GET")
+	r.HandleFunc("/webhooks", h.requireAdmin(h.Webhooks)).Methods("GET")
+
+	// API routes
+	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/openapi.json", h.APIOpenAPISpec).Methods("GET")
+	api.HandleFunc("/docs", h.APIDocs).Methods("GET")
+	api.HandleFunc("/search", h.APISearch).Methods("GET")
+	api.HandleFunc("/suggest", h.APISuggest).Methods("GET")
+	api.HandleFunc("/watchlist", h.APIWatchlistAdd).Methods("POST")
+	api.HandleFunc("/watchlist/{id}", h.APIWatchlistRemove).Methods("DELETE")
+	api.HandleFunc("/watchlist/{id}/toggle", h.APIWatchlistToggle).Methods("PUT")
+	api.HandleFunc("/watchlist/{id}/rating", h.APIWatchlistRating).Methods("PUT")
+	api.HandleFunc("/watchlist/{id}/tags", h.APIWatchlistTags).Methods("PUT")
+	api.HandleFunc("/watchlist/{id}/poster", h.APIWatchlistPoster).Methods("PUT")
+	api.HandleFunc("/collections/{id}/watchlist", h.APICollectionWatchlistAdd).Methods("POST")
+	api.HandleFunc("/movies/{id}/videos", h.APIMovieVideos).Methods("GET")
+	api.HandleFunc("/tv/{id}/videos", h.APITVShowVideos).Methods("GET")
+	api.HandleFunc("/stats", h.APIStats).Methods("GET")
+
+	// Versioned JSON API mirroring every page
+	v1 := api.PathPrefix("/v1").Subrouter()
+	v1.HandleFunc("/movies", h.APIV1Movies).Methods("GET")
+	v1.HandleFunc("/movies/{id:[0-9]+}", h.APIV1MovieDetails).Methods("GET")
+	v1.HandleFunc("/movies/{id:[0-9]+}/credits", h.APIV1MovieCredits).Methods("GET")
+	v1.HandleFunc("/movies/{id:[0-9]+}/videos", h.APIV1MovieVideos).Methods("GET")
+	v1.HandleFunc("/movies/{id:[0-9]+}/images", h.APIV1MovieImages).Methods("GET")
+	v1.HandleFunc("/movies/{id:[0-9]+}/release_dates", h.APIV1MovieReleaseDates).Methods("GET")
+	v1.HandleFunc("/collections/{id:[0-9]+}", h.APIV1Collection).Methods("GET")
+	v1.HandleFunc("/collections/{id:[0-9]+}/watchlist", h.APIV1CollectionWatchlistAdd).Methods("POST")
+	v1.HandleFunc("/tv", h.APIV1TVShows).Methods("GET")
+	v1.HandleFunc("/tv/{id:[0-9]+}", h.APIV1TVShowDetails).Methods("GET")
+	v1.HandleFunc("/tv/{id:[0-9]+}/credits", h.APIV1TVShowCredits).Methods("GET")
+	v1.HandleFunc("/tv/{id:[0-9]+}/videos", h.APIV1TVShowVideos).Methods("GET")
+	v1.HandleFunc("/tv/{id:[0-9]+}/images", h.APIV1TVImages).Methods("GET")
+	v1.HandleFunc("/tv/{id:[0-9]+}/content_ratings", h.APIV1TVContentRatings).Methods("GET")
+	v1.HandleFunc("/tv/{id:[0-9]+}/season/{season:[0-9]+}", h.APIV1TVSeason).Methods("GET")
+	v1.HandleFunc("/genres/{type:movie|tv}", h.APIV1Genres).Methods("GET")
+	v1.HandleFunc("/certifications/{type:movie|tv}", h.APIV1Certifications).Methods("GET")
+	v1.HandleFunc("/search", h.APIV1Search).Methods("GET")
+	v1.HandleFunc("/discover/{type:movie|tv}", h.APIV1Discover).Methods("GET")
+	v1.HandleFunc("/watchlist", h.APIV1Watchlist).Methods("GET")
+	v1.HandleFunc("/watchlist", h.APIV1WatchlistAdd).Methods("POST")
+	v1.HandleFunc("/watchlist/events", h.WatchlistEvents).Methods("GET")
+	v1.HandleFunc("/watchlist/changes", h.APIV1WatchlistChanges).Methods("GET")
+	v1.HandleFunc("/watchlist/{type:movie|tv}/{id:[0-9]+}", h.APIV1WatchlistRemove).Methods("DELETE")
+	v1.HandleFunc("/watchlist/{type:movie|tv}/{id:[0-9]+}/toggle", h.APIV1WatchlistToggle).Methods("PUT")
+	v1.HandleFunc("/watchlist/{type:movie|tv}/{id:[0-9]+}/rating", h.APIV1WatchlistRating).Methods("PUT")
+	v1.HandleFunc("/watchlist/{type:movie|tv}/{id:[0-9]+}/tags", h.APIV1WatchlistTags).Methods("PUT")
+	v1.HandleFunc("/watchlist/{type:movie|tv}/{id:[0-9]+}/poster", h.APIV1WatchlistPoster).Methods("PUT")
+	v1.HandleFunc("/stats", h.APIV1Stats).Methods("GET")
+	v1.HandleFunc("/calendar", h.APIV1Calendar).Methods("GET")
+	v1.HandleFunc("/content_profile", h.APIV1ContentProfile).Methods("GET")
+	v1.HandleFunc("/webhooks", h.requireAdmin(h.APIV1Webhooks)).Methods("GET")
+	v1.HandleFunc("/webhooks", h.requireAdmin(h.APIV1WebhookCreate)).Methods("POST")
+	v1.HandleFunc("/webhooks/{id:[0-9a-f]+}", h.requireAdmin(h.APIV1WebhookDelete)).Methods("DELETE")
+	v1.HandleFunc("/webhooks/{id:[0-9a-f]+}/test", h.requireAdmin(h.APIV1WebhookTest)).Methods("POST")
+	v1.HandleFunc("/webhooks/{id:[0-9a-f]+}/deliveries", h.requireAdmin(h.APIV1WebhookDeliveries)).Methods("GET")
+	v1.NotFoundHandler = http.HandlerFunc(h.APIV1NotFound)
+	// Unknown API URLs get a plain 404 rather than the HTML page
+	api.NotFoundHandler = http.NotFoundHandler()
+
+	// GraphQL endpoint; GET runs queries only, POST also runs mutations
+	r.HandleFunc("/graphql", h.GraphQL).Methods("GET", "POST")
+
+	r.NotFoundHandler = http.HandlerFunc(h.NotFound)
+
+	return r
+}
@@ -0,0 +1,566 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"muvi-discovery-app/internal/models"
+)
+
+// searchQuery is a search box string split into free text and filters, e.g.
+// `"dark knight" year:2008 type:movie genre:action lang:en rating>=7`
+type searchQuery struct {
+	Text      string   // free text sent to TMDB, quoted phrases included
+	Phrases   []string // quoted phrases that result titles must contain
+	Type      string   // movie, tv or person; empty when not given
+	Year      int
+	Genre     string
+	Language  string
+	MinRating float64
+
+	tokens []queryToken
+}
+
+// queryToken is one space-separated piece of the raw query
+type queryToken struct {
+	raw   string
+	key   string // filter key, or "" for free text
+	label string // chip label for filters
+}
+
+// SearchChip is a parsed filter shown above search results; following RemoveURL drops it
+type SearchChip struct {
+	Label     string
+	RemoveURL string
+}
+
+var searchTypeLabels = map[string]string{"movie": "Movies", "tv": "TV Shows", "person": "People"}
+
+func parseSearchQuery(raw string) searchQuery {
+	var q searchQuery
+	var text []string
+
+	for _, tok := range splitQuery(raw) {
+		if phrase, ok := unquote(tok.raw); ok {
+			if phrase != "" {
+				q.Phrases = append(q.Phrases, phrase)
+				text = append(text, phrase)
+			}
+			q.tokens = append(q.tokens, tok)
+			continue
+		}
+
+		if q.applyFilter(&tok) {
+			q.tokens = append(q.tokens, tok)
+			continue
+		}
+
+		text = append(text, tok.raw)
+		q.tokens = append(q.tokens, tok)
+	}
+
+	q.Text = strings.Join(text, " ")
+	return q
+}
+
+// applyFilter recognizes key:value and rating>=N tokens; anything malformed stays free text
+func (q *searchQuery) applyFilter(tok *queryToken) bool {
+	raw := tok.raw
+
+	if rest, ok := strings.CutPrefix(strings.ToLower(raw), "rating"); ok {
+		for _, op := range []string{">=", ">", ":"} {
+			if v, ok := strings.CutPrefix(rest, op); ok {
+				rating, err := strconv.ParseFloat(v, 64)
+				if err != nil || rating < 0 || rating > 10 {
+					return false
+				}
+				q.MinRating = rating
+				tok.key = "rating"
+				tok.label = fmt.Sprintf("Rating ≥ %g", rating)
+				return true
+			}
+		}
+		return false
+	}
+
+	key, value, found := strings.Cut(raw, ":")
+	if !found {
+		return false
+	}
+	key = strings.ToLower(key)
+	if v, ok := unquote(value); ok {
+		value = v
+	}
+	if value == "" {
+		return false
+	}
+
+	switch key {
+	case "year":
+		year, err := strconv.Atoi(value)
+		if err != nil || year < 1870 || year > 2100 {
+			return false
+		}
+		q.Year = year
+		tok.label = fmt.Sprintf("Year: %d", year)
+	case "type":
+		t := strings.ToLower(value)
+		switch t {
+		case "movies", "film", "films":
+			t = "movie"
+		case "show", "shows", "series":
+			t = "tv"
+		case "people":
+			t = "person"
+		}
+		if _, ok := searchTypeLabels[t]; !ok {
+			return false
+		}
+		q.Type = t
+		tok.label = "Type: " + searchTypeLabels[t]
+	case "genre":
+		q.Genre = value
+		tok.label = "Genre: " + value
+	case "lang", "language":
+		if len(value) != 2 {
+			return false
+		}
+		q.Language = strings.ToLower(value)
+		tok.label = "Language: " + q.Language
+	default:
+		return false
+	}
+
+	tok.key = key
+	return true
+}
+
+// splitQuery splits on whitespace, keeping quoted runs (including key:"a b") together
+func splitQuery(raw string) []queryToken {
+	var tokens []queryToken
+	var b strings.Builder
+	inQuote := false
+
+	flush := func() {
+		if b.Len() > 0 {
+			tokens = append(tokens, queryToken{raw: b.String()})
+			b.Reset()
+		}
+	}
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			b.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func unquote(s string) (string, bool) {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		return strings.TrimSpace(s[1 : len(s)-1]), true
+	}
+	// An unterminated quote still reads as a phrase while the user is typing
+	if strings.HasPrefix(s, `"`) && strings.Count(s, `"`) == 1 {
+		return strings.TrimSpace(s[1:]), true
+	}
+	return "", false
+}
+
+// HasFilters reports whether anything beyond plain free text was given
+func (q searchQuery) HasFilters() bool {
+	return q.Year != 0 || q.Genre != "" || q.Language != "" || q.MinRating != 0 || len(q.Phrases) > 0
+}
+
+// without rebuilds the raw query leaving out tokens for which skip returns true
+func (q searchQuery) without(skip func(i int, tok queryToken) bool) string {
+	var parts []string
+	for i, tok := range q.tokens {
+		if !skip(i, tok) {
+			parts = append(parts, tok.raw)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Chips returns one removable chip per recognized filter
+func (q searchQuery) Chips(tabType string) []SearchChip {
+	var chips []SearchChip
+	for i, tok := range q.tokens {
+		if tok.key == "" {
+			continue
+		}
+		params := url.Values{}
+		params.Set("q", q.without(func(j int, _ queryToken) bool { return j == i }))
+		if tabType != "" {
+			params.Set("type", tabType)
+		}
+		chips = append(chips, SearchChip{Label: tok.label, RemoveURL: "/search?" + params.Encode()})
+	}
+	return chips
+}
+
+// TabQuery is the raw query without type: filters, so the result tabs can switch type
+func (q searchQuery) TabQuery() string {
+	return q.without(func(_ int, tok queryToken) bool { return tok.key == "type" })
+}
+
+// discoverFilters maps the query's filters onto a TMDB discover call
+func (q searchQuery) discoverFilters(genreID int) models.SearchFilters {
+	filters := models.SearchFilters{Language: q.Language, SortBy: "popularity"}
+	if q.Year != 0 {
+		year := q.Year
+		filters.Year = &year
+	}
+	if genreID != 0 {
+		filters.Genre = &genreID
+	}
+	if q.MinRating != 0 {
+		rating := q.MinRating
+		filters.Rating = &rating
+	}
+	return filters
+}
+
+// matches applies the filters TMDB search can't, to a single result
+func (q searchQuery) matches(title, originalTitle, date, language string, rating float64, genreIDs []int, genreID int) bool {
+	if q.Year != 0 && yearPrefix(date) != strconv.Itoa(q.Year) {
+		return false
+	}
+	if q.Language != "" && language != q.Language {
+		return false
+	}
+	if q.MinRating != 0 && rating < q.MinRating {
+		return false
+	}
+	if q.Genre != "" && !containsInt(genreIDs, genreID) {
+		return false
+	}
+	for _, phrase := range q.Phrases {
+		p := strings.ToLower(phrase)
+		if !strings.Contains(strings.ToLower(title), p) && !strings.Contains(strings.ToLower(originalTitle), p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q searchQuery) filterMovies(movies []models.Movie, genreID int) []models.Movie {
+	if !q.HasFilters() {
+		return movies
+	}
+	filtered := []models.Movie{}
+	for _, m := range movies {
+		if q.matches(m.Title, m.OriginalTitle, m.ReleaseDate, m.OriginalLanguage, m.VoteAverage, m.GenreIDs, genreID) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+func (q searchQuery) filterTVShows(shows []models.TVShow, genreID int) []models.TVShow {
+	if !q.HasFilters() {
+		return shows
+	}
+	filtered := []models.TVShow{}
+	for _, s := range shows {
+		if q.matches(s.Name, s.OriginalName, s.FirstAirDate, s.OriginalLanguage, s.VoteAverage, s.GenreIDs, genreID) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+// filterPeople keeps the people whose names contain every quoted phrase
+func (q searchQuery) filterPeople(people []models.Person) []models.Person {
+	filtered := []models.Person{}
+	for _, p := range people {
+		if q.matches(p.Name, p.Name, "", "", 0, nil, 0) {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+func yearPrefix(date string) string {
+	if len(date) >= 4 {
+		return date[:4]
+	}
+	return ""
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveGenre finds a genre ID by name (case-insensitive) or by numeric ID
+func (h *Handler) resolveGenre(name, mediaType string) (int, bool) {
+	var genres []models.Genre
+	if mediaType == "tv" {
+		resp, err := h.tmdbService.GetTVGenres()
+		if err != nil {
+			log.Printf("Error fetching TV genres: %v", err)
+			return 0, false
+		}
+		genres = resp.Genres
+	} else {
+		resp, err := h.tmdbService.GetMovieGenres()
+		if err != nil {
+			log.Printf("Error fetching movie genres: %v", err)
+			return 0, false
+		}
+		genres = resp.Genres
+	}
+
+	id, _ := strconv.Atoi(name)
+	for _, g := range genres {
+		if g.ID == id || strings.EqualFold(g.Name, name) {
+			return g.ID, true
+		}
+	}
+	// TV genres are combined, so "action" should still find "Action & Adventure"
+	for _, g := range genres {
+		if strings.HasPrefix(strings.ToLower(g.Name), strings.ToLower(name)) {
+			return g.ID, true
+		}
+	}
+	return 0, false
+}
+
+// queryGenres resolves the genre filter for the media types being searched.
+// ok is false when a genre was given but matches none of them.
+func (h *Handler) queryGenres(q searchQuery, searchType string) (movieGenre, tvGenre int, ok bool) {
+	if q.Genre == "" || searchType == "person" {
+		return 0, 0, true
+	}
+	var movieOK, tvOK bool
+	if searchType != "tv" {
+		movieGenre, movieOK = h.resolveGenre(q.Genre, "movie")
+	}
+	if searchType != "movie" {
+		tvGenre, tvOK = h.resolveGenre(q.Genre, "tv")
+	}
+	return movieGenre, tvGenre, movieOK || tvOK
+}
+
+// searchPoolPages bounds how many TMDB search pages a filtered search reads.
+// TMDB search takes a year but no genre, language or rating, and discover
+// takes those but no text, so with text those filters are applied here to the
+// best matches, which are then paginated locally. That keeps the page count
+// true to what is shown.
+const searchPoolPages = 5
+
+// searchPageSize matches TMDB's, so local pages look like its own
+const searchPageSize = 20
+
+// filteredSearch returns one page of the results from fetch that pass keep,
+// and the number of pages. A nil keep passes TMDB's page straight through.
+func filteredSearch[T any](fetch func(page int) (*models.TMDBResponse[T], error), keep func([]T) []T, page int) ([]T, int, error) {
+	if keep == nil {
+		resp, err := fetch(page)
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Results, resp.TotalPages, nil
+	}
+
+	pool := []T{}
+	for p := 1; p <= searchPoolPages; p++ {
+		resp, err := fetch(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		pool = append(pool, keep(resp.Results)...)
+		if p >= resp.TotalPages {
+			break
+		}
+	}
+
+	totalPages := (len(pool) + searchPageSize - 1) / searchPageSize
+	start, end := pageBounds(len(pool), page, searchPageSize)
+	return pool[start:end], totalPages, nil
+}
+
+// filtersLocally reports whether the query has filters TMDB search can't take
+func (q searchQuery) filtersLocally() bool {
+	return q.Genre != "" || q.Language != "" || q.MinRating != 0 || len(q.Phrases) > 0
+}
+
+// filtersTitles reports whether the query has filters that only describe
+// titles, and so rule out people
+func (q searchQuery) filtersTitles() bool {
+	return q.Year != 0 || q.Genre != "" || q.Language != "" || q.MinRating != 0
+}
+
+func (h *Handler) searchMovies(q searchQuery, genreID, page int) ([]models.Movie, int, error) {
+	var keep func([]models.Movie) []models.Movie
+	if q.filtersLocally() {
+		keep = func(movies []models.Movie) []models.Movie { return q.filterMovies(movies, genreID) }
+	}
+	return filteredSearch(func(page int) (*models.TMDBResponse[models.Movie], error) {
+		resp, err := h.tmdbService.SearchMoviesByYear(q.Text, q.Year, page)
+		if err == nil {
+			h.suggestService.RecordMovies(resp.Results)
+		}
+		return resp, err
+	}, keep, page)
+}
+
+func (h *Handler) searchTVShows(q searchQuery, genreID, page int) ([]models.TVShow, int, error) {
+	var keep func([]models.TVShow) []models.TVShow
+	if q.filtersLocally() {
+		keep = func(shows []models.TVShow) []models.TVShow { return q.filterTVShows(shows, genreID) }
+	}
+	return filteredSearch(func(page int) (*models.TMDBResponse[models.TVShow], error) {
+		resp, err := h.tmdbService.SearchTVShowsByYear(q.Text, q.Year, page)
+		if err == nil {
+			h.suggestService.RecordTVShows(resp.Results)
+		}
+		return resp, err
+	}, keep, page)
+}
+
+// searchWithQuery runs a text search, passing the year to TMDB and applying
+// the remaining filters through filteredSearch. Problems with the query are
+// shown as data.Error; a failed TMDB request is returned.
+func (h *Handler) searchWithQuery(data *PageData, q searchQuery, page int) error {
+	movieGenre, tvGenre, ok := h.queryGenres(q, data.SearchType)
+	if !ok {
+		data.Error = fmt.Sprintf("Unknown genre \"%s\"", q.Genre)
+		return nil
+	}
+	data.CurrentPage = page
+
+	switch data.SearchType {
+	case "movie":
+		movies, totalPages, err := h.searchMovies(q, movieGenre, page)
+		if err != nil {
+			return fmt.Errorf("searching movies: %w", err)
+		}
+		data.Movies = movies
+		data.TotalPages = totalPages
+	case "tv":
+		shows, totalPages, err := h.searchTVShows(q, tvGenre, page)
+		if err != nil {
+			return fmt.Errorf("searching TV shows: %w", err)
+		}
+		data.TVShows = shows
+		data.TotalPages = totalPages
+	case "person":
+		peopleResp, err := h.tmdbService.SearchPeople(q.Text, page)
+		if err != nil {
+			return fmt.Errorf("searching people: %w", err)
+		}
+		data.People = peopleResp.Results
+		data.CurrentPage = peopleResp.Page
+		data.TotalPages = peopleResp.TotalPages
+	default:
+		if q.HasFilters() {
+			return h.searchFilteredMulti(data, q, movieGenre, tvGenre, page)
+		}
+		multiResp, err := h.tmdbService.SearchMulti(q.Text, page)
+		if err != nil {
+			return err
+		}
+		groups := models.GroupSearchResults(multiResp.Results)
+		h.suggestService.RecordMovies(groups.Movies)
+		h.suggestService.RecordTVShows(groups.TVShows)
+		data.Movies = groups.Movies
+		data.TVShows = groups.TVShows
+		data.People = groups.People
+		data.CurrentPage = multiResp.Page
+		data.TotalPages = multiResp.TotalPages
+	}
+	return nil
+}
+
+// searchFilteredMulti searches every type with filters. Multi search takes
+// no year, so movies and TV shows are searched separately; people are only
+// searched when no filter rules them out.
+func (h *Handler) searchFilteredMulti(data *PageData, q searchQuery, movieGenre, tvGenre, page int) error {
+	// With a genre that only exists for one media type, skip the other
+	if q.Genre == "" || movieGenre != 0 {
+		movies, totalPages, err := h.searchMovies(q, movieGenre, page)
+		if err != nil {
+			return fmt.Errorf("searching movies: %w", err)
+		}
+		data.Movies = movies
+		data.TotalPages = max(data.TotalPages, totalPages)
+	}
+	if q.Genre == "" || tvGenre != 0 {
+		shows, totalPages, err := h.searchTVShows(q, tvGenre, page)
+		if err != nil {
+			return fmt.Errorf("searching TV shows: %w", err)
+		}
+		data.TVShows = shows
+		data.TotalPages = max(data.TotalPages, totalPages)
+	}
+	if q.filtersTitles() {
+		return nil
+	}
+
+	people, totalPages, err := filteredSearch(func(page int) (*models.TMDBResponse[models.Person], error) {
+		return h.tmdbService.SearchPeople(q.Text, page)
+	}, q.filterPeople, page)
+	if err != nil {
+		return fmt.Errorf("searching people: %w", err)
+	}
+	data.People = people
+	data.TotalPages = max(data.TotalPages, totalPages)
+	return nil
+}
+
+// discoverFromQuery serves queries made only of filters through TMDB
+// discover. Like searchWithQuery it returns a failed TMDB request.
+func (h *Handler) discoverFromQuery(data *PageData, q searchQuery, page int) error {
+	if data.SearchType == "person" {
+		data.Error = "Add a name to search for people"
+		return nil
+	}
+
+	movieGenre, tvGenre, ok := h.queryGenres(q, data.SearchType)
+	if !ok {
+		data.Error = fmt.Sprintf("Unknown genre \"%s\"", q.Genre)
+		return nil
+	}
+	data.CurrentPage = page
+
+	// With a genre that only exists for one media type, skip the other
+	if data.SearchType != "tv" && (q.Genre == "" || movieGenre != 0) {
+		moviesResp, err := h.tmdbService.DiscoverMovies(q.discoverFilters(movieGenre), page)
+		if err != nil {
+			return fmt.Errorf("discovering movies: %w", err)
+		}
+		h.suggestService.RecordMovies(moviesResp.Results)
+		data.Movies = moviesResp.Results
+		data.TotalPages = max(data.TotalPages, moviesResp.TotalPages)
+	}
+
+	if data.SearchType != "movie" && (q.Genre == "" || tvGenre != 0) {
+		tvResp, err := h.tmdbService.DiscoverTVShows(q.discoverFilters(tvGenre), page)
+		if err != nil {
+			return fmt.Errorf("discovering TV shows: %w", err)
+		}
+		h.suggestService.RecordTVShows(tvResp.Results)
+		data.TVShows = tvResp.Results
+		data.TotalPages = max(data.TotalPages, tvResp.TotalPages)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"muvi-discovery-app/internal/models"
+	"muvi-discovery-app/internal/services"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want searchQuery
+	}{
+		{"batman", searchQuery{Text: "batman"}},
+		{"  dark   knight ", searchQuery{Text: "dark knight"}},
+		{`"dark knight" rises`, searchQuery{Text: "dark knight rises", Phrases: []string{"dark knight"}}},
+		{`"dark knight`, searchQuery{Text: "dark knight", Phrases: []string{"dark knight"}}},
+		{`"" alien`, searchQuery{Text: "alien"}},
+		{"alien year:1979", searchQuery{Text: "alien", Year: 1979}},
+		{"YEAR:1979", searchQuery{Year: 1979}},
+		{"type:movie", searchQuery{Type: "movie"}},
+		{"type:series", searchQuery{Type: "tv"}},
+		{"type:people", searchQuery{Type: "person"}},
+		{`genre:"science fiction" space`, searchQuery{Text: "space", Genre: "science fiction"}},
+		{"lang:FR", searchQuery{Language: "fr"}},
+		{"language:ja", searchQuery{Language: "ja"}},
+		{"rating>=7.5", searchQuery{MinRating: 7.5}},
+		{"rating>8", searchQuery{MinRating: 8}},
+		{"rating:6", searchQuery{MinRating: 6}},
+
+		// Bad values stay free text
+		{"year:1700", searchQuery{Text: "year:1700"}},
+		{"year:soon", searchQuery{Text: "year:soon"}},
+		{"type:book", searchQuery{Text: "type:book"}},
+		{"lang:french", searchQuery{Text: "lang:french"}},
+		{"rating>=11", searchQuery{Text: "rating>=11"}},
+		{"rating<5", searchQuery{Text: "rating<5"}},
+		{"genre:", searchQuery{Text: "genre:"}},
+		{"director:nolan", searchQuery{Text: "director:nolan"}},
+	}
+	for _, tt := range tests {
+		got := parseSearchQuery(tt.raw)
+		got.tokens = nil
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSearchQuery(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestSplitQuery(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"a b  c", []string{"a", "b", "c"}},
+		{`"a b" c`, []string{`"a b"`, "c"}},
+		{`genre:"science fiction" x`, []string{`genre:"science fiction"`, "x"}},
+		{`"open quote here`, []string{`"open quote here`}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, tok := range splitQuery(tt.raw) {
+			got = append(got, tok.raw)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitQuery(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestSearchQueryChips(t *testing.T) {
+	q := parseSearchQuery("alien year:1979 type:movie")
+	chips := q.Chips("")
+	if len(chips) != 2 {
+		t.Fatalf("got %d chips, want 2", len(chips))
+	}
+	if chips[0].Label != "Year: 1979" || chips[0].RemoveURL != "/search?q=alien+type%3Amovie" {
+		t.Errorf("year chip = %+v", chips[0])
+	}
+	if got := q.TabQuery(); got != "alien year:1979" {
+		t.Errorf("TabQuery() = %q, want %q", got, "alien year:1979")
+	}
+}
+
+func TestFilteredSearchPaginatesLocally(t *testing.T) {
+	calls := 0
+	fetch := func(page int) (*models.TMDBResponse[int], error) {
+		calls++
+		results := make([]int, searchPageSize)
+		for i := range results {
+			results[i] = (page-1)*searchPageSize + i
+		}
+		return &models.TMDBResponse[int]{Page: page, Results: results, TotalPages: 100}, nil
+	}
+	even := func(values []int) []int {
+		var kept []int
+		for _, v := range values {
+			if v%2 == 0 {
+				kept = append(kept, v)
+			}
+		}
+		return kept
+	}
+
+	got, totalPages, err := filteredSearch(fetch, even, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != searchPoolPages {
+		t.Errorf("fetched %d pages, want %d", calls, searchPoolPages)
+	}
+	// 5 pages of 20 leave 50 even numbers: pages of 20, 20 and 10
+	if totalPages != 3 || len(got) != searchPageSize || got[0] != 40 {
+		t.Errorf("page 2 = %v of %d pages", got, totalPages)
+	}
+	for _, page := range []int{9, math.MaxInt} {
+		if got, _, _ := filteredSearch(fetch, even, page); got == nil || len(got) != 0 {
+			t.Errorf("page %d past the end = %#v, want none", page, got)
+		}
+	}
+}
+
+// genreTransport answers TMDB genre list requests
+type genreTransport struct{}
+
+func (genreTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	body := `{"genres":[{"id":28,"name":"Action"},{"id":878,"name":"Science Fiction"}]}`
+	if strings.Contains(r.URL.Path, "/genre/tv/") {
+		body = `{"genres":[{"id":10759,"name":"Action & Adventure"}]}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+func TestQueryGenres(t *testing.T) {
+	transport := http.DefaultTransport
+	http.DefaultTransport = genreTransport{}
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	h := &Handler{tmdbService: services.NewTMDBService("test", "US")}
+
+	tests := []struct {
+		genre, searchType string
+		movie, tv         int
+		ok                bool
+	}{
+		{"", "all", 0, 0, true},
+		{"action", "all", 28, 10759, true},
+		{"science fiction", "all", 878, 0, true},
+		{"878", "movie", 878, 0, true},
+		{"science fiction", "tv", 0, 0, false},
+		{"western", "all", 0, 0, false},
+		{"western", "person", 0, 0, true},
+	}
+	for _, tt := range tests {
+		movie, tv, ok := h.queryGenres(searchQuery{Genre: tt.genre}, tt.searchType)
+		if movie != tt.movie || tv != tt.tv || ok != tt.ok {
+			t.Errorf("queryGenres(%q, %q) = %d, %d, %v, want %d, %d, %v",
+				tt.genre, tt.searchType, movie, tv, ok, tt.movie, tt.tv, tt.ok)
+		}
+	}
+}
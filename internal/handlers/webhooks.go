@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"muvi-discovery-app/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// webhookPageDeliveries is how many recent deliveries the webhooks page shows
+const webhookPageDeliveries = 25
+
+// Webhooks lists the configured webhooks and recent deliveries
+func (h *Handler) Webhooks(w http.ResponseWriter, r *http.Request) {
+	deliveries := h.webhookService.Deliveries("")
+	if len(deliveries) > webhookPageDeliveries {
+		deliveries = deliveries[:webhookPageDeliveries]
+	}
+
+	data := PageData{
+		Title:           "Webhooks",
+		ContentTemplate: "webhooks-content",
+		Webhooks:        h.webhookService.List(),
+		WebhookEvents:   services.WebhookEvents,
+		Deliveries:      deliveries,
+	}
+
+	h.renderTemplate(w, r, "base.html", data)
+}
+
+func (h *Handler) APIV1Webhooks(w http.ResponseWriter, r *http.Request) {
+	writeAPIData(w, h.webhookService.List(), nil)
+}
+
+// APIV1WebhookCreate adds a webhook. The response is the only one with its
+// signing secret.
+func (h *Handler) APIV1WebhookCreate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	webhook, err := h.webhookService.Create(body.URL, body.Events)
+	if errors.Is(err, services.ErrInvalidWebhook) {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving webhook: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "Failed to save webhook")
+		return
+	}
+
+	writeAPIJSON(w, http.StatusCreated, APIResponse{Data: webhook})
+}
+
+func (h *Handler) APIV1WebhookDelete(w http.ResponseWriter, r *http.Request) {
+	err := h.webhookService.Delete(mux.Vars(r)["id"])
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving webhooks: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIV1WebhookTest sends a ping to a webhook and returns the delivery, which
+// reports whether the receiver accepted it
+func (h *Handler) APIV1WebhookTest(w http.ResponseWriter, r *http.Request) {
+	delivery, err := h.webhookService.Test(mux.Vars(r)["id"])
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error sending test webhook: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "Failed to send test delivery")
+		return
+	}
+
+	writeAPIData(w, delivery, nil)
+}
+
+func (h *Handler) APIV1WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	writeAPIData(w, h.webhookService.Deliveries(mux.Vars(r)["id"]), nil)
+}
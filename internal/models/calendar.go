@@ -0,0 +1,20 @@
+package models
+
+// CalendarEntry is one dated release: a movie premiere or a TV episode airing
+type CalendarEntry struct {
+	Date          string  `json:"date"` // YYYY-MM-DD
+	Type          string  `json:"type"` // "movie" or "tv"
+	ID            int     `json:"id"`
+	Title         string  `json:"title"`
+	PosterPath    *string `json:"poster_path"`
+	SeasonNumber  int     `json:"season_number,omitempty"`
+	EpisodeNumber int     `json:"episode_number,omitempty"`
+	EpisodeName   string  `json:"episode_name,omitempty"`
+	InWatchlist   bool    `json:"in_watchlist"`
+}
+
+// CalendarDay groups the entries that fall on one date
+type CalendarDay struct {
+	Date    string          `json:"date"`
+	Entries []CalendarEntry `json:"entries"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// CollectionSummary is the collection reference embedded in movie details
+type CollectionSummary struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	PosterPath   *string `json:"poster_path"`
+	BackdropPath *string `json:"backdrop_path"`
+}
+
+// Collection is a franchise with every movie that belongs to it
+type Collection struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Overview     string  `json:"overview"`
+	PosterPath   *string `json:"poster_path"`
+	BackdropPath *string `json:"backdrop_path"`
+	Parts        []Movie `json:"parts"`
+}
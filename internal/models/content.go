@@ -0,0 +1,22 @@
+package models
+
+// ContentProfile restricts which titles the instance shows, e.g. for a
+// kids' setup. The zero value only hides adult titles.
+type ContentProfile struct {
+	AllowAdult bool `json:"allow_adult"`
+	// MaxMovieCertification and MaxTVCertification are the highest allowed
+	// ratings in the configured region, e.g. "PG" or "TV-PG". Titles without
+	// a rating there are hidden while a maximum is set.
+	MaxMovieCertification string `json:"max_movie_certification,omitempty"`
+	MaxTVCertification    string `json:"max_tv_certification,omitempty"`
+	// ExcludeGenres holds TMDB genre IDs; ExcludeKeywords are matched
+	// case-insensitively against titles and overviews
+	ExcludeGenres   []int    `json:"exclude_genres,omitempty"`
+	ExcludeKeywords []string `json:"exclude_keywords,omitempty"`
+}
+
+// Restricted reports whether the profile hides anything beyond adult titles
+func (p ContentProfile) Restricted() bool {
+	return p.MaxMovieCertification != "" || p.MaxTVCertification != "" ||
+		len(p.ExcludeGenres) > 0 || len(p.ExcludeKeywords) > 0
+}
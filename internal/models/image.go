@@ -0,0 +1,31 @@
+package models
+
+// Image is a poster, backdrop or logo from TMDB
+type Image struct {
+	FilePath    string  `json:"file_path"`
+	AspectRatio float64 `json:"aspect_ratio"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	// ISO6391 is the language of any text in the image; nil means it has none
+	ISO6391     *string `json:"iso_639_1"`
+	VoteAverage float64 `json:"vote_average"`
+	VoteCount   int     `json:"vote_count"`
+}
+
+// ImagesResponse represents the response from TMDB images endpoints
+type ImagesResponse struct {
+	ID        int     `json:"id"`
+	Posters   []Image `json:"posters"`
+	Backdrops []Image `json:"backdrops"`
+	Logos     []Image `json:"logos"`
+}
+
+// HasPoster reports whether path is one of the posters
+func (r *ImagesResponse) HasPoster(path string) bool {
+	for _, img := range r.Posters {
+		if img.FilePath == path {
+			return true
+		}
+	}
+	return false
+}
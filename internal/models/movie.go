@@ -4,26 +4,32 @@ import "time"
 
 // Movie represents a movie from TMDB API
 type Movie struct {
-	ID               int     `json:"id"`
-	Title            string  `json:"title"`
-	Overview         string  `json:"overview"`
-	PosterPath       *string `json:"poster_path"`
-	BackdropPath     *string `json:"backdrop_path"`
-	ReleaseDate      string  `json:"release_date"`
-	VoteAverage      float64 `json:"vote_average"`
-	VoteCount        int     `json:"vote_count"`
-	GenreIDs         []int   `json:"genre_ids"`
-	Adult            bool    `json:"adult"`
-	OriginalLanguage string  `json:"original_language"`
-	OriginalTitle    string  `json:"original_title"`
-	Popularity       float64 `json:"popularity"`
-	Video            bool    `json:"video"`
+	ID               int      `json:"id"`
+	Title            string   `json:"title"`
+	Overview         string   `json:"overview"`
+	PosterPath       *string  `json:"poster_path"`
+	BackdropPath     *string  `json:"backdrop_path"`
+	ReleaseDate      string   `json:"release_date"`
+	VoteAverage      float64  `json:"vote_average"`
+	VoteCount        int      `json:"vote_count"`
+	GenreIDs         []int    `json:"genre_ids"`
+	Adult            bool     `json:"adult"`
+	OriginalLanguage string   `json:"original_language"`
+	OriginalTitle    string   `json:"original_title"`
+	Popularity       float64  `json:"popularity"`
+	Video            bool     `json:"video"`
+	AggregateScore   *float64 `json:"aggregate_score,omitempty"` // 0-100, see AggregateRating
+	// SourceRatings holds the OMDB ratings when list enrichment has looked them up
+	SourceRatings []SourceScore `json:"source_ratings,omitempty"`
+	// ScorePartial marks an AggregateScore that is TMDB's alone, because the
+	// other sources haven't been looked up yet
+	ScorePartial bool `json:"score_partial,omitempty"`
 }
 
 // MovieDetails represents detailed movie information
 type MovieDetails struct {
 	Movie
-	BelongsToCollection interface{}         `json:"belongs_to_collection"`
+	BelongsToCollection *CollectionSummary  `json:"belongs_to_collection"`
 	Budget              int                 `json:"budget"`
 	Genres              []Genre             `json:"genres"`
 	Homepage            string              `json:"homepage"`
@@ -53,26 +59,32 @@ type TVShow struct {
 	OriginalName     string   `json:"original_name"`
 	Popularity       float64  `json:"popularity"`
 	OriginCountry    []string `json:"origin_country"`
+	AggregateScore   *float64 `json:"aggregate_score,omitempty"` // 0-100, see AggregateRating
+	// SourceRatings holds the OMDB ratings when list enrichment has looked them up
+	SourceRatings []SourceScore `json:"source_ratings,omitempty"`
+	// ScorePartial marks an AggregateScore that is TMDB's alone, because the
+	// other sources haven't been looked up yet
+	ScorePartial bool `json:"score_partial,omitempty"`
 }
 
 // TVShowDetails represents detailed TV show information
 type TVShowDetails struct {
 	TVShow
-	CreatedBy           []interface{}       `json:"created_by"`
+	CreatedBy           []Creator           `json:"created_by"`
 	EpisodeRunTime      []int               `json:"episode_run_time"`
 	Genres              []Genre             `json:"genres"`
 	Homepage            string              `json:"homepage"`
 	InProduction        bool                `json:"in_production"`
 	Languages           []string            `json:"languages"`
 	LastAirDate         string              `json:"last_air_date"`
-	LastEpisodeToAir    interface{}         `json:"last_episode_to_air"`
-	NextEpisodeToAir    interface{}         `json:"next_episode_to_air"`
+	LastEpisodeToAir    *Episode            `json:"last_episode_to_air"`
+	NextEpisodeToAir    *Episode            `json:"next_episode_to_air"`
 	Networks            []interface{}       `json:"networks"`
 	NumberOfEpisodes    int                 `json:"number_of_episodes"`
 	NumberOfSeasons     int                 `json:"number_of_seasons"`
 	ProductionCompanies []ProductionCompany `json:"production_companies"`
 	ProductionCountries []ProductionCountry `json:"production_countries"`
-	Seasons             []interface{}       `json:"seasons"`
+	Seasons             []Season            `json:"seasons"`
 	SpokenLanguages     []SpokenLanguage    `json:"spoken_languages"`
 	Status              string              `json:"status"`
 	Tagline             string              `json:"tagline"`
@@ -80,6 +92,15 @@ type TVShowDetails struct {
 	ExternalIDs         ExternalIDs         `json:"external_ids"`
 }
 
+// Creator represents a TV show creator
+type Creator struct {
+	ID          int     `json:"id"`
+	CreditID    string  `json:"credit_id"`
+	Name        string  `json:"name"`
+	Gender      int     `json:"gender"`
+	ProfilePath *string `json:"profile_path"`
+}
+
 // ExternalIDs represents a list of external IDs (e.g., IMDB, TVDB)
 type ExternalIDs struct {
 	IMDBID      string `json:"imdb_id"`
@@ -151,6 +172,7 @@ type OMDBMovie struct {
 	Production string   `json:"Production"`
 	Website    string   `json:"Website"`
 	Response   string   `json:"Response"`
+	Error      string   `json:"Error,omitempty"`
 }
 
 // Rating represents a rating from various sources
@@ -170,6 +192,27 @@ type WatchlistItem struct {
 	Watched     bool       `json:"watched"`
 	AddedAt     time.Time  `json:"added_at"`
 	WatchedAt   *time.Time `json:"watched_at,omitempty"`
+	Rating      *float64   `json:"rating,omitempty"` // personal rating, 0-10
+	Tags        []string   `json:"tags,omitempty"`
+	// CustomPosterPath is a poster the user picked from the title's images
+	CustomPosterPath *string `json:"custom_poster_path,omitempty"`
+	// Status and NumberOfSeasons come from TMDB like the fields above, and are
+	// kept current by the background refresh along with them
+	Status          string     `json:"status,omitempty"`
+	NumberOfSeasons int        `json:"number_of_seasons,omitempty"`
+	RefreshedAt     *time.Time `json:"refreshed_at,omitempty"`
+	// AggregateScore and SourceRatings are filled in when listing; they aren't stored
+	AggregateScore *float64      `json:"aggregate_score,omitempty"`
+	SourceRatings  []SourceScore `json:"source_ratings,omitempty"`
+	ScorePartial   bool          `json:"score_partial,omitempty"`
+}
+
+// Poster returns the user's chosen poster, or TMDB's default
+func (i WatchlistItem) Poster() *string {
+	if i.CustomPosterPath != nil {
+		return i.CustomPosterPath
+	}
+	return i.PosterPath
 }
 
 // SearchFilters represents search and discovery filters
@@ -177,8 +220,11 @@ type SearchFilters struct {
 	Genre     *int     `json:"genre,omitempty"`
 	Year      *int     `json:"year,omitempty"`
 	Rating    *float64 `json:"rating,omitempty"`
+	Language  string   `json:"language,omitempty"`
 	SortBy    string   `json:"sort_by,omitempty"`
 	SortOrder string   `json:"sort_order,omitempty"`
+	// Certification keeps titles rated at most this in the configured region, e.g. "PG-13"
+	Certification string `json:"certification,omitempty"`
 }
 
 // Credits represents cast and crew information
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// OMDBInt is a number OMDB sends as a string ("5", "1,234" or "N/A"). It
+// decodes to 0 when there's no number.
+type OMDBInt int
+
+func (n *OMDBInt) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		// Accept a plain JSON number too
+		var i int
+		if err := json.Unmarshal(data, &i); err != nil {
+			return err
+		}
+		*n = OMDBInt(i)
+		return nil
+	}
+
+	i, err := strconv.Atoi(strings.ReplaceAll(s, ",", ""))
+	if err != nil {
+		*n = 0
+		return nil
+	}
+	*n = OMDBInt(i)
+	return nil
+}
+
+// OMDBSeries is an OMDB record of type "series"
+type OMDBSeries struct {
+	OMDBMovie
+	TotalSeasons OMDBInt `json:"totalSeasons"`
+}
+
+// OMDBSeason lists a season's episodes with their IMDb ratings
+type OMDBSeason struct {
+	Title        string              `json:"Title"`
+	Season       OMDBInt             `json:"Season"`
+	TotalSeasons OMDBInt             `json:"totalSeasons"`
+	Episodes     []OMDBSeasonEpisode `json:"Episodes"`
+	Response     string              `json:"Response"`
+	Error        string              `json:"Error,omitempty"`
+}
+
+// OMDBSeasonEpisode is an episode entry in an OMDBSeason
+type OMDBSeasonEpisode struct {
+	Title      string  `json:"Title"`
+	Released   string  `json:"Released"`
+	Episode    OMDBInt `json:"Episode"`
+	IMDBRating string  `json:"imdbRating"`
+	IMDBID     string  `json:"imdbID"`
+}
+
+// OMDBEpisode is an OMDB record of type "episode"
+type OMDBEpisode struct {
+	OMDBMovie
+	Season   OMDBInt `json:"Season"`
+	Episode  OMDBInt `json:"Episode"`
+	SeriesID string  `json:"seriesID"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// SourceScore is one source's rating normalized to a 0-100 scale
+type SourceScore struct {
+	Source string  `json:"source"` // "TMDB", "IMDb", "Rotten Tomatoes" or "Metacritic"
+	Short  string  `json:"short"`  // label for tight spaces: "TMDB", "IMDb", "RT" or "MC"
+	Score  float64 `json:"score"`
+	Raw    string  `json:"raw"` // as reported, e.g. "7.8/10" or "87%"
+	Weight float64 `json:"weight"`
+}
+
+// AggregateRating is a weighted 0-100 score combining TMDB and OMDB ratings
+type AggregateRating struct {
+	Score   float64       `json:"score"`
+	Sources []SourceScore `json:"sources"`
+}
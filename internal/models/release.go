@@ -0,0 +1,118 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// ReleaseType is TMDB's kind of release, in the order TMDB numbers them
+type ReleaseType int
+
+const (
+	ReleasePremiere ReleaseType = iota + 1
+	ReleaseTheatricalLimited
+	ReleaseTheatrical
+	ReleaseDigital
+	ReleasePhysical
+	ReleaseTV
+)
+
+var releaseTypeNames = map[ReleaseType]string{
+	ReleasePremiere:          "Premiere",
+	ReleaseTheatricalLimited: "Theatrical (limited)",
+	ReleaseTheatrical:        "Theatrical",
+	ReleaseDigital:           "Digital",
+	ReleasePhysical:          "Physical",
+	ReleaseTV:                "TV",
+}
+
+func (t ReleaseType) String() string {
+	if name, ok := releaseTypeNames[t]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// ReleaseDate is one release of a movie in a country
+type ReleaseDate struct {
+	Certification string      `json:"certification"`
+	Descriptors   []string    `json:"descriptors"`
+	ISO6391       string      `json:"iso_639_1"`
+	Note          string      `json:"note"`
+	ReleaseDate   time.Time   `json:"release_date"`
+	Type          ReleaseType `json:"type"`
+}
+
+// CountryReleaseDates lists a movie's releases in one country
+type CountryReleaseDates struct {
+	ISO31661     string        `json:"iso_3166_1"`
+	ReleaseDates []ReleaseDate `json:"release_dates"`
+}
+
+// ReleaseDatesResponse is TMDB's /movie/{id}/release_dates response
+type ReleaseDatesResponse struct {
+	ID      int                   `json:"id"`
+	Results []CountryReleaseDates `json:"results"`
+}
+
+// ForCountry returns the releases in a country ordered by date, or nil
+func (r *ReleaseDatesResponse) ForCountry(country string) []ReleaseDate {
+	for _, c := range r.Results {
+		if c.ISO31661 == country {
+			releases := slices.Clone(c.ReleaseDates)
+			slices.SortStableFunc(releases, func(a, b ReleaseDate) int {
+				return a.ReleaseDate.Compare(b.ReleaseDate)
+			})
+			return releases
+		}
+	}
+	return nil
+}
+
+// Certification returns a movie's rating in a country, preferring the
+// theatrical release's and falling back to any release that has one
+func (r *ReleaseDatesResponse) Certification(country string) string {
+	var fallback string
+	for _, rd := range r.ForCountry(country) {
+		if rd.Certification == "" {
+			continue
+		}
+		if rd.Type == ReleaseTheatrical || rd.Type == ReleaseTheatricalLimited {
+			return rd.Certification
+		}
+		if fallback == "" {
+			fallback = rd.Certification
+		}
+	}
+	return fallback
+}
+
+// ContentRating is a TV show's rating in one country
+type ContentRating struct {
+	Descriptors []string `json:"descriptors"`
+	ISO31661    string   `json:"iso_3166_1"`
+	Rating      string   `json:"rating"`
+}
+
+// ContentRatingsResponse is TMDB's /tv/{id}/content_ratings response
+type ContentRatingsResponse struct {
+	ID      int             `json:"id"`
+	Results []ContentRating `json:"results"`
+}
+
+// ForCountry returns a show's rating in a country, or "" when it has none
+func (r *ContentRatingsResponse) ForCountry(country string) string {
+	for _, c := range r.Results {
+		if c.ISO31661 == country {
+			return c.Rating
+		}
+	}
+	return ""
+}
+
+// Certification is one rating in a country's rating system, e.g. PG-13
+type Certification struct {
+	Certification string `json:"certification"`
+	Meaning       string `json:"meaning"`
+	Order         int    `json:"order"`
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// tmdbReleaseDates is a /movie/{id}/release_dates response, out of date
+// order as TMDB sends it
+const tmdbReleaseDates = `{"id":603,"results":[
+	{"iso_3166_1":"GB","release_dates":[{"certification":"15","release_date":"1999-06-11T00:00:00.000Z","type":3}]},
+	{"iso_3166_1":"US","release_dates":[
+		{"certification":"","release_date":"1999-09-21T00:00:00.000Z","type":5},
+		{"certification":"R","release_date":"1999-03-31T00:00:00.000Z","type":3},
+		{"certification":"","note":"Westwood","release_date":"1999-03-24T00:00:00.000Z","type":1},
+		{"certification":"NR","release_date":"2021-12-22T00:00:00.000Z","type":4}]}]}`
+
+func TestReleaseDatesForCountry(t *testing.T) {
+	var r ReleaseDatesResponse
+	if err := json.Unmarshal([]byte(tmdbReleaseDates), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		country string
+		types   []ReleaseType
+		dates   []string
+	}{
+		{"US", []ReleaseType{ReleasePremiere, ReleaseTheatrical, ReleasePhysical, ReleaseDigital},
+			[]string{"1999-03-24", "1999-03-31", "1999-09-21", "2021-12-22"}},
+		{"GB", []ReleaseType{ReleaseTheatrical}, []string{"1999-06-11"}},
+		{"FR", nil, nil},
+	}
+	for _, tt := range tests {
+		releases := r.ForCountry(tt.country)
+		if len(releases) != len(tt.types) {
+			t.Errorf("ForCountry(%s) = %+v, want %d releases", tt.country, releases, len(tt.types))
+			continue
+		}
+		for i, rd := range releases {
+			if rd.Type != tt.types[i] || rd.ReleaseDate.Format("2006-01-02") != tt.dates[i] {
+				t.Errorf("ForCountry(%s)[%d] = %s on %s, want %s on %s", tt.country, i,
+					rd.Type, rd.ReleaseDate.Format("2006-01-02"), tt.types[i], tt.dates[i])
+			}
+		}
+	}
+
+	// Sorting works on a copy
+	if first := r.Results[1].ReleaseDates[0]; first.Type != ReleasePhysical {
+		t.Errorf("response reordered: first US release is %s", first.Type)
+	}
+}
+
+func TestReleaseCertification(t *testing.T) {
+	release := func(typ ReleaseType, cert string) ReleaseDate {
+		return ReleaseDate{Type: typ, Certification: cert}
+	}
+	tests := []struct {
+		name     string
+		releases []ReleaseDate
+		want     string
+	}{
+		{"theatrical wins over earlier releases",
+			[]ReleaseDate{release(ReleasePremiere, "NR"), release(ReleaseDigital, "PG"), release(ReleaseTheatrical, "PG-13")}, "PG-13"},
+		{"limited theatrical counts as theatrical",
+			[]ReleaseDate{release(ReleaseDigital, "R"), release(ReleaseTheatricalLimited, "PG-13")}, "PG-13"},
+		{"uncertified theatrical falls back to the first certified release",
+			[]ReleaseDate{release(ReleaseTheatrical, ""), release(ReleasePhysical, "R"), release(ReleaseDigital, "NC-17")}, "R"},
+		{"no certification", []ReleaseDate{release(ReleaseTheatrical, ""), release(ReleaseTV, "")}, ""},
+		{"no releases", nil, ""},
+	}
+	for _, tt := range tests {
+		r := ReleaseDatesResponse{Results: []CountryReleaseDates{
+			{ISO31661: "GB", ReleaseDates: []ReleaseDate{release(ReleaseTheatrical, "18")}},
+			{ISO31661: "US", ReleaseDates: tt.releases},
+		}}
+		if got := r.Certification("US"); got != tt.want {
+			t.Errorf("%s: Certification = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContentRatingsForCountry(t *testing.T) {
+	r := ContentRatingsResponse{Results: []ContentRating{
+		{ISO31661: "DE", Rating: "16"},
+		{ISO31661: "US", Rating: "TV-MA"},
+	}}
+	tests := []struct {
+		country string
+		want    string
+	}{
+		{"US", "TV-MA"},
+		{"DE", "16"},
+		{"GB", ""},
+	}
+	for _, tt := range tests {
+		if got := r.ForCountry(tt.country); got != tt.want {
+			t.Errorf("ForCountry(%s) = %q, want %q", tt.country, got, tt.want)
+		}
+	}
+}
+
+func TestReleaseTypeString(t *testing.T) {
+	tests := []struct {
+		typ  ReleaseType
+		want string
+	}{
+		{ReleasePremiere, "Premiere"},
+		{ReleaseTheatricalLimited, "Theatrical (limited)"},
+		{ReleaseDigital, "Digital"},
+		{ReleaseTV, "TV"},
+		{0, "Unknown"},
+		{7, "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.typ.String(); got != tt.want {
+			t.Errorf("ReleaseType(%d) = %q, want %q", int(tt.typ), got, tt.want)
+		}
+	}
+}
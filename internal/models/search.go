@@ -0,0 +1,134 @@
+package models
+
+// MultiSearchResult is one entry of a TMDB multi-search. Which fields are
+// filled depends on MediaType: "movie", "tv" or "person".
+type MultiSearchResult struct {
+	ID               int     `json:"id"`
+	MediaType        string  `json:"media_type"`
+	Adult            bool    `json:"adult"`
+	Popularity       float64 `json:"popularity"`
+	Overview         string  `json:"overview,omitempty"`
+	PosterPath       *string `json:"poster_path,omitempty"`
+	BackdropPath     *string `json:"backdrop_path,omitempty"`
+	VoteAverage      float64 `json:"vote_average,omitempty"`
+	VoteCount        int     `json:"vote_count,omitempty"`
+	GenreIDs         []int   `json:"genre_ids,omitempty"`
+	OriginalLanguage string  `json:"original_language,omitempty"`
+
+	// Movies
+	Title         string `json:"title,omitempty"`
+	OriginalTitle string `json:"original_title,omitempty"`
+	ReleaseDate   string `json:"release_date,omitempty"`
+
+	// TV shows and people
+	Name          string   `json:"name,omitempty"`
+	OriginalName  string   `json:"original_name,omitempty"`
+	FirstAirDate  string   `json:"first_air_date,omitempty"`
+	OriginCountry []string `json:"origin_country,omitempty"`
+
+	// People
+	ProfilePath        *string             `json:"profile_path,omitempty"`
+	KnownForDepartment string              `json:"known_for_department,omitempty"`
+	KnownFor           []MultiSearchResult `json:"known_for,omitempty"`
+}
+
+// Person represents a person from TMDB search results
+type Person struct {
+	ID                 int                 `json:"id"`
+	Name               string              `json:"name"`
+	ProfilePath        *string             `json:"profile_path"`
+	KnownForDepartment string              `json:"known_for_department"`
+	Popularity         float64             `json:"popularity"`
+	Adult              bool                `json:"adult"`
+	KnownFor           []MultiSearchResult `json:"known_for"`
+}
+
+// SearchGroups holds multi-search results split by media type
+type SearchGroups struct {
+	Movies  []Movie  `json:"movies"`
+	TVShows []TVShow `json:"tv_shows"`
+	People  []Person `json:"people"`
+}
+
+func (r MultiSearchResult) Movie() Movie {
+	return Movie{
+		ID:               r.ID,
+		Title:            r.Title,
+		Overview:         r.Overview,
+		PosterPath:       r.PosterPath,
+		BackdropPath:     r.BackdropPath,
+		ReleaseDate:      r.ReleaseDate,
+		VoteAverage:      r.VoteAverage,
+		VoteCount:        r.VoteCount,
+		GenreIDs:         r.GenreIDs,
+		Adult:            r.Adult,
+		OriginalLanguage: r.OriginalLanguage,
+		OriginalTitle:    r.OriginalTitle,
+		Popularity:       r.Popularity,
+	}
+}
+
+func (r MultiSearchResult) TVShow() TVShow {
+	return TVShow{
+		ID:               r.ID,
+		Name:             r.Name,
+		Overview:         r.Overview,
+		PosterPath:       r.PosterPath,
+		BackdropPath:     r.BackdropPath,
+		FirstAirDate:     r.FirstAirDate,
+		VoteAverage:      r.VoteAverage,
+		VoteCount:        r.VoteCount,
+		GenreIDs:         r.GenreIDs,
+		Adult:            r.Adult,
+		OriginalLanguage: r.OriginalLanguage,
+		OriginalName:     r.OriginalName,
+		Popularity:       r.Popularity,
+		OriginCountry:    r.OriginCountry,
+	}
+}
+
+func (r MultiSearchResult) Person() Person {
+	return Person{
+		ID:                 r.ID,
+		Name:               r.Name,
+		ProfilePath:        r.ProfilePath,
+		KnownForDepartment: r.KnownForDepartment,
+		Popularity:         r.Popularity,
+		Adult:              r.Adult,
+		KnownFor:           r.KnownFor,
+	}
+}
+
+// DisplayTitle returns the title of a movie or the name of a show or person
+func (r MultiSearchResult) DisplayTitle() string {
+	if r.MediaType == "movie" {
+		return r.Title
+	}
+	return r.Name
+}
+
+// GroupSearchResults splits multi-search results by media type, keeping TMDB's order within each group
+func GroupSearchResults(results []MultiSearchResult) SearchGroups {
+	groups := SearchGroups{Movies: []Movie{}, TVShows: []TVShow{}, People: []Person{}}
+	for _, r := range results {
+		switch r.MediaType {
+		case "movie":
+			groups.Movies = append(groups.Movies, r.Movie())
+		case "tv":
+			groups.TVShows = append(groups.TVShows, r.TVShow())
+		case "person":
+			groups.People = append(groups.People, r.Person())
+		}
+	}
+	return groups
+}
+
+// Suggestion is a compact search-as-you-type result
+type Suggestion struct {
+	ID         int     `json:"id"`
+	MediaType  string  `json:"media_type"`
+	Title      string  `json:"title"`
+	Year       string  `json:"year,omitempty"`
+	PosterURL  string  `json:"poster_url,omitempty"`
+	Popularity float64 `json:"-"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// Season is a season summary as listed in TV show details
+type Season struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Overview     string  `json:"overview"`
+	AirDate      string  `json:"air_date"`
+	EpisodeCount int     `json:"episode_count"`
+	PosterPath   *string `json:"poster_path"`
+	SeasonNumber int     `json:"season_number"`
+	VoteAverage  float64 `json:"vote_average"`
+}
+
+// SeasonDetails is a season with its episodes, from TMDB's season endpoint
+type SeasonDetails struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Overview     string    `json:"overview"`
+	AirDate      string    `json:"air_date"`
+	PosterPath   *string   `json:"poster_path"`
+	SeasonNumber int       `json:"season_number"`
+	VoteAverage  float64   `json:"vote_average"`
+	Episodes     []Episode `json:"episodes"`
+}
+
+// Episode is one episode of a season
+type Episode struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Overview      string  `json:"overview"`
+	AirDate       string  `json:"air_date"`
+	EpisodeNumber int     `json:"episode_number"`
+	SeasonNumber  int     `json:"season_number"`
+	Runtime       *int    `json:"runtime"`
+	StillPath     *string `json:"still_path"`
+	VoteAverage   float64 `json:"vote_average"`
+	VoteCount     int     `json:"vote_count"`
+	// IMDBID and IMDBRating are filled in from OMDB when it has the season
+	IMDBID     string `json:"imdb_id,omitempty"`
+	IMDBRating string `json:"imdb_rating,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// WatchStats summarizes viewing activity computed from the watchlist
+type WatchStats struct {
+	TotalTitles    int     `json:"total_titles"`
+	WatchedTitles  int     `json:"watched_titles"`
+	WatchedMovies  int     `json:"watched_movies"`
+	WatchedShows   int     `json:"watched_shows"`
+	MinutesWatched int     `json:"minutes_watched"`
+	HoursWatched   float64 `json:"hours_watched"`
+
+	PerMonth []PeriodCount `json:"per_month"`
+	PerYear  []PeriodCount `json:"per_year"`
+	Decades  []PeriodCount `json:"decades"`
+
+	TopGenres    []NameCount `json:"top_genres"`
+	TopDirectors []NameCount `json:"top_directors"`
+	TopActors    []NameCount `json:"top_actors"`
+
+	// Averages are taken over the titles the user has rated, so the two are comparable
+	RatedTitles           int     `json:"rated_titles"`
+	AveragePersonalRating float64 `json:"average_personal_rating,omitempty"`
+	AverageTMDBRating     float64 `json:"average_tmdb_rating,omitempty"`
+
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// PeriodCount counts titles within a period such as "2024-05", "2024" or "1990s"
+type PeriodCount struct {
+	Period string `json:"period"`
+	Count  int    `json:"count"`
+}
+
+// NameCount counts titles associated with a genre or person
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// WatchlistChange is a change to a tracked title that the background refresh
+// found on TMDB
+type WatchlistChange struct {
+	Type  string `json:"type"` // "movie" or "tv"
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	// Kind is "release_date", "new_season" or "status"
+	Kind       string    `json:"kind"`
+	Old        string    `json:"old"`
+	New        string    `json:"new"`
+	DetectedAt time.Time `json:"detected_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Webhook is an outgoing HTTP callback for watchlist and release events
+type Webhook struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"` // empty means every event
+	// Secret signs each delivery. It is only shown when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body of every delivery
+type WebhookPayload struct {
+	ID        string      `json:"id"` // the same on every retry, for deduplication
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery records one attempt to deliver a payload
+type WebhookDelivery struct {
+	PayloadID   string     `json:"payload_id"`
+	WebhookID   string     `json:"webhook_id"`
+	URL         string     `json:"url"`
+	Event       string     `json:"event"`
+	Attempt     int        `json:"attempt"`
+	StatusCode  int        `json:"status_code,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Success     bool       `json:"success"`
+	DurationMS  int64      `json:"duration_ms"`
+	DeliveredAt time.Time  `json:"delivered_at"`
+	NextRetry   *time.Time `json:"next_retry,omitempty"` // unset once retries run out
+}
+
+// ReleaseNotice is the data of title.released and episode.aired events
+type ReleaseNotice struct {
+	Type          string  `json:"type"` // "movie" or "tv"
+	ID            int     `json:"id"`
+	Title         string  `json:"title"`
+	Date          string  `json:"date"`
+	PosterPath    *string `json:"poster_path"`
+	SeasonNumber  int     `json:"season_number,omitempty"`
+	EpisodeNumber int     `json:"episode_number,omitempty"`
+	EpisodeName   string  `json:"episode_name,omitempty"`
+}
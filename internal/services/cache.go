@@ -0,0 +1,135 @@
+package services
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ttlCache is a small in-memory cache whose entries expire after a fixed duration
+type ttlCache[V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry[V]
+	copies  bool // Get and Set deep-copy values; see newCopyingCache
+}
+
+type cacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	return &ttlCache[V]{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry[V]),
+	}
+}
+
+// newCopyingCache is like newTTLCache but hands every caller its own deep
+// copy, for values such as TMDB details that callers may fill in or trim
+// after fetching them
+func newCopyingCache[V any](ttl time.Duration) *ttlCache[V] {
+	c := newTTLCache[V](ttl)
+	c.copies = true
+	return c
+}
+
+func (c *ttlCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	if c.copies {
+		return deepCopy(entry.value), true
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.copies {
+		value = deepCopy(value)
+	}
+	c.entries[key] = cacheEntry[V]{value: value, expiresAt: time.Now().Add(c.ttl)}
+
+	// Sweep expired entries occasionally so the map doesn't grow without bound
+	if len(c.entries)%256 == 0 {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+}
+
+// deepCopy copies v along with everything its pointers, slices, maps and
+// interfaces refer to. Unexported struct fields are copied shallowly.
+func deepCopy[V any](v V) V {
+	src := reflect.ValueOf(&v).Elem()
+	dst := reflect.New(src.Type()).Elem()
+	dst.Set(copyValue(src))
+	return dst.Interface().(V)
+}
+
+func copyValue(src reflect.Value) reflect.Value {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		dst := reflect.New(src.Type().Elem())
+		dst.Elem().Set(copyValue(src.Elem()))
+		return dst
+	case reflect.Struct:
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(src)
+		for i := range dst.NumField() {
+			if dst.Field(i).CanSet() {
+				dst.Field(i).Set(copyValue(src.Field(i)))
+			}
+		}
+		return dst
+	case reflect.Array:
+		dst := reflect.New(src.Type()).Elem()
+		for i := range src.Len() {
+			dst.Index(i).Set(copyValue(src.Index(i)))
+		}
+		return dst
+	case reflect.Slice:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := range src.Len() {
+			dst.Index(i).Set(copyValue(src.Index(i)))
+		}
+		return dst
+	case reflect.Map:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		dst := reflect.MakeMapWithSize(src.Type(), src.Len())
+		for iter := src.MapRange(); iter.Next(); {
+			dst.SetMapIndex(iter.Key(), copyValue(iter.Value()))
+		}
+		return dst
+	case reflect.Interface:
+		if src.IsNil() {
+			return reflect.Zero(src.Type())
+		}
+		dst := reflect.New(src.Type()).Elem()
+		dst.Set(copyValue(src.Elem()))
+		return dst
+	default:
+		return src
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+const (
+	// DefaultCalendarDays and MaxCalendarDays bound how far ahead the calendar looks
+	DefaultCalendarDays = 30
+	MaxCalendarDays     = 90
+
+	// calendarWorkers bounds concurrent TMDB lookups for next episodes and
+	// release dates
+	calendarWorkers = 4
+
+	// calendarCacheTTL is how long an assembled calendar is reused. A
+	// watchlist change or a new day starts a new one.
+	calendarCacheTTL = 15 * time.Minute
+
+	calendarDateLayout = "2006-01-02"
+)
+
+// CalendarService builds a release calendar from TMDB's upcoming and airing
+// lists and the watchlist: movie release dates and the next episode of each
+// tracked show
+type CalendarService struct {
+	tmdbService      *TMDBService
+	watchlistService *WatchlistService
+	cache            *ttlCache[[]models.CalendarEntry] // by day, range, scope and watchlist version
+}
+
+func NewCalendarService(tmdbService *TMDBService, watchlistService *WatchlistService) *CalendarService {
+	return &CalendarService{
+		tmdbService:      tmdbService,
+		watchlistService: watchlistService,
+		cache:            newTTLCache[[]models.CalendarEntry](calendarCacheTTL),
+	}
+}
+
+// Entries returns the releases from today through the given number of days,
+// in date order. With watchlistOnly, TMDB's upcoming and airing lists are
+// left out. Failed lookups are logged and skipped so one bad title doesn't
+// empty the calendar. Calendars are cached, so the page and feed can be
+// reloaded without looking every show up again.
+func (s *CalendarService) Entries(days int, watchlistOnly bool) []models.CalendarEntry {
+	today := time.Now()
+	from := today.Format(calendarDateLayout)
+	_, seq := s.watchlistService.State()
+	key := fmt.Sprintf("%s:%d:%t:%d", from, days, watchlistOnly, seq)
+	if cached, ok := s.cache.Get(key); ok {
+		return slices.Clone(cached)
+	}
+
+	entries := s.build(today, days, watchlistOnly)
+	s.cache.Set(key, entries)
+	return slices.Clone(entries)
+}
+
+func (s *CalendarService) build(today time.Time, days int, watchlistOnly bool) []models.CalendarEntry {
+	from := today.Format(calendarDateLayout)
+	to := today.AddDate(0, 0, days).Format(calendarDateLayout)
+	inRange := func(date string) bool { return date >= from && date <= to }
+
+	var entries []models.CalendarEntry
+	seen := make(map[string]bool)
+	add := func(e models.CalendarEntry) {
+		key := fmt.Sprintf("%s:%d:%s:%d:%d", e.Type, e.ID, e.Date, e.SeasonNumber, e.EpisodeNumber)
+		if !inRange(e.Date) || seen[key] {
+			return
+		}
+		seen[key] = true
+		if !e.InWatchlist {
+			e.InWatchlist = s.watchlistService.IsInWatchlist(e.Type, e.ID)
+		}
+		entries = append(entries, e)
+	}
+
+	// Watchlist first so its entries win when TMDB lists repeat them. The
+	// release date stored with a movie may have moved since, so movies not
+	// out yet are looked up again.
+	var showIDs, movieIDs []int
+	var movies []models.WatchlistItem
+	for _, item := range s.watchlistService.GetAllItems() {
+		if item.Type == "tv" {
+			showIDs = append(showIDs, item.ID)
+			continue
+		}
+		movies = append(movies, item)
+		if item.ReleaseDate == "" || item.ReleaseDate >= from {
+			movieIDs = append(movieIDs, item.ID)
+		}
+	}
+	releaseDates := make(map[int]string)
+	for _, details := range fetchDetails(movieIDs, s.tmdbService.GetMovieDetails, "release date for movie") {
+		releaseDates[details.ID] = details.ReleaseDate
+	}
+	for _, item := range movies {
+		date, ok := releaseDates[item.ID]
+		if !ok {
+			date = item.ReleaseDate
+		}
+		add(models.CalendarEntry{
+			Date:        date,
+			Type:        "movie",
+			ID:          item.ID,
+			Title:       item.Title,
+			PosterPath:  item.Poster(),
+			InWatchlist: true,
+		})
+	}
+
+	if !watchlistOnly {
+		if upcoming, err := s.tmdbService.GetUpcomingMovies(1); err != nil {
+			log.Printf("Error fetching upcoming movies: %v", err)
+		} else {
+			for _, m := range upcoming.Results {
+				add(models.CalendarEntry{Date: m.ReleaseDate, Type: "movie", ID: m.ID, Title: m.Title, PosterPath: m.PosterPath})
+			}
+		}
+
+		for _, list := range []func(int) (*models.TMDBResponse[models.TVShow], error){
+			s.tmdbService.GetAiringTodayTVShows,
+			s.tmdbService.GetOnTheAirTVShows,
+		} {
+			shows, err := list(1)
+			if err != nil {
+				log.Printf("Error fetching airing TV shows: %v", err)
+				continue
+			}
+			for _, show := range shows.Results {
+				showIDs = append(showIDs, show.ID)
+			}
+		}
+	}
+
+	for _, details := range fetchDetails(showIDs, s.tmdbService.GetTVShowDetails, "next episode for TV show") {
+		// A show airing today may already list today's episode as the last one
+		for _, ep := range []*models.Episode{details.LastEpisodeToAir, details.NextEpisodeToAir} {
+			if ep == nil {
+				continue
+			}
+			add(models.CalendarEntry{
+				Date:          ep.AirDate,
+				Type:          "tv",
+				ID:            details.ID,
+				Title:         details.Name,
+				PosterPath:    details.PosterPath,
+				SeasonNumber:  ep.SeasonNumber,
+				EpisodeNumber: ep.EpisodeNumber,
+				EpisodeName:   ep.Name,
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.InWatchlist != b.InWatchlist {
+			return a.InWatchlist
+		}
+		return a.Title < b.Title
+	})
+	return entries
+}
+
+// fetchDetails looks up each distinct ID with fetch, dropping failures. what
+// describes the lookup in logs.
+func fetchDetails[T any](ids []int, fetch func(int) (*T, error), what string) []*T {
+	var unique []int
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	results := make([]*T, len(unique))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range calendarWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				details, err := fetch(unique[i])
+				if err != nil {
+					if !errors.Is(err, ErrContentRestricted) {
+						log.Printf("Error fetching %s %d: %v", what, unique[i], err)
+					}
+					continue
+				}
+				results[i] = details
+			}
+		}()
+	}
+	for i := range unique {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	found := results[:0]
+	for _, details := range results {
+		if details != nil {
+			found = append(found, details)
+		}
+	}
+	return found
+}
+
+// GroupByDay splits date-ordered entries into one group per date
+func GroupByDay(entries []models.CalendarEntry) []models.CalendarDay {
+	var days []models.CalendarDay
+	for _, e := range entries {
+		if len(days) == 0 || days[len(days)-1].Date != e.Date {
+			days = append(days, models.CalendarDay{Date: e.Date})
+		}
+		days[len(days)-1].Entries = append(days[len(days)-1].Entries, e)
+	}
+	return days
+}
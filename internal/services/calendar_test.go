@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+func TestCalendarUsesCurrentReleaseDates(t *testing.T) {
+	day := func(offset int) string { return time.Now().AddDate(0, 0, offset).Format(calendarDateLayout) }
+	fake := newFakeTMDB(t, map[string]string{
+		// Moved up from the date stored when it was added
+		"/movie/10": fmt.Sprintf(`{"id":10,"title":"Moved Up","release_date":%q}`, day(5)),
+		"/tv/20":    fmt.Sprintf(`{"id":20,"name":"Show","next_episode_to_air":{"air_date":%q,"season_number":2,"episode_number":1}}`, day(3)),
+	})
+	watchlist := NewWatchlistService(filepath.Join(t.TempDir(), "watchlist.json"))
+	if _, err := watchlist.AddItems([]models.WatchlistItem{
+		{Type: "movie", ID: 10, Title: "Moved Up", ReleaseDate: day(60)},
+		{Type: "movie", ID: 11, Title: "Out Already", ReleaseDate: "1999-03-31"},
+		{Type: "tv", ID: 20, Title: "Show"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewCalendarService(NewTMDBService("test", "US"), watchlist)
+
+	entries := s.Entries(DefaultCalendarDays, true)
+	if len(entries) != 2 || entries[0].ID != 20 || entries[1].ID != 10 || entries[1].Date != day(5) {
+		t.Fatalf("entries = %+v, want the episode, then the movie on its new date", entries)
+	}
+	if n := fake.count("/movie/11"); n != 0 {
+		t.Errorf("released movie looked up %d times, want 0", n)
+	}
+
+	// The calendar is reused, and callers get their own copy
+	entries[0].Title = "changed"
+	if again := s.Entries(DefaultCalendarDays, true); again[0].Title != "Show" {
+		t.Errorf("cached calendar was changed by a caller: %+v", again[0])
+	}
+	if n := len(s.cache.entries); n != 1 {
+		t.Errorf("%d calendars cached, want 1", n)
+	}
+
+	// A watchlist change starts a new one
+	if err := watchlist.RemoveItem("tv", 20); err != nil {
+		t.Fatal(err)
+	}
+	if entries := s.Entries(DefaultCalendarDays, true); len(entries) != 1 || entries[0].ID != 10 {
+		t.Errorf("entries after removing the show = %+v, want only the movie", entries)
+	}
+}
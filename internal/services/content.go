@@ -0,0 +1,354 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"muvi-discovery-app/internal/fulltext"
+	"muvi-discovery-app/internal/models"
+)
+
+const (
+	// contentWorkers bounds concurrent certification lookups while filtering a list
+	contentWorkers = 8
+
+	// contentVerdictTTL is how long a title's certification check is reused
+	contentVerdictTTL = 24 * time.Hour
+)
+
+// ErrContentRestricted is returned for a title the content profile hides
+var ErrContentRestricted = errors.New("title is restricted by the content profile")
+
+// contentFilter applies a ContentProfile to TMDB results. Adult, genre and
+// keyword checks use the list data; certification checks look up each
+// title's rating in the region, and their verdicts are cached. Discover
+// results skip them, as TMDB has applied the cap already.
+type contentFilter struct {
+	tmdb     *TMDBService
+	profile  models.ContentProfile
+	genres   map[int]bool
+	keywords [][]string // each keyword as words, so "war" doesn't match "award"
+	verdicts *ttlCache[bool]
+}
+
+func newContentFilter(tmdb *TMDBService, profile models.ContentProfile) *contentFilter {
+	f := &contentFilter{
+		tmdb:     tmdb,
+		profile:  profile,
+		genres:   make(map[int]bool),
+		verdicts: newTTLCache[bool](contentVerdictTTL),
+	}
+	for _, id := range profile.ExcludeGenres {
+		f.genres[id] = true
+	}
+	for _, kw := range profile.ExcludeKeywords {
+		if words := fulltext.Tokenize(kw); len(words) > 0 {
+			f.keywords = append(f.keywords, words)
+		}
+	}
+	return f
+}
+
+// content describes one title for filtering
+type content struct {
+	mediaType string
+	id        int
+	adult     bool
+	genreIDs  []int
+	text      []string // title, original title and overview
+}
+
+// allowedLocally runs the checks that need no extra requests
+func (f *contentFilter) allowedLocally(c content) bool {
+	if c.adult && !f.profile.AllowAdult {
+		return false
+	}
+	for _, id := range c.genreIDs {
+		if f.genres[id] {
+			return false
+		}
+	}
+	for _, text := range c.text {
+		words := fulltext.Tokenize(text)
+		for _, kw := range f.keywords {
+			if containsWords(words, kw) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsWords reports whether phrase appears in words as a run of whole words
+func containsWords(words, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *contentFilter) maxCertification(mediaType string) string {
+	switch mediaType {
+	case "movie":
+		return f.profile.MaxMovieCertification
+	case "tv":
+		return f.profile.MaxTVCertification
+	}
+	// People have no rating
+	return ""
+}
+
+// allowedCertification reports whether a title's rating in the region is at
+// most the profile's maximum. Unrated titles and failed lookups are hidden.
+func (f *contentFilter) allowedCertification(mediaType string, id int) (bool, error) {
+	max := f.maxCertification(mediaType)
+	if max == "" {
+		return true, nil
+	}
+
+	var cert string
+	if mediaType == "tv" {
+		ratings, err := f.tmdb.GetTVContentRatings(id)
+		if err != nil {
+			return false, err
+		}
+		cert = ratings.ForCountry(f.tmdb.Region())
+	} else {
+		releases, err := f.tmdb.GetMovieReleaseDates(id)
+		if err != nil {
+			return false, err
+		}
+		cert = releases.Certification(f.tmdb.Region())
+	}
+	if cert == "" {
+		return false, nil
+	}
+
+	certs, err := f.tmdb.GetCertifications(mediaType)
+	if err != nil {
+		return false, err
+	}
+	order := make(map[string]int, len(certs))
+	for _, c := range certs {
+		order[c.Certification] = c.Order
+	}
+	maxOrder, ok := order[max]
+	if !ok {
+		return false, fmt.Errorf("certification %q is not used for %s in %s", max, mediaType, f.tmdb.Region())
+	}
+	certOrder, ok := order[cert]
+	return ok && certOrder <= maxOrder, nil
+}
+
+// allowed runs every check for a single title. Failed certification lookups
+// hide the title but aren't cached, so it's checked again next time.
+func (f *contentFilter) allowed(c content) bool {
+	if !f.allowedLocally(c) {
+		return false
+	}
+	key := fmt.Sprintf("%s:%d", c.mediaType, c.id)
+	if ok, cached := f.verdicts.Get(key); cached {
+		return ok
+	}
+	ok, err := f.allowedCertification(c.mediaType, c.id)
+	if err != nil {
+		log.Printf("Error checking certification for %s %d: %v", c.mediaType, c.id, err)
+		return false
+	}
+	f.verdicts.Set(key, ok)
+	return ok
+}
+
+// keep returns which of the titles pass, checking certifications concurrently
+// unless certified says TMDB has capped them already
+func (f *contentFilter) keep(items []content, certified bool) []bool {
+	keep := make([]bool, len(items))
+	var pending []int
+	for i, c := range items {
+		if !f.allowedLocally(c) {
+			continue
+		}
+		if certified || f.maxCertification(c.mediaType) == "" {
+			keep[i] = true
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(contentWorkers, len(pending)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				keep[i] = f.allowed(items[i])
+			}
+		}()
+	}
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return keep
+}
+
+func filterSlice[T any](items []T, describe func(T) content, f *contentFilter, certified bool) []T {
+	described := make([]content, len(items))
+	for i, item := range items {
+		described[i] = describe(item)
+	}
+	keep := f.keep(described, certified)
+
+	kept := items[:0]
+	for i, item := range items {
+		if keep[i] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+func movieContent(m models.Movie) content {
+	return content{"movie", m.ID, m.Adult, m.GenreIDs, []string{m.Title, m.OriginalTitle, m.Overview}}
+}
+
+func tvShowContent(t models.TVShow) content {
+	return content{"tv", t.ID, t.Adult, t.GenreIDs, []string{t.Name, t.OriginalName, t.Overview}}
+}
+
+func (f *contentFilter) movies(movies []models.Movie) []models.Movie {
+	return filterSlice(movies, movieContent, f, false)
+}
+
+func (f *contentFilter) tvShows(shows []models.TVShow) []models.TVShow {
+	return filterSlice(shows, tvShowContent, f, false)
+}
+
+// discoveredMovies filters discover results, which discoverParams capped with
+// certification.lte, so only the checks TMDB couldn't do run
+func (f *contentFilter) discoveredMovies(movies []models.Movie) []models.Movie {
+	return filterSlice(movies, movieContent, f, true)
+}
+
+func (f *contentFilter) discoveredTVShows(shows []models.TVShow) []models.TVShow {
+	return filterSlice(shows, tvShowContent, f, true)
+}
+
+func multiContent(r models.MultiSearchResult) content {
+	switch r.MediaType {
+	case "movie":
+		return movieContent(r.Movie())
+	case "tv":
+		return tvShowContent(r.TVShow())
+	}
+	return content{mediaType: r.MediaType, id: r.ID, adult: r.Adult}
+}
+
+// knownFor trims the titles listed under a person without certification
+// lookups, which would cost up to three requests per person
+func (f *contentFilter) knownFor(results []models.MultiSearchResult) []models.MultiSearchResult {
+	kept := results[:0]
+	for _, r := range results {
+		if f.allowedLocally(multiContent(r)) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// multi filters multi-search results; people are only checked for the adult flag
+func (f *contentFilter) multi(results []models.MultiSearchResult) []models.MultiSearchResult {
+	results = filterSlice(results, multiContent, f, false)
+	for i := range results {
+		results[i].KnownFor = f.knownFor(results[i].KnownFor)
+	}
+	return results
+}
+
+func (f *contentFilter) people(people []models.Person) []models.Person {
+	people = filterSlice(people, func(p models.Person) content {
+		return content{mediaType: "person", id: p.ID, adult: p.Adult}
+	}, f, false)
+	for i := range people {
+		people[i].KnownFor = f.knownFor(people[i].KnownFor)
+	}
+	return people
+}
+
+// discoverParams adds a discover query's certification cap and the profile's
+// restrictions, so TMDB does most of the filtering. The lower of the
+// requested certification and the profile's maximum wins.
+func (f *contentFilter) discoverParams(params url.Values, mediaType, certification string) {
+	if len(f.profile.ExcludeGenres) > 0 {
+		ids := make([]string, len(f.profile.ExcludeGenres))
+		for i, id := range f.profile.ExcludeGenres {
+			ids[i] = strconv.Itoa(id)
+		}
+		params.Set("without_genres", strings.Join(ids, ","))
+	}
+
+	if max := f.maxCertification(mediaType); max != "" && !f.certificationAtMost(mediaType, certification, max) {
+		certification = max
+	}
+	if certification != "" {
+		params.Set("certification_country", f.tmdb.Region())
+		params.Set("certification.lte", certification)
+	}
+}
+
+// certificationAtMost reports whether cert is a known rating no higher than max
+func (f *contentFilter) certificationAtMost(mediaType, cert, max string) bool {
+	if cert == "" {
+		return false
+	}
+	certs, err := f.tmdb.GetCertifications(mediaType)
+	if err != nil {
+		log.Printf("Error fetching %s certifications: %v", mediaType, err)
+		return false
+	}
+	order := make(map[string]int, len(certs))
+	for _, c := range certs {
+		order[c.Certification] = c.Order
+	}
+	certOrder, okCert := order[cert]
+	maxOrder, okMax := order[max]
+	return okCert && okMax && certOrder <= maxOrder
+}
+
+func genreIDs(genres []models.Genre) []int {
+	ids := make([]int, len(genres))
+	for i, g := range genres {
+		ids[i] = g.ID
+	}
+	return ids
+}
+
+func (f *contentFilter) checkMovie(d *models.MovieDetails) error {
+	c := movieContent(d.Movie)
+	c.genreIDs = genreIDs(d.Genres)
+	if !f.allowed(c) {
+		return ErrContentRestricted
+	}
+	return nil
+}
+
+func (f *contentFilter) checkTVShow(d *models.TVShowDetails) error {
+	c := tvShowContent(d.TVShow)
+	c.genreIDs = genreIDs(d.Genres)
+	if !f.allowed(c) {
+		return ErrContentRestricted
+	}
+	return nil
+}
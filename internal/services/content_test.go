@@ -0,0 +1,172 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"muvi-discovery-app/internal/models"
+)
+
+// fakeTMDB answers TMDB requests from canned bodies keyed by path, and
+// counts the requests it gets
+type fakeTMDB struct {
+	mu     sync.Mutex
+	bodies map[string]string
+	calls  map[string]int
+}
+
+func (f *fakeTMDB) RoundTrip(r *http.Request) (*http.Response, error) {
+	path := strings.TrimPrefix(r.URL.Path, "/3")
+	f.mu.Lock()
+	f.calls[path]++
+	body, ok := f.bodies[path]
+	f.mu.Unlock()
+
+	status := http.StatusOK
+	if !ok {
+		status, body = http.StatusNotFound, `{"status_message":"not found"}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+func (f *fakeTMDB) count(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[path]
+}
+
+// newFakeTMDB routes the default transport, which TMDBService uses, to a
+// fakeTMDB for the rest of the test
+func newFakeTMDB(t *testing.T, bodies map[string]string) *fakeTMDB {
+	t.Helper()
+	fake := &fakeTMDB{bodies: bodies, calls: make(map[string]int)}
+	transport := http.DefaultTransport
+	http.DefaultTransport = fake
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	return fake
+}
+
+const usCertifications = `{"certifications":{"US":[
+	{"certification":"G","order":1},{"certification":"PG","order":2},
+	{"certification":"PG-13","order":3},{"certification":"R","order":4}]}}`
+
+func releaseDates(cert string) string {
+	return `{"results":[{"iso_3166_1":"US","release_dates":[{"certification":"` + cert + `","release_date":"2020-01-01T00:00:00.000Z","type":3}]}]}`
+}
+
+func TestContentKeywordsMatchWholeWords(t *testing.T) {
+	f := newContentFilter(nil, models.ContentProfile{ExcludeKeywords: []string{"war", " Serial Killer ", ""}})
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Award-winning drama", true},
+		{"Star Wars", true},
+		{"Software engineers", true},
+		{"War of the Worlds", false},
+		{"After the war, a soldier returns", false},
+		{"WAR!", false},
+		{"A serial killer stalks the city", false},
+		{"A serial about a killer whale", true},
+	}
+	for _, tt := range tests {
+		if got := f.allowedLocally(content{text: []string{tt.text}}); got != tt.want {
+			t.Errorf("allowedLocally(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestContentAdultAndGenres(t *testing.T) {
+	f := newContentFilter(nil, models.ContentProfile{ExcludeGenres: []int{27}})
+	if f.allowedLocally(content{adult: true}) {
+		t.Error("adult title allowed without AllowAdult")
+	}
+	if f.allowedLocally(content{genreIDs: []int{18, 27}}) {
+		t.Error("title in an excluded genre allowed")
+	}
+	if !f.allowedLocally(content{genreIDs: []int{18}}) {
+		t.Error("title in an allowed genre hidden")
+	}
+
+	f = newContentFilter(nil, models.ContentProfile{AllowAdult: true})
+	if !f.allowedLocally(content{adult: true}) {
+		t.Error("adult title hidden with AllowAdult")
+	}
+}
+
+func TestContentCertifications(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/certification/movie/list": usCertifications,
+		"/movie/1/release_dates":    releaseDates("PG"),
+		"/movie/2/release_dates":    releaseDates("R"),
+		"/movie/3/release_dates":    releaseDates(""),
+	})
+	tmdb := NewTMDBService("test", "US")
+	tmdb.SetContentProfile(models.ContentProfile{MaxMovieCertification: "PG-13"})
+
+	movies := []models.Movie{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	kept := tmdb.content.movies(movies)
+	if len(kept) != 1 || kept[0].ID != 1 {
+		t.Fatalf("kept %+v, want only movie 1 (2 is R, 3 unrated, 4 failed)", kept)
+	}
+
+	// Verdicts are cached, failed lookups are not
+	tmdb.content.movies([]models.Movie{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}})
+	for _, path := range []string{"/movie/1/release_dates", "/movie/2/release_dates", "/movie/3/release_dates"} {
+		if n := fake.count(path); n != 1 {
+			t.Errorf("%s requested %d times, want 1", path, n)
+		}
+	}
+	if n := fake.count("/movie/4/release_dates"); n != 2 {
+		t.Errorf("failed lookup requested %d times, want 2", n)
+	}
+
+	// Discover results were capped by TMDB, so they aren't looked up
+	tmdb.content.discoveredMovies([]models.Movie{{ID: 5}})
+	if n := fake.count("/movie/5/release_dates"); n != 0 {
+		t.Errorf("discover result looked up %d times", n)
+	}
+
+	// People have no rating to check
+	people := tmdb.content.people([]models.Person{{ID: 6}})
+	if len(people) != 1 || fake.count("/movie/6/release_dates") != 0 {
+		t.Errorf("person filtered by certification: %+v", people)
+	}
+}
+
+func TestContentDiscoverParams(t *testing.T) {
+	newFakeTMDB(t, map[string]string{"/certification/movie/list": usCertifications})
+	tmdb := NewTMDBService("test", "US")
+	tmdb.SetContentProfile(models.ContentProfile{MaxMovieCertification: "PG-13", ExcludeGenres: []int{27, 53}})
+
+	tests := []struct {
+		requested, want string
+	}{
+		{"", "PG-13"},
+		{"PG", "PG"},
+		{"R", "PG-13"},
+		{"NC-17", "PG-13"},
+	}
+	for _, tt := range tests {
+		params := url.Values{}
+		tmdb.content.discoverParams(params, "movie", tt.requested)
+		if got := params.Get("certification.lte"); got != tt.want {
+			t.Errorf("certification %q: certification.lte = %q, want %q", tt.requested, got, tt.want)
+		}
+		if got := params.Get("without_genres"); got != "27,53" {
+			t.Errorf("without_genres = %q", got)
+		}
+		if got := params.Get("certification_country"); got != "US" {
+			t.Errorf("certification_country = %q", got)
+		}
+	}
+}
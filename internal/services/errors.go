@@ -0,0 +1,27 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// APIError is a non-200 answer from an upstream API
+type APIError struct {
+	Service    string // "TMDB" or "OMDB"
+	StatusCode int
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API request failed with status: %d", e.Service, e.StatusCode)
+}
+
+// IsNotFound reports whether err means the requested title, image or other
+// resource doesn't exist upstream, as opposed to the upstream failing
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+	return errors.Is(err, ErrOMDBNotFound) || errors.Is(err, ErrImageNotFound)
+}
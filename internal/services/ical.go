@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+// icsEscaper escapes TEXT values as required by RFC 5545
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`, "\r", "")
+
+// WriteICS writes calendar entries as an iCalendar feed of all-day events.
+// baseURL (e.g. "https://example.com") is used for event links; UIDs stay
+// stable across refreshes so calendar apps update events instead of
+// duplicating them.
+func WriteICS(w io.Writer, entries []models.CalendarEntry, baseURL string) error {
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		writeICSLine(&b, fmt.Sprintf(format, args...))
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Muvi Discovery//Release Calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:Muvi Discovery Releases")
+
+	for _, e := range entries {
+		day, err := time.Parse(calendarDateLayout, e.Date)
+		if err != nil {
+			continue
+		}
+
+		uid := fmt.Sprintf("movie-%d", e.ID)
+		summary := e.Title
+		link := fmt.Sprintf("%s/movies/%d", baseURL, e.ID)
+		if e.Type == "tv" {
+			uid = fmt.Sprintf("tv-%d-s%de%d", e.ID, e.SeasonNumber, e.EpisodeNumber)
+			summary = fmt.Sprintf("%s S%02dE%02d", e.Title, e.SeasonNumber, e.EpisodeNumber)
+			if e.EpisodeName != "" {
+				summary += " - " + e.EpisodeName
+			}
+			link = fmt.Sprintf("%s/tv/%d/season/%d", baseURL, e.ID, e.SeasonNumber)
+		}
+
+		line("BEGIN:VEVENT")
+		line("UID:%s@muvi-discovery", uid)
+		line("DTSTAMP:%s", stamp)
+		line("DTSTART;VALUE=DATE:%s", day.Format("20060102"))
+		line("DTEND;VALUE=DATE:%s", day.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:%s", icsEscaper.Replace(summary))
+		line("URL:%s", link)
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+
+	line("END:VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeICSLine ends a content line with CRLF, folding it so no line is
+// longer than 75 octets without splitting a UTF-8 sequence
+func writeICSLine(b *strings.Builder, s string) {
+	// Continuation lines start with a space, leaving one octet less
+	for limit := 75; len(s) > limit; limit = 74 {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"muvi-discovery-app/internal/models"
+)
+
+func writeTestICS(t *testing.T, entries []models.CalendarEntry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := WriteICS(&b, entries, "https://muvi.example"); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// unfoldICS joins folded lines back into content lines
+func unfoldICS(feed string) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(feed, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func TestWriteICSEscapesText(t *testing.T) {
+	feed := writeTestICS(t, []models.CalendarEntry{{
+		Date:  "2026-11-01",
+		Type:  "movie",
+		ID:    1,
+		Title: "Back\\Slash; Commas, and\nNew lines\r",
+	}})
+
+	want := `SUMMARY:Back\\Slash\; Commas\, and\nNew lines`
+	for _, line := range unfoldICS(feed) {
+		if strings.HasPrefix(line, "SUMMARY:") {
+			if line != want {
+				t.Errorf("got %q, want %q", line, want)
+			}
+			return
+		}
+	}
+	t.Fatalf("no SUMMARY in feed:\n%s", feed)
+}
+
+func TestWriteICSFoldsLongLines(t *testing.T) {
+	title := strings.TrimSpace(strings.Repeat("Amélie à Zürich ", 20))
+	feed := writeTestICS(t, []models.CalendarEntry{{
+		Date:          "2026-11-01",
+		Type:          "tv",
+		ID:            2,
+		Title:         title,
+		SeasonNumber:  1,
+		EpisodeNumber: 2,
+	}})
+
+	if !strings.HasSuffix(feed, "\r\n") || strings.Contains(strings.ReplaceAll(feed, "\r\n", ""), "\n") {
+		t.Fatal("lines don't all end in CRLF")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(feed, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("fold splits a UTF-8 sequence: %q", line)
+		}
+	}
+
+	want := "SUMMARY:" + title + " S01E02"
+	found := false
+	for _, line := range unfoldICS(feed) {
+		found = found || line == want
+	}
+	if !found {
+		t.Errorf("unfolded feed lacks %q:\n%s", want, feed)
+	}
+}
+
+func TestWriteICSKeepsUIDsStable(t *testing.T) {
+	entries := []models.CalendarEntry{
+		{Date: "2026-11-01", Type: "movie", ID: 603, Title: "The Matrix"},
+		{Date: "2026-11-02", Type: "tv", ID: 1399, Title: "Show", SeasonNumber: 8, EpisodeNumber: 3},
+		{Date: "2026-11-09", Type: "tv", ID: 1399, Title: "Show", SeasonNumber: 8, EpisodeNumber: 4},
+	}
+	uidPattern := regexp.MustCompile(`(?m)^UID:(.*)\r$`)
+	uids := func(feed string) []string {
+		var uids []string
+		for _, m := range uidPattern.FindAllStringSubmatch(feed, -1) {
+			uids = append(uids, m[1])
+		}
+		return uids
+	}
+
+	first := uids(writeTestICS(t, entries))
+	want := []string{"movie-603@muvi-discovery", "tv-1399-s8e3@muvi-discovery", "tv-1399-s8e4@muvi-discovery"}
+	if strings.Join(first, " ") != strings.Join(want, " ") {
+		t.Fatalf("UIDs = %v, want %v", first, want)
+	}
+
+	// A moved release date or renamed episode is the same event
+	entries[0].Date = "2026-12-01"
+	entries[1].EpisodeName = "The Long Night"
+	if again := uids(writeTestICS(t, entries)); strings.Join(again, " ") != strings.Join(want, " ") {
+		t.Errorf("UIDs changed to %v", again)
+	}
+}
@@ -0,0 +1,456 @@
+package services
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultImageCacheSize bounds the disk cache when no size is configured
+const DefaultImageCacheSize = 512 << 20
+
+const (
+	// maxImageBytes caps a download from TMDB; originals are a few MB at most
+	maxImageBytes = 20 << 20
+
+	// maxImagePixels caps the size of an image decoded for resizing
+	maxImagePixels = 50_000_000
+)
+
+// tmdbImageWidths are the widths TMDB serves directly, smallest first
+var tmdbImageWidths = []int{45, 92, 154, 185, 300, 342, 500, 780, 1280}
+
+// localImageWidths are the extra widths resized locally, for srcset steps
+// TMDB skips. Other widths are refused, so the cache can't be filled with
+// one copy of an image per pixel width.
+var localImageWidths = []int{120, 240, 400, 640, 1000}
+
+// imagePathPattern matches TMDB image file names such as "/kqjL17yufvn9OVLyXYpvtyrFfak.jpg"
+var imagePathPattern = regexp.MustCompile(`^/[A-Za-z0-9_-]+\.(jpg|png|svg)$`)
+
+var (
+	// ErrInvalidImage is returned for a size or path the proxy doesn't serve
+	ErrInvalidImage = errors.New("invalid image size or path")
+	// ErrImageNotFound is returned when TMDB has no such image
+	ErrImageNotFound = errors.New("image not found")
+)
+
+// CachedImage is an open file from the image cache; callers close File
+type CachedImage struct {
+	File    *os.File
+	Name    string
+	ModTime time.Time
+	// ETag never changes for a size and path, since TMDB gives every
+	// image version a new file name
+	ETag string
+}
+
+// ImageService proxies TMDB images through a disk cache. Files are evicted
+// least recently used first once the cache grows past its size limit. Widths
+// TMDB doesn't serve are resized locally from the next larger one. Images
+// keep TMDB's format. WebP output is not implemented: the standard library
+// has no WebP encoder, and a lossless one would make posters larger than
+// TMDB's JPEGs.
+type ImageService struct {
+	dir        string
+	maxSize    int64
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	size     int64
+	lru      *list.List               // of *imageEntry, most recently used first
+	entries  map[string]*list.Element // by cache key, "size/name"
+	inflight map[string]*imageFetch
+}
+
+type imageEntry struct {
+	key  string
+	size int64
+	pins int // readers using the file; pinned entries aren't evicted
+}
+
+// imageFetch lets concurrent requests for the same image share one download
+type imageFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewImageService opens the cache in dir, indexing any files already there.
+// maxSize is in bytes; 0 uses DefaultImageCacheSize.
+func NewImageService(dir string, maxSize int64) *ImageService {
+	if maxSize <= 0 {
+		maxSize = DefaultImageCacheSize
+	}
+	s := &ImageService{
+		dir:     dir,
+		maxSize: maxSize,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]*imageFetch),
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Error creating image cache directory: %v", err)
+	}
+	s.loadIndex()
+	return s
+}
+
+// loadIndex rebuilds the LRU order from file modification times, which Open
+// bumps on every hit
+func (s *ImageService) loadIndex() {
+	type file struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	filepath.WalkDir(s.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			// Leftover from an interrupted download
+			os.Remove(path)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return nil
+		}
+		files = append(files, file{filepath.ToSlash(rel), info.Size(), info.ModTime()})
+		return nil
+	})
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		s.entries[f.key] = s.lru.PushFront(&imageEntry{key: f.key, size: f.size})
+		s.size += f.size
+	}
+	s.evict()
+}
+
+// Open returns the image at a TMDB size ("original" or "w" plus a width in
+// pixels) and path, downloading or generating it on a miss
+func (s *ImageService) Open(size, path string) (*CachedImage, error) {
+	width, err := parseImageSize(size)
+	if err != nil || !imagePathPattern.MatchString(path) {
+		return nil, ErrInvalidImage
+	}
+
+	key := size + path
+	filename := s.filename(key)
+	release, err := s.acquire(key, func() ([]byte, error) { return s.produce(size, width, path) })
+	if err != nil {
+		return nil, err
+	}
+	// Once open, the file stays readable even if it's evicted
+	f, err := os.Open(filename)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	s.touch(key)
+	now := time.Now()
+	os.Chtimes(filename, now, now)
+
+	sum := sha1.Sum([]byte(key))
+	return &CachedImage{
+		File:    f,
+		Name:    filepath.Base(filename),
+		ModTime: info.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%s"`, sum[:8], strconv.FormatInt(info.Size(), 36)),
+	}, nil
+}
+
+// parseImageSize returns the width for "w<pixels>", or 0 for "original".
+// Only TMDB's widths and localImageWidths are accepted.
+func parseImageSize(size string) (int, error) {
+	if size == "original" {
+		return 0, nil
+	}
+	if !strings.HasPrefix(size, "w") {
+		return 0, ErrInvalidImage
+	}
+	width, err := strconv.Atoi(size[1:])
+	if err != nil || strconv.Itoa(width) != size[1:] {
+		return 0, ErrInvalidImage
+	}
+	if !slices.Contains(tmdbImageWidths, width) && !slices.Contains(localImageWidths, width) {
+		return 0, ErrInvalidImage
+	}
+	return width, nil
+}
+
+func (s *ImageService) filename(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+// acquire makes sure key is cached, running produce at most once at a time
+// per key, and pins it so it isn't evicted until release is called
+func (s *ImageService) acquire(key string, produce func() ([]byte, error)) (release func(), err error) {
+	// Another request's store can evict the image between a shared fetch
+	// finishing and this one pinning it, so that is retried
+	for range 3 {
+		s.mu.Lock()
+		if el, ok := s.entries[key]; ok {
+			el.Value.(*imageEntry).pins++
+			s.mu.Unlock()
+			return s.releaser(key), nil
+		}
+		if fetch, ok := s.inflight[key]; ok {
+			s.mu.Unlock()
+			<-fetch.done
+			if fetch.err != nil {
+				return nil, fetch.err
+			}
+			continue
+		}
+		fetch := &imageFetch{done: make(chan struct{})}
+		s.inflight[key] = fetch
+		s.mu.Unlock()
+
+		data, err := produce()
+		if err == nil {
+			err = s.store(key, data)
+		}
+
+		s.mu.Lock()
+		delete(s.inflight, key)
+		s.mu.Unlock()
+		fetch.err = err
+		close(fetch.done)
+		if err != nil {
+			return nil, err
+		}
+		return s.releaser(key), nil
+	}
+	return nil, fmt.Errorf("image %s was evicted before it could be read", key)
+}
+
+// releaser unpins key, evicting it then if the cache is over its limit
+func (s *ImageService) releaser(key string) func() {
+	return sync.OnceFunc(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if el, ok := s.entries[key]; ok {
+			el.Value.(*imageEntry).pins--
+		}
+		s.evict()
+	})
+}
+
+// produce downloads a size TMDB serves, or resizes the next larger one
+func (s *ImageService) produce(size string, width int, path string) ([]byte, error) {
+	source := sourceSize(width)
+	if source == size || strings.HasSuffix(path, ".svg") {
+		return s.download(size, path)
+	}
+
+	sourceKey := source + path
+	release, err := s.acquire(sourceKey, func() ([]byte, error) { return s.download(source, path) })
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(s.filename(sourceKey))
+	release()
+	if err != nil {
+		return nil, err
+	}
+	return resizeImage(data, width)
+}
+
+// sourceSize picks the smallest TMDB size at least width pixels wide
+func sourceSize(width int) string {
+	if width == 0 {
+		return "original"
+	}
+	for _, w := range tmdbImageWidths {
+		if w >= width {
+			return "w" + strconv.Itoa(w)
+		}
+	}
+	return "original"
+}
+
+func (s *ImageService) download(size, path string) ([]byte, error) {
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/%s%s", TMDBImageBaseURL, size, path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrImageNotFound
+	default:
+		return nil, &APIError{Service: "TMDB image", StatusCode: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image %s%s is larger than %d bytes", size, path, maxImageBytes)
+	}
+	return data, nil
+}
+
+// store writes an image into the cache through a temporary file, so readers
+// never see a partial one, then evicts old images if the cache is too big.
+// The new entry is stored pinned, for its caller to release.
+func (s *ImageService) store(key string, data []byte) error {
+	filename := s.filename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".download-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = s.lru.PushFront(&imageEntry{key: key, size: int64(len(data)), pins: 1})
+	s.size += int64(len(data))
+	s.evict()
+	return nil
+}
+
+// touch marks key as the most recently used image
+func (s *ImageService) touch(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.lru.MoveToFront(el)
+	}
+}
+
+// evict removes least recently used images until the cache fits, skipping
+// pinned ones, which are evicted once released; callers hold s.mu
+func (s *ImageService) evict() {
+	el := s.lru.Back()
+	for el != nil && s.size > s.maxSize {
+		entry := el.Value.(*imageEntry)
+		prev := el.Prev()
+		if entry.pins == 0 {
+			s.lru.Remove(el)
+			delete(s.entries, entry.key)
+			s.size -= entry.size
+			if err := os.Remove(s.filename(entry.key)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Error evicting cached image %s: %v", entry.key, err)
+			}
+		}
+		el = prev
+	}
+}
+
+// resizeImage scales a JPEG or PNG down to width pixels, keeping its format
+// and aspect ratio. Images already narrower are returned unchanged.
+func resizeImage(data []byte, width int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("image is too large to resize: %dx%d", cfg.Width, cfg.Height)
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if src.Bounds().Dx() <= width {
+		return data, nil
+	}
+
+	dst := scaleToWidth(src, width)
+	var buf bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleToWidth downscales with a box filter: each output pixel averages the
+// source pixels it covers
+func scaleToWidth(src image.Image, width int) *image.RGBA64 {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	height := max(1, int(math.Round(float64(sh)*float64(width)/float64(sw))))
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*sh/height
+		y1 := max(b.Min.Y+(y+1)*sh/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*sw/width
+			x1 := max(b.Min.X+(x+1)*sw/width, x0+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testPNG encodes a width × height image
+func testPNG(t *testing.T, width, height int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func readImage(t *testing.T, img *CachedImage) []byte {
+	t.Helper()
+	defer img.File.Close()
+	data, err := io.ReadAll(img.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseImageSize(t *testing.T) {
+	tests := []struct {
+		size  string
+		width int
+		ok    bool
+	}{
+		{"original", 0, true},
+		{"w500", 500, true},
+		{"w45", 45, true},
+		{"w1280", 1280, true},
+		{"w240", 240, true}, // generated locally
+		{"w241", 0, false},
+		{"w3840", 0, false},
+		{"w0", 0, false},
+		{"w0500", 0, false},
+		{"w-500", 0, false},
+		{"h632", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		width, err := parseImageSize(tt.size)
+		if (err == nil) != tt.ok || width != tt.width {
+			t.Errorf("parseImageSize(%q) = %d, %v, want %d, ok %v", tt.size, width, err, tt.width, tt.ok)
+		}
+	}
+}
+
+func TestImageServiceCachesDownloads(t *testing.T) {
+	poster := testPNG(t, 50, 75)
+	fake := newFakeTMDB(t, map[string]string{"/t/p/w500/poster.png": poster})
+	s := NewImageService(t.TempDir(), 0)
+
+	first, err := s.Open("w500", "/poster.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readImage(t, first); string(got) != poster {
+		t.Errorf("served %d bytes, want the %d downloaded", len(got), len(poster))
+	}
+
+	second, err := s.Open("w500", "/poster.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readImage(t, second)
+	if n := fake.count("/t/p/w500/poster.png"); n != 1 {
+		t.Errorf("downloaded %d times, want 1", n)
+	}
+	if first.ETag == "" || first.ETag != second.ETag {
+		t.Errorf("ETags %s and %s, want the same non-empty tag", first.ETag, second.ETag)
+	}
+
+	// The cache is rebuilt from disk on restart
+	restarted := NewImageService(s.dir, 0)
+	third, err := restarted.Open("w500", "/poster.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readImage(t, third)
+	if n := fake.count("/t/p/w500/poster.png"); n != 1 {
+		t.Errorf("downloaded %d times after restart, want 1", n)
+	}
+}
+
+func TestImageServiceResizes(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{"/t/p/w300/poster.png": testPNG(t, 300, 450)})
+	s := NewImageService(t.TempDir(), 0)
+
+	img, err := s.Open("w240", "/poster.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(readImage(t, img)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "png" || cfg.Width != 240 || cfg.Height != 360 {
+		t.Errorf("got a %dx%d %s, want a 240x360 png", cfg.Width, cfg.Height, format)
+	}
+
+	// The source size is cached too, and served without resizing
+	if _, err := os.Stat(s.filename("w300/poster.png")); err != nil {
+		t.Errorf("source image wasn't cached: %v", err)
+	}
+	if img, err := s.Open("w300", "/poster.png"); err != nil {
+		t.Error(err)
+	} else {
+		readImage(t, img)
+	}
+	if n := fake.count("/t/p/w300/poster.png"); n != 1 {
+		t.Errorf("downloaded the source %d times, want 1", n)
+	}
+}
+
+func TestImageServiceErrors(t *testing.T) {
+	newFakeTMDB(t, map[string]string{
+		"/t/p/w500/huge.jpg": strings.Repeat("x", maxImageBytes+1),
+		"/t/p/w780/text.jpg": "not an image",
+	})
+	s := NewImageService(t.TempDir(), 0)
+
+	tests := []struct {
+		size, path string
+		want       error
+	}{
+		{"w241", "/poster.jpg", ErrInvalidImage},
+		{"w500", "/../../etc/passwd", ErrInvalidImage},
+		{"w500", "/poster.gif", ErrInvalidImage},
+		{"w500", "/missing.jpg", ErrImageNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := s.Open(tt.size, tt.path); !errors.Is(err, tt.want) {
+			t.Errorf("Open(%q, %q) error = %v, want %v", tt.size, tt.path, err, tt.want)
+		}
+	}
+
+	if _, err := s.Open("w500", "/huge.jpg"); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("oversized download: err = %v", err)
+	}
+	if _, err := s.Open("w640", "/text.jpg"); err == nil || !strings.Contains(err.Error(), "decode") {
+		t.Errorf("resizing a non-image: err = %v", err)
+	}
+	if s.size != int64(len("not an image")) {
+		t.Errorf("cache holds %d bytes, want only the undecodable source", s.size)
+	}
+}
+
+func TestImageServiceEvictsLeastRecentlyUsed(t *testing.T) {
+	image := testPNG(t, 20, 30)
+	newFakeTMDB(t, map[string]string{
+		"/t/p/w92/a.png": image,
+		"/t/p/w92/b.png": image,
+		"/t/p/w92/c.png": image,
+	})
+	size := int64(len(image))
+	s := NewImageService(t.TempDir(), 2*size)
+
+	open := func(name string) {
+		t.Helper()
+		img, err := s.Open("w92", "/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		readImage(t, img)
+	}
+	cached := func(name string) bool {
+		_, err := os.Stat(s.filename("w92/" + name))
+		return err == nil
+	}
+
+	open("a.png")
+	open("b.png")
+	open("a.png") // b is now the least recently used
+	open("c.png")
+	if !cached("a.png") || cached("b.png") || !cached("c.png") {
+		t.Errorf("cached a=%v b=%v c=%v, want a and c", cached("a.png"), cached("b.png"), cached("c.png"))
+	}
+	if s.size != 2*size {
+		t.Errorf("cache size = %d, want %d", s.size, 2*size)
+	}
+}
+
+func TestImageServiceKeepsPinnedImages(t *testing.T) {
+	image := testPNG(t, 20, 30)
+	newFakeTMDB(t, map[string]string{"/t/p/w92/a.png": image, "/t/p/w92/b.png": image})
+	s := NewImageService(t.TempDir(), int64(len(image)))
+
+	// a is being read, e.g. as the source of a resize, when b pushes the
+	// cache over its limit
+	release, err := s.acquire("w92/a.png", func() ([]byte, error) { return s.download("w92", "/a.png") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := s.Open("w92", "/b.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readImage(t, img)
+
+	// b was the only image that could go
+	data, err := os.ReadFile(s.filename("w92/a.png"))
+	if err != nil || string(data) != image {
+		t.Fatalf("pinned image was evicted: %v", err)
+	}
+	if _, err := os.Stat(s.filename("w92/b.png")); !os.IsNotExist(err) {
+		t.Errorf("unpinned image is still cached: %v", err)
+	}
+
+	release()
+	release() // releasing twice is harmless
+	if s.size != int64(len(image)) || s.entries["w92/a.png"].Value.(*imageEntry).pins != 0 {
+		t.Errorf("cache size = %d, want %d with a unpinned", s.size, len(image))
+	}
+}
@@ -2,10 +2,15 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"muvi-discovery-app/internal/models"
@@ -13,9 +18,26 @@ import (
 
 const OMDBBaseURL = "https://www.omdbapi.com"
 
+// omdbCacheTTL is long because the free OMDB tier allows only 1,000 requests a day
+const omdbCacheTTL = 24 * time.Hour
+
+// ErrOMDBQuotaExceeded is returned once OMDB reports the daily request limit
+// is used up. No further requests are sent until the limit resets.
+var ErrOMDBQuotaExceeded = errors.New("OMDB daily request limit reached")
+
+// ErrOMDBNotFound is returned when OMDB has no record for an IMDb ID
+var ErrOMDBNotFound = errors.New("movie not found")
+
 type OMDBService struct {
 	apiKey     string
 	httpClient *http.Client
+	cache      *ttlCache[*models.OMDBMovie] // by IMDb ID; nil records a title OMDB doesn't know
+	series     *ttlCache[*models.OMDBSeries]
+	seasons    *ttlCache[*models.OMDBSeason]  // by "imdbID:season"
+	episodes   *ttlCache[*models.OMDBEpisode] // by "imdbID:season:episode"
+
+	mu           sync.Mutex
+	quotaResetAt time.Time
 }
 
 func NewOMDBService(apiKey string) *OMDBService {
@@ -24,7 +46,29 @@ func NewOMDBService(apiKey string) *OMDBService {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		cache:    newTTLCache[*models.OMDBMovie](omdbCacheTTL),
+		series:   newTTLCache[*models.OMDBSeries](omdbCacheTTL),
+		seasons:  newTTLCache[*models.OMDBSeason](omdbCacheTTL),
+		episodes: newTTLCache[*models.OMDBEpisode](omdbCacheTTL),
+	}
+}
+
+// QuotaExhausted reports whether the daily request limit was hit and hasn't reset yet
+func (s *OMDBService) QuotaExhausted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Before(s.quotaResetAt)
+}
+
+// markQuotaExhausted pauses requests until the next UTC midnight, when OMDB resets daily limits
+func (s *OMDBService) markQuotaExhausted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Now().Before(s.quotaResetAt) {
+		return
 	}
+	s.quotaResetAt = time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	log.Printf("OMDB request limit reached; pausing OMDB requests until %s", s.quotaResetAt.Format(time.RFC3339))
 }
 
 func (s *OMDBService) makeRequest(params url.Values) (*http.Response, error) {
@@ -33,6 +77,10 @@ func (s *OMDBService) makeRequest(params url.Values) (*http.Response, error) {
 	}
 	params.Set("apikey", s.apiKey)
 
+	if s.QuotaExhausted() {
+		return nil, ErrOMDBQuotaExceeded
+	}
+
 	reqURL := fmt.Sprintf("%s?%s", OMDBBaseURL, params.Encode())
 	
 	resp, err := s.httpClient.Get(reqURL)
@@ -41,14 +89,41 @@ func (s *OMDBService) makeRequest(params url.Values) (*http.Response, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		resp.Body.Close()
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		defer resp.Body.Close()
+		// Over quota, OMDB answers 401 with {"Response":"False","Error":"Request limit reached!"}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if strings.Contains(string(body), "Request limit reached") {
+			s.markQuotaExhausted()
+			return nil, ErrOMDBQuotaExceeded
+		}
+		return nil, &APIError{Service: "OMDB", StatusCode: resp.StatusCode}
 	}
 
 	return resp, nil
 }
 
+// failure converts a "Response":"False" answer to an error. Only an unknown
+// title or ID gives ErrOMDBNotFound, which callers may cache; other errors,
+// such as "Error getting data.", are passing failures.
+func (s *OMDBService) failure(message string) error {
+	switch {
+	case message == "Movie not found!", message == "Incorrect IMDb ID.", message == "Series or episode not found!":
+		return ErrOMDBNotFound
+	case strings.Contains(message, "Request limit reached"):
+		s.markQuotaExhausted()
+		return ErrOMDBQuotaExceeded
+	}
+	return fmt.Errorf("OMDB request failed: %s", message)
+}
+
 func (s *OMDBService) GetMovieByIMDBID(imdbID string) (*models.OMDBMovie, error) {
+	if cached, ok := s.cache.Get(imdbID); ok {
+		if cached == nil {
+			return nil, ErrOMDBNotFound
+		}
+		return cached, nil
+	}
+
 	params := url.Values{}
 	params.Set("i", imdbID)
 	params.Set("plot", "full")
@@ -65,9 +140,14 @@ func (s *OMDBService) GetMovieByIMDBID(imdbID string) (*models.OMDBMovie, error)
 	}
 
 	if result.Response == "False" {
-		return nil, fmt.Errorf("movie not found")
+		err := s.failure(result.Error)
+		if errors.Is(err, ErrOMDBNotFound) {
+			s.cache.Set(imdbID, nil)
+		}
+		return nil, err
 	}
 
+	s.cache.Set(imdbID, &result)
 	return &result, nil
 }
 
@@ -92,21 +172,34 @@ func (s *OMDBService) GetMovieByTitle(title string, year *int) (*models.OMDBMovi
 	}
 
 	if result.Response == "False" {
-		return nil, fmt.Errorf("movie not found")
+		return nil, s.failure(result.Error)
 	}
 
 	return &result, nil
 }
 
-func (s *OMDBService) SearchMovies(title string, page int) (*struct {
+// OMDBSearchResults is a page of OMDB search results
+type OMDBSearchResults struct {
 	Search       []models.OMDBMovie `json:"Search"`
 	TotalResults string             `json:"totalResults"`
 	Response     string             `json:"Response"`
-}, error) {
+}
+
+func (s *OMDBService) SearchMovies(title string, page int) (*OMDBSearchResults, error) {
+	return s.search(title, "movie", page)
+}
+
+// SearchSeries searches TV series by title
+func (s *OMDBService) SearchSeries(title string, page int) (*OMDBSearchResults, error) {
+	return s.search(title, "series", page)
+}
+
+// search runs an OMDB title search limited to kind: "movie", "series" or "episode"
+func (s *OMDBService) search(title, kind string, page int) (*OMDBSearchResults, error) {
 	params := url.Values{}
 	params.Set("s", title)
 	params.Set("page", strconv.Itoa(page))
-	params.Set("type", "movie")
+	params.Set("type", kind)
 
 	resp, err := s.makeRequest(params)
 	if err != nil {
@@ -114,11 +207,7 @@ func (s *OMDBService) SearchMovies(title string, page int) (*struct {
 	}
 	defer resp.Body.Close()
 
-	var result struct {
-		Search       []models.OMDBMovie `json:"Search"`
-		TotalResults string             `json:"totalResults"`
-		Response     string             `json:"Response"`
-	}
+	var result OMDBSearchResults
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
@@ -130,6 +219,132 @@ func (s *OMDBService) SearchMovies(title string, page int) (*struct {
 	return &result, nil
 }
 
+// GetSeriesByIMDBID returns a TV series record, including its season count
+func (s *OMDBService) GetSeriesByIMDBID(imdbID string) (*models.OMDBSeries, error) {
+	if cached, ok := s.series.Get(imdbID); ok {
+		if cached == nil {
+			return nil, ErrOMDBNotFound
+		}
+		return cached, nil
+	}
+
+	params := url.Values{}
+	params.Set("i", imdbID)
+	params.Set("type", "series")
+	params.Set("plot", "full")
+
+	resp, err := s.makeRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.OMDBSeries
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if result.Response == "False" {
+		err := s.failure(result.Error)
+		if errors.Is(err, ErrOMDBNotFound) {
+			s.series.Set(imdbID, nil)
+		}
+		return nil, err
+	}
+
+	s.series.Set(imdbID, &result)
+	return &result, nil
+}
+
+// Get returns the OMDB record of a movie, or for mediaType "tv" of a series
+func (s *OMDBService) Get(mediaType, imdbID string) (*models.OMDBMovie, error) {
+	if mediaType != "tv" {
+		return s.GetMovieByIMDBID(imdbID)
+	}
+	series, err := s.GetSeriesByIMDBID(imdbID)
+	if err != nil {
+		return nil, err
+	}
+	return &series.OMDBMovie, nil
+}
+
+// GetSeason lists a season's episodes with their IMDb IDs and ratings.
+// imdbID is the series' ID.
+func (s *OMDBService) GetSeason(imdbID string, season int) (*models.OMDBSeason, error) {
+	key := fmt.Sprintf("%s:%d", imdbID, season)
+	if cached, ok := s.seasons.Get(key); ok {
+		if cached == nil {
+			return nil, ErrOMDBNotFound
+		}
+		return cached, nil
+	}
+
+	params := url.Values{}
+	params.Set("i", imdbID)
+	params.Set("Season", strconv.Itoa(season))
+
+	resp, err := s.makeRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.OMDBSeason
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if result.Response == "False" {
+		err := s.failure(result.Error)
+		if errors.Is(err, ErrOMDBNotFound) {
+			s.seasons.Set(key, nil)
+		}
+		return nil, err
+	}
+
+	s.seasons.Set(key, &result)
+	return &result, nil
+}
+
+// GetEpisode returns the full record of one episode of a series
+func (s *OMDBService) GetEpisode(imdbID string, season, episode int) (*models.OMDBEpisode, error) {
+	key := fmt.Sprintf("%s:%d:%d", imdbID, season, episode)
+	if cached, ok := s.episodes.Get(key); ok {
+		if cached == nil {
+			return nil, ErrOMDBNotFound
+		}
+		return cached, nil
+	}
+
+	params := url.Values{}
+	params.Set("i", imdbID)
+	params.Set("Season", strconv.Itoa(season))
+	params.Set("Episode", strconv.Itoa(episode))
+	params.Set("plot", "full")
+
+	resp, err := s.makeRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.OMDBEpisode
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if result.Response == "False" {
+		err := s.failure(result.Error)
+		if errors.Is(err, ErrOMDBNotFound) {
+			s.episodes.Set(key, nil)
+		}
+		return nil, err
+	}
+
+	s.episodes.Set(key, &result)
+	return &result, nil
+}
+
 func (s *OMDBService) GetRatings(imdbID string) (*struct {
 	IMDBRating string          `json:"imdbRating"`
 	IMDBVotes  string          `json:"imdbVotes"`
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeOMDB answers OMDB requests from canned bodies, counts them, and passes
+// other requests on to next. Bodies are keyed by IMDb ID, or search title,
+// followed by ":season" and ":episode" when the request names them.
+type fakeOMDB struct {
+	mu     sync.Mutex
+	bodies map[string]string
+	calls  map[string]int
+	kinds  map[string]string // the last "type" asked for, by key
+	next   http.RoundTripper
+}
+
+func (f *fakeOMDB) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !strings.Contains(OMDBBaseURL, r.URL.Host) {
+		return f.next.RoundTrip(r)
+	}
+	q := r.URL.Query()
+	id := q.Get("i")
+	if id == "" {
+		id = q.Get("s")
+	}
+	for _, param := range []string{"Season", "Episode"} {
+		if v := q.Get(param); v != "" {
+			id += ":" + v
+		}
+	}
+	f.mu.Lock()
+	f.calls[id]++
+	f.kinds[id] = q.Get("type")
+	body, ok := f.bodies[id]
+	f.mu.Unlock()
+	if !ok {
+		body = `{"Response":"False","Error":"Incorrect IMDb ID."}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+func (f *fakeOMDB) count(id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[id]
+}
+
+func (f *fakeOMDB) kind(id string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.kinds[id]
+}
+
+// newFakeOMDB routes OMDB requests on the default transport to a fakeOMDB
+// for the rest of the test; call it after newFakeTMDB to use both
+func newFakeOMDB(t *testing.T, bodies map[string]string) *fakeOMDB {
+	t.Helper()
+	fake := &fakeOMDB{bodies: bodies, calls: make(map[string]int), kinds: make(map[string]string), next: http.DefaultTransport}
+	http.DefaultTransport = fake
+	t.Cleanup(func() { http.DefaultTransport = fake.next })
+	return fake
+}
+
+func TestOMDBCachesOnlyMissingRecords(t *testing.T) {
+	fake := newFakeOMDB(t, map[string]string{
+		"tt0133093": `{"Title":"The Matrix","imdbID":"tt0133093","Response":"True"}`,
+		"tt0000001": `{"Response":"False","Error":"Movie not found!"}`,
+		"tt0000002": `{"Response":"False","Error":"Error getting data."}`,
+	})
+	s := NewOMDBService("test")
+
+	tests := []struct {
+		id       string
+		notFound bool
+		requests int // after two lookups
+	}{
+		{"tt0133093", false, 1},
+		{"tt0000001", true, 1},
+		{"tt9999999", true, 1}, // "Incorrect IMDb ID."
+		{"tt0000002", false, 2},
+	}
+	for _, tt := range tests {
+		for range 2 {
+			_, err := s.GetMovieByIMDBID(tt.id)
+			if errors.Is(err, ErrOMDBNotFound) != tt.notFound {
+				t.Errorf("GetMovieByIMDBID(%s) error = %v, want not found %v", tt.id, err, tt.notFound)
+			}
+		}
+		if n := fake.count(tt.id); n != tt.requests {
+			t.Errorf("%s: %d requests, want %d", tt.id, n, tt.requests)
+		}
+	}
+
+	// A failure is an error, but not one that means the title doesn't exist
+	if _, err := s.GetSeriesByIMDBID("tt0000002"); err == nil || IsNotFound(err) || !strings.Contains(err.Error(), "Error getting data.") {
+		t.Errorf("GetSeriesByIMDBID error = %v, want the OMDB message", err)
+	}
+}
+
+func TestOMDBQuotaErrorPausesRequests(t *testing.T) {
+	fake := newFakeOMDB(t, map[string]string{
+		"tt0000001": `{"Response":"False","Error":"Request limit reached!"}`,
+	})
+	s := NewOMDBService("test")
+
+	if _, err := s.GetMovieByIMDBID("tt0000001"); !errors.Is(err, ErrOMDBQuotaExceeded) {
+		t.Fatalf("error = %v, want ErrOMDBQuotaExceeded", err)
+	}
+	if _, err := s.GetMovieByIMDBID("tt0133093"); !errors.Is(err, ErrOMDBQuotaExceeded) {
+		t.Errorf("second lookup error = %v, want ErrOMDBQuotaExceeded", err)
+	}
+	if n := fake.count("tt0133093"); n != 0 {
+		t.Errorf("%d requests sent over quota, want 0", n)
+	}
+}
+
+func TestRatingGetUsesDetailsIMDBID(t *testing.T) {
+	tmdb := newFakeTMDB(t, map[string]string{
+		"/movie/603": `{"id":603,"title":"The Matrix","imdb_id":"tt0133093","vote_average":8.2,"vote_count":25000}`,
+	})
+	newFakeOMDB(t, map[string]string{
+		"tt0133093": `{"imdbID":"tt0133093","Ratings":[{"Source":"Internet Movie Database","Value":"8.7/10"}],"Response":"True"}`,
+	})
+	s := NewRatingService(NewTMDBService("test", "US"), NewOMDBService("test"))
+
+	rating, err := s.Get("movie", 603)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rating.Sources) != 2 {
+		t.Errorf("sources = %+v, want TMDB and IMDb", rating.Sources)
+	}
+	if n := tmdb.count("/movie/603/external_ids"); n != 0 {
+		t.Errorf("external IDs fetched %d times, want 0", n)
+	}
+}
+
+func TestRatingGetLooksUpSeries(t *testing.T) {
+	newFakeTMDB(t, map[string]string{
+		"/tv/1399": `{"id":1399,"name":"Game of Thrones","vote_average":8.4,"vote_count":20000,"external_ids":{"imdb_id":"tt0944947"}}`,
+	})
+	omdb := newFakeOMDB(t, map[string]string{
+		"tt0944947": `{"imdbID":"tt0944947","Type":"series","totalSeasons":"8","Ratings":[{"Source":"Internet Movie Database","Value":"9.2/10"}],"Response":"True"}`,
+	})
+	s := NewRatingService(NewTMDBService("test", "US"), NewOMDBService("test"))
+
+	rating, err := s.Get("tv", 1399)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rating.Sources) != 2 {
+		t.Errorf("sources = %+v, want TMDB and IMDb", rating.Sources)
+	}
+	if kind := omdb.kind("tt0944947"); kind != "series" {
+		t.Errorf("OMDB lookup type = %q, want series", kind)
+	}
+}
+
+func TestOMDBSearchKinds(t *testing.T) {
+	omdb := newFakeOMDB(t, map[string]string{
+		"matrix": `{"Search":[{"Title":"The Matrix","imdbID":"tt0133093","Type":"movie"}],"totalResults":"1","Response":"True"}`,
+		"office": `{"Search":[{"Title":"The Office","imdbID":"tt0386676","Type":"series"}],"totalResults":"1","Response":"True"}`,
+	})
+	s := NewOMDBService("test")
+
+	tests := []struct {
+		title  string
+		search func(string, int) (*OMDBSearchResults, error)
+		kind   string
+		id     string
+	}{
+		{"matrix", s.SearchMovies, "movie", "tt0133093"},
+		{"office", s.SearchSeries, "series", "tt0386676"},
+	}
+	for _, tt := range tests {
+		results, err := tt.search(tt.title, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.title, err)
+		}
+		if len(results.Search) != 1 || results.Search[0].IMDBID != tt.id {
+			t.Errorf("%s: results = %+v, want %s", tt.title, results.Search, tt.id)
+		}
+		if kind := omdb.kind(tt.title); kind != tt.kind {
+			t.Errorf("%s: search type = %q, want %q", tt.title, kind, tt.kind)
+		}
+	}
+
+	if _, err := s.SearchSeries("nothing", 1); err == nil {
+		t.Error("SearchSeries without results: no error")
+	}
+}
+
+func TestOMDBGetSeasonAndEpisode(t *testing.T) {
+	omdb := newFakeOMDB(t, map[string]string{
+		"tt0944947:1":   `{"Title":"Game of Thrones","Season":"1","totalSeasons":"8","Episodes":[{"Title":"Winter Is Coming","Episode":"1","imdbRating":"8.9","imdbID":"tt1480055"}],"Response":"True"}`,
+		"tt0944947:1:1": `{"Title":"Winter Is Coming","Season":"1","Episode":"1","seriesID":"tt0944947","imdbID":"tt1480055","imdbRating":"8.9","Type":"episode","Response":"True"}`,
+		"tt0944947:9:1": `{"Response":"False","Error":"Series or episode not found!"}`,
+	})
+	s := NewOMDBService("test")
+
+	season, err := s.GetSeason("tt0944947", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if season.TotalSeasons != 8 || len(season.Episodes) != 1 || season.Episodes[0].IMDBID != "tt1480055" {
+		t.Errorf("season = %+v", season)
+	}
+
+	for range 2 {
+		episode, err := s.GetEpisode("tt0944947", 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if episode.Season != 1 || episode.Episode != 1 || episode.SeriesID != "tt0944947" || episode.IMDBRating != "8.9" {
+			t.Errorf("episode = %+v", episode)
+		}
+	}
+	if n := omdb.count("tt0944947:1:1"); n != 1 {
+		t.Errorf("episode fetched %d times, want 1", n)
+	}
+
+	for range 2 {
+		if _, err := s.GetEpisode("tt0944947", 9, 1); !errors.Is(err, ErrOMDBNotFound) {
+			t.Errorf("missing episode error = %v, want ErrOMDBNotFound", err)
+		}
+	}
+	if n := omdb.count("tt0944947:9:1"); n != 1 {
+		t.Errorf("missing episode fetched %d times, want 1", n)
+	}
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces calls to at most one per interval on average, letting
+// up to burst calls through back to back after a quiet period
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	next     time.Time // when the next call may start, ignoring burst credit
+}
+
+func newRateLimiter(perSecond, burst int) *rateLimiter {
+	return &rateLimiter{interval: time.Second / time.Duration(perSecond), burst: burst}
+}
+
+// Wait blocks until a call is allowed
+func (l *rateLimiter) Wait() {
+	l.mu.Lock()
+	now := time.Now()
+	// Idle time earns up to burst calls of credit
+	if earliest := now.Add(-time.Duration(l.burst-1) * l.interval); l.next.Before(earliest) {
+		l.next = earliest
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+}
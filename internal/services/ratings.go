@@ -0,0 +1,372 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+const (
+	ratingCacheTTL = 12 * time.Hour
+	ratingWorkers  = 8
+
+	// tmdbConfidentVotes is the vote count at which TMDB gets its full weight;
+	// below it the weight shrinks so a 10/10 from three voters can't dominate
+	tmdbConfidentVotes = 500
+)
+
+// ratingWeights sets how much each source counts towards the aggregate score
+var ratingWeights = map[string]float64{
+	"TMDB":            1,
+	"IMDb":            1.5,
+	"Rotten Tomatoes": 1,
+	"Metacritic":      1,
+}
+
+var sourceShortNames = map[string]string{
+	"IMDb":            "IMDb",
+	"Rotten Tomatoes": "RT",
+	"Metacritic":      "MC",
+}
+
+// RatingService combines TMDB and OMDB ratings into a single 0-100 score.
+// Full ratings are cached per title; lists fall back to the TMDB score alone
+// for titles that haven't been looked up yet.
+type RatingService struct {
+	tmdbService *TMDBService
+	omdbService *OMDBService
+	cache       *ttlCache[*models.AggregateRating]
+	prefetching atomic.Bool
+}
+
+func NewRatingService(tmdbService *TMDBService, omdbService *OMDBService) *RatingService {
+	return &RatingService{
+		tmdbService: tmdbService,
+		omdbService: omdbService,
+		cache:       newTTLCache[*models.AggregateRating](ratingCacheTTL),
+	}
+}
+
+// NormalizeRating converts "7.8/10", "87%" or "74/100" to a 0-100 score
+func NormalizeRating(raw string) (float64, bool) {
+	raw = strings.TrimSpace(raw)
+
+	if pct, ok := strings.CutSuffix(raw, "%"); ok {
+		v, err := strconv.ParseFloat(pct, 64)
+		if err != nil || v < 0 || v > 100 {
+			return 0, false
+		}
+		return v, true
+	}
+
+	num, den, ok := strings.Cut(raw, "/")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, false
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d <= 0 || n < 0 || n > d {
+		return 0, false
+	}
+	return n / d * 100, true
+}
+
+// Aggregate combines a TMDB vote average (0-10) with the ratings in an OMDB
+// record, which may be nil. It returns nil when no source has a rating.
+func (s *RatingService) Aggregate(voteAverage float64, voteCount int, omdb *models.OMDBMovie) *models.AggregateRating {
+	var sources []models.SourceScore
+
+	if voteCount > 0 {
+		confidence := math.Min(float64(voteCount)/tmdbConfidentVotes, 1)
+		sources = append(sources, models.SourceScore{
+			Source: "TMDB",
+			Score:  voteAverage * 10,
+			Raw:    fmt.Sprintf("%.1f/10", voteAverage),
+			Weight: ratingWeights["TMDB"] * confidence,
+		})
+	}
+
+	if omdb != nil {
+		imdb := s.omdbService.GetIMDBRating(omdb.Ratings)
+		if imdb == nil && omdb.IMDBRating != "" && omdb.IMDBRating != "N/A" {
+			raw := omdb.IMDBRating + "/10"
+			imdb = &raw
+		}
+		for source, raw := range map[string]*string{
+			"IMDb":            imdb,
+			"Rotten Tomatoes": s.omdbService.GetRottenTomatoesRating(omdb.Ratings),
+			"Metacritic":      s.omdbService.GetMetacriticRating(omdb.Ratings),
+		} {
+			if raw == nil {
+				continue
+			}
+			if score, ok := NormalizeRating(*raw); ok {
+				sources = append(sources, models.SourceScore{
+					Source: source,
+					Short:  sourceShortNames[source],
+					Score:  math.Round(score*10) / 10,
+					Raw:    *raw,
+					Weight: ratingWeights[source],
+				})
+			}
+		}
+	}
+
+	var sum, weights float64
+	for _, src := range sources {
+		sum += src.Score * src.Weight
+		weights += src.Weight
+	}
+	if weights == 0 {
+		return nil
+	}
+
+	// Fixed order so the breakdown doesn't shuffle between page loads
+	order := map[string]int{"TMDB": 0, "IMDb": 1, "Rotten Tomatoes": 2, "Metacritic": 3}
+	sort.Slice(sources, func(i, j int) bool { return order[sources[i].Source] < order[sources[j].Source] })
+
+	return &models.AggregateRating{
+		Score:   math.Round(sum/weights*10) / 10,
+		Sources: sources,
+	}
+}
+
+// Record caches a rating computed elsewhere, e.g. on a details page that
+// already fetched TMDB and OMDB data
+func (s *RatingService) Record(mediaType string, id int, rating *models.AggregateRating) {
+	if rating != nil {
+		s.cache.Set(ratingKey(mediaType, id), rating)
+	}
+}
+
+// Get returns the full aggregate rating for a title, fetching TMDB details
+// and the OMDB record when it isn't cached. If OMDB fails the TMDB-only
+// rating is returned but not cached, so it's retried next time.
+func (s *RatingService) Get(mediaType string, id int) (*models.AggregateRating, error) {
+	if rating, ok := s.cache.Get(ratingKey(mediaType, id)); ok {
+		return rating, nil
+	}
+
+	// Details carry the IMDb ID, so external IDs needn't be fetched
+	if mediaType == "tv" {
+		details, err := s.tmdbService.GetTVShowDetails(id)
+		if err != nil {
+			return nil, err
+		}
+		return s.rate(mediaType, id, details.ExternalIDs.IMDBID, details.VoteAverage, details.VoteCount)
+	}
+	details, err := s.tmdbService.GetMovieDetails(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.rate(mediaType, id, details.IMDBId, details.VoteAverage, details.VoteCount)
+}
+
+// lookup resolves the IMDb ID through TMDB external IDs, for titles known
+// only from a list, and rates them
+func (s *RatingService) lookup(mediaType string, id int, voteAverage float64, voteCount int) (*models.AggregateRating, error) {
+	ids, err := s.tmdbService.GetExternalIDs(mediaType, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.rate(mediaType, id, ids.IMDBID, voteAverage, voteCount)
+}
+
+// rate combines the OMDB record for imdbID with the given TMDB votes,
+// caching the result
+func (s *RatingService) rate(mediaType string, id int, imdbID string, voteAverage float64, voteCount int) (*models.AggregateRating, error) {
+	key := ratingKey(mediaType, id)
+
+	var omdb *models.OMDBMovie
+	var err error
+	if imdbID != "" {
+		omdb, err = s.omdbService.Get(mediaType, imdbID)
+		if err != nil && !errors.Is(err, ErrOMDBNotFound) {
+			if !errors.Is(err, ErrOMDBQuotaExceeded) {
+				log.Printf("Error fetching OMDB ratings for %s: %v", key, err)
+			}
+			return s.Aggregate(voteAverage, voteCount, nil), nil
+		}
+	}
+
+	rating := s.Aggregate(voteAverage, voteCount, omdb)
+	s.Record(mediaType, id, rating)
+	return rating, nil
+}
+
+// AnnotateMovies sets AggregateScore on each movie, and SourceRatings when
+// the full rating is known. With fetch, uncached ratings are looked up as a
+// batch; without it, or while the OMDB quota is used up, they fall back to
+// the TMDB score alone.
+func (s *RatingService) AnnotateMovies(movies []models.Movie, fetch bool) {
+	targets := make([]ratingTarget, len(movies))
+	for i := range movies {
+		m := &movies[i]
+		targets[i] = ratingTarget{"movie", m.ID, m.VoteAverage, m.VoteCount, &m.AggregateScore, &m.SourceRatings, &m.ScorePartial}
+	}
+	s.annotate(targets, fetch)
+}
+
+// AnnotateTVShows is AnnotateMovies for TV shows
+func (s *RatingService) AnnotateTVShows(shows []models.TVShow, fetch bool) {
+	targets := make([]ratingTarget, len(shows))
+	for i := range shows {
+		t := &shows[i]
+		targets[i] = ratingTarget{"tv", t.ID, t.VoteAverage, t.VoteCount, &t.AggregateScore, &t.SourceRatings, &t.ScorePartial}
+	}
+	s.annotate(targets, fetch)
+}
+
+// AnnotateWatchlist is AnnotateMovies for watchlist items. Items don't carry
+// a vote count, so their TMDB score gets full weight.
+func (s *RatingService) AnnotateWatchlist(items []models.WatchlistItem, fetch bool) {
+	targets := make([]ratingTarget, len(items))
+	for i := range items {
+		it := &items[i]
+		targets[i] = ratingTarget{it.Type, it.ID, it.VoteAverage, tmdbConfidentVotes, &it.AggregateScore, &it.SourceRatings, &it.ScorePartial}
+	}
+	s.annotate(targets, fetch)
+}
+
+// Prefetch looks up the full ratings of annotated titles that only have a
+// partial score, in the background, so sorts by score can use the cache
+// instead of waiting on TMDB and OMDB. One batch runs at a time; titles
+// asked for while one runs are picked up on a later request.
+func (s *RatingService) Prefetch(movies []models.Movie, shows []models.TVShow, items []models.WatchlistItem) {
+	var targets []ratingTarget
+	for _, m := range movies {
+		if m.ScorePartial {
+			targets = append(targets, ratingTarget{mediaType: "movie", id: m.ID, voteAverage: m.VoteAverage, voteCount: m.VoteCount})
+		}
+	}
+	for _, t := range shows {
+		if t.ScorePartial {
+			targets = append(targets, ratingTarget{mediaType: "tv", id: t.ID, voteAverage: t.VoteAverage, voteCount: t.VoteCount})
+		}
+	}
+	for _, it := range items {
+		if it.ScorePartial {
+			targets = append(targets, ratingTarget{mediaType: it.Type, id: it.ID, voteAverage: it.VoteAverage, voteCount: tmdbConfidentVotes})
+		}
+	}
+	if len(targets) == 0 || !s.prefetching.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer s.prefetching.Store(false)
+		for _, t := range targets {
+			if s.omdbService.QuotaExhausted() {
+				return
+			}
+			key := ratingKey(t.mediaType, t.id)
+			if _, ok := s.cache.Get(key); ok {
+				continue
+			}
+			if _, err := s.lookup(t.mediaType, t.id, t.voteAverage, t.voteCount); err != nil {
+				log.Printf("Error prefetching rating for %s: %v", key, err)
+			}
+		}
+	}()
+}
+
+type ratingTarget struct {
+	mediaType   string
+	id          int
+	voteAverage float64
+	voteCount   int
+	score       **float64
+	sources     *[]models.SourceScore
+	partial     *bool
+}
+
+// set fills in a full rating; TMDB is left out of the sources because
+// cards already show its score
+func (t ratingTarget) set(rating *models.AggregateRating) {
+	*t.score = &rating.Score
+	*t.partial = false
+	*t.sources = nil
+	for _, src := range rating.Sources {
+		if src.Source != "TMDB" {
+			*t.sources = append(*t.sources, src)
+		}
+	}
+}
+
+func (s *RatingService) annotate(targets []ratingTarget, fetch bool) {
+	// Lists can repeat a title (e.g. across search groups); look each up once
+	missing := make(map[string][]int)
+	var order []string
+	for i, t := range targets {
+		key := ratingKey(t.mediaType, t.id)
+		if rating, ok := s.cache.Get(key); ok {
+			t.set(rating)
+			continue
+		}
+		if _, seen := missing[key]; !seen {
+			order = append(order, key)
+		}
+		missing[key] = append(missing[key], i)
+	}
+
+	if fetch && len(order) > 0 && !s.omdbService.QuotaExhausted() {
+		jobs := make(chan string)
+		var wg sync.WaitGroup
+		for range min(ratingWorkers, len(order)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for key := range jobs {
+					t := targets[missing[key][0]]
+					if s.omdbService.QuotaExhausted() {
+						continue
+					}
+					rating, err := s.lookup(t.mediaType, t.id, t.voteAverage, t.voteCount)
+					if err != nil {
+						log.Printf("Error fetching rating for %s: %v", key, err)
+						continue
+					}
+					if _, ok := s.cache.Get(key); ok && rating != nil {
+						for _, i := range missing[key] {
+							targets[i].set(rating)
+						}
+					}
+				}
+			}()
+		}
+		for _, key := range order {
+			jobs <- key
+		}
+		close(jobs)
+		wg.Wait()
+	}
+
+	for _, key := range order {
+		for _, i := range missing[key] {
+			t := targets[i]
+			if *t.score != nil {
+				continue
+			}
+			if rating := s.Aggregate(t.voteAverage, t.voteCount, nil); rating != nil {
+				*t.score = &rating.Score
+				*t.partial = true
+			}
+		}
+	}
+}
+
+func ratingKey(mediaType string, id int) string {
+	return fmt.Sprintf("%s:%d", mediaType, id)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+
+	"muvi-discovery-app/internal/models"
+)
+
+func TestNormalizeRating(t *testing.T) {
+	tests := []struct {
+		raw   string
+		score float64
+		ok    bool
+	}{
+		{"7.5/10", 75, true},
+		{" 8/10 ", 80, true},
+		{"85%", 85, true},
+		{"68/100", 68, true},
+		{"0/10", 0, true},
+		{"N/A", 0, false},
+		{"", 0, false},
+		{"120%", 0, false},
+		{"11/10", 0, false},
+		{"7/0", 0, false},
+		{"-1/10", 0, false},
+		{"abc/10", 0, false},
+	}
+	for _, tt := range tests {
+		score, ok := NormalizeRating(tt.raw)
+		if score != tt.score || ok != tt.ok {
+			t.Errorf("NormalizeRating(%q) = %v, %v, want %v, %v", tt.raw, score, ok, tt.score, tt.ok)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	omdb := func(imdbRating string, ratings ...models.Rating) *models.OMDBMovie {
+		return &models.OMDBMovie{IMDBRating: imdbRating, Ratings: ratings}
+	}
+	imdb := func(v string) models.Rating { return models.Rating{Source: "Internet Movie Database", Value: v} }
+	rt := func(v string) models.Rating { return models.Rating{Source: "Rotten Tomatoes", Value: v} }
+	mc := func(v string) models.Rating { return models.Rating{Source: "Metacritic", Value: v} }
+
+	tests := []struct {
+		name        string
+		voteAverage float64
+		voteCount   int
+		omdb        *models.OMDBMovie
+		score       float64
+		weights     map[string]float64 // nil when no rating is expected
+	}{
+		{
+			name:        "TMDB only",
+			voteAverage: 8, voteCount: 1000,
+			score:   80,
+			weights: map[string]float64{"TMDB": 1},
+		},
+		{
+			name:        "zero votes and no OMDB record",
+			voteAverage: 9, voteCount: 0,
+		},
+		{
+			name:        "zero votes leaves TMDB out",
+			voteAverage: 9, voteCount: 0,
+			omdb:    omdb("", imdb("7.5/10")),
+			score:   75,
+			weights: map[string]float64{"IMDb": 1.5},
+		},
+		{
+			name:        "TMDB weight scales with votes up to 500",
+			voteAverage: 6, voteCount: 250,
+			omdb:    omdb("", imdb("8.0/10")),
+			score:   75, // (60*0.5 + 80*1.5) / 2
+			weights: map[string]float64{"TMDB": 0.5, "IMDb": 1.5},
+		},
+		{
+			name:        "every source",
+			voteAverage: 7, voteCount: 500,
+			omdb:    omdb("9.0", imdb("9.0/10"), rt("85%"), mc("68/100")),
+			score:   79.6, // (70 + 90*1.5 + 85 + 68) / 4.5
+			weights: map[string]float64{"TMDB": 1, "IMDb": 1.5, "Rotten Tomatoes": 1, "Metacritic": 1},
+		},
+		{
+			name:        "IMDb falls back to the imdbRating field",
+			voteAverage: 0, voteCount: 0,
+			omdb:    omdb("7.5", rt("N/A")),
+			score:   75,
+			weights: map[string]float64{"IMDb": 1.5},
+		},
+		{
+			name:        "N/A everywhere",
+			voteAverage: 0, voteCount: 0,
+			omdb: omdb("N/A", imdb("N/A"), mc("N/A")),
+		},
+	}
+
+	s := NewRatingService(nil, NewOMDBService("test"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rating := s.Aggregate(tt.voteAverage, tt.voteCount, tt.omdb)
+			if tt.weights == nil {
+				if rating != nil {
+					t.Fatalf("rating = %+v, want nil", rating)
+				}
+				return
+			}
+			if rating == nil {
+				t.Fatal("rating = nil")
+			}
+			if rating.Score != tt.score {
+				t.Errorf("score = %v, want %v", rating.Score, tt.score)
+			}
+			if len(rating.Sources) != len(tt.weights) {
+				t.Fatalf("sources = %+v, want %v", rating.Sources, tt.weights)
+			}
+			for _, src := range rating.Sources {
+				if want, ok := tt.weights[src.Source]; !ok || src.Weight != want {
+					t.Errorf("%s weight = %v, want %v", src.Source, src.Weight, want)
+				}
+			}
+		})
+	}
+}
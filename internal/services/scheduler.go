@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Scheduler runs background jobs at fixed intervals until it is stopped.
+// Each job runs on its own goroutine, so a slow job never delays another,
+// and never overlaps with itself.
+type Scheduler struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{ctx: ctx, cancel: cancel}
+}
+
+// Every runs job after delay and then every interval. The job's context is
+// cancelled when the scheduler stops, and long jobs should return when it is.
+func (s *Scheduler) Every(name string, delay, interval time.Duration, job func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-timer.C:
+			}
+			s.run(name, job)
+			timer.Reset(interval)
+		}
+	}()
+}
+
+// run calls job, logging rather than crashing on a panic
+func (s *Scheduler) run(name string, job func(ctx context.Context)) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("Scheduled job %s panicked: %v\n%s", name, err, debug.Stack())
+		}
+	}()
+	job(s.ctx)
+}
+
+// Stop cancels running jobs and waits for them to return, or until ctx is done
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
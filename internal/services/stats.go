@@ -0,0 +1,316 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+const (
+	// statsWorkers bounds concurrent TMDB lookups while computing stats
+	statsWorkers = 4
+	// statsTopN is the number of entries kept in each "top" list
+	statsTopN = 10
+	// statsBilledCast limits actor counting to the leading cast of each title
+	statsBilledCast = 5
+)
+
+// StatsService computes viewing statistics from the watchlist and TMDB metadata
+type StatsService struct {
+	tmdbService      *TMDBService
+	watchlistService *WatchlistService
+}
+
+func NewStatsService(tmdbService *TMDBService, watchlistService *WatchlistService) *StatsService {
+	return &StatsService{
+		tmdbService:      tmdbService,
+		watchlistService: watchlistService,
+	}
+}
+
+// titleFacts holds the metadata about one watched title that feeds into the stats
+type titleFacts struct {
+	minutes   int
+	genres    []string
+	directors []string
+	actors    []string
+}
+
+func (s *StatsService) Compute() *models.WatchStats {
+	items := s.watchlistService.GetAllItems()
+	stats := &models.WatchStats{
+		TotalTitles: len(items),
+		GeneratedAt: time.Now(),
+	}
+
+	var watched []models.WatchlistItem
+	for _, item := range items {
+		if item.Watched {
+			watched = append(watched, item)
+		}
+	}
+	stats.WatchedTitles = len(watched)
+
+	facts := s.fetchFacts(watched)
+
+	perMonth := map[string]int{}
+	perYear := map[string]int{}
+	decades := map[string]int{}
+	genres := map[string]int{}
+	directors := map[string]int{}
+	actors := map[string]int{}
+
+	var personalSum, tmdbSum float64
+	for i, item := range watched {
+		if item.Type == "tv" {
+			stats.WatchedShows++
+		} else {
+			stats.WatchedMovies++
+		}
+
+		if item.WatchedAt != nil {
+			perMonth[item.WatchedAt.Format("2006-01")]++
+			perYear[item.WatchedAt.Format("2006")]++
+		}
+
+		if len(item.ReleaseDate) >= 4 {
+			var year int
+			if _, err := fmt.Sscanf(item.ReleaseDate[:4], "%d", &year); err == nil && year > 0 {
+				decades[fmt.Sprintf("%ds", year/10*10)]++
+			}
+		}
+
+		if item.Rating != nil {
+			stats.RatedTitles++
+			personalSum += *item.Rating
+			tmdbSum += item.VoteAverage
+		}
+
+		f := facts[i]
+		stats.MinutesWatched += f.minutes
+		for _, name := range f.genres {
+			genres[name]++
+		}
+		for _, name := range f.directors {
+			directors[name]++
+		}
+		for _, name := range f.actors {
+			actors[name]++
+		}
+	}
+
+	stats.HoursWatched = float64(stats.MinutesWatched) / 60
+	if stats.RatedTitles > 0 {
+		stats.AveragePersonalRating = personalSum / float64(stats.RatedTitles)
+		stats.AverageTMDBRating = tmdbSum / float64(stats.RatedTitles)
+	}
+
+	stats.PerMonth = sortedPeriods(perMonth)
+	stats.PerYear = sortedPeriods(perYear)
+	stats.Decades = sortedPeriods(decades)
+	stats.TopGenres = topNames(genres, statsTopN)
+	stats.TopDirectors = topNames(directors, statsTopN)
+	stats.TopActors = topNames(actors, statsTopN)
+
+	return stats
+}
+
+// fetchFacts looks up runtime, genres and people for each item, indexed like items.
+// Lookups that fail are logged and leave the corresponding entry empty.
+func (s *StatsService) fetchFacts(items []models.WatchlistItem) []titleFacts {
+	facts := make([]titleFacts, len(items))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range statsWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var err error
+				if items[i].Type == "tv" {
+					facts[i], err = s.tvFacts(items[i])
+				} else {
+					facts[i], err = s.movieFacts(items[i].ID)
+				}
+				if err != nil {
+					log.Printf("Error fetching stats metadata for %s %d: %v", items[i].Type, items[i].ID, err)
+				}
+			}
+		}()
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return facts
+}
+
+func (s *StatsService) movieFacts(id int) (titleFacts, error) {
+	var f titleFacts
+
+	details, err := s.tmdbService.GetMovieDetails(id)
+	if err != nil {
+		return f, err
+	}
+	f.minutes = details.Runtime
+	for _, g := range details.Genres {
+		f.genres = append(f.genres, g.Name)
+	}
+
+	credits, err := s.tmdbService.GetMovieCredits(id)
+	if err != nil {
+		return f, err
+	}
+	for _, c := range credits.Crew {
+		if c.Job == "Director" {
+			f.directors = append(f.directors, c.Name)
+		}
+	}
+	f.actors = billedCast(credits.Cast)
+
+	return f, nil
+}
+
+func (s *StatsService) tvFacts(item models.WatchlistItem) (titleFacts, error) {
+	var f titleFacts
+
+	details, err := s.tmdbService.GetTVShowDetails(item.ID)
+	if err != nil {
+		return f, err
+	}
+	fetchSeason := func(number int) (*models.SeasonDetails, error) {
+		return s.tmdbService.GetTVSeason(item.ID, number)
+	}
+	f.minutes = episodeRuntime(details) * watchedEpisodes(details, item.WatchedAt, fetchSeason)
+	for _, g := range details.Genres {
+		f.genres = append(f.genres, g.Name)
+	}
+	// Shows rarely credit a single director, so their creators stand in
+	for _, c := range details.CreatedBy {
+		f.directors = append(f.directors, c.Name)
+	}
+
+	credits, err := s.tmdbService.GetTVShowCredits(item.ID)
+	if err != nil {
+		return f, err
+	}
+	f.actors = billedCast(credits.Cast)
+
+	return f, nil
+}
+
+// episodeRuntime is a show's average episode length in minutes. TMDB often
+// leaves episode_run_time empty, so the latest episode's runtime stands in.
+func episodeRuntime(details *models.TVShowDetails) int {
+	if len(details.EpisodeRunTime) > 0 {
+		total := 0
+		for _, m := range details.EpisodeRunTime {
+			total += m
+		}
+		return total / len(details.EpisodeRunTime)
+	}
+	if ep := details.LastEpisodeToAir; ep != nil && ep.Runtime != nil {
+		return *ep.Runtime
+	}
+	return 0
+}
+
+// watchedEpisodes counts the episodes a show marked watched at watchedAt
+// had aired by then: every regular season up to the latest episode TMDB
+// lists as aired, leaving out specials, episodes still to come and seasons
+// that started after it was marked. A nil watchedAt counts up to now.
+// Seasons air in order, so only the latest one begun by then can have been
+// partway through; unless that's the season of the latest aired episode,
+// its episodes' air dates are looked up with fetchSeason.
+func watchedEpisodes(details *models.TVShowDetails, watchedAt *time.Time, fetchSeason func(number int) (*models.SeasonDetails, error)) int {
+	last := details.LastEpisodeToAir
+	if last == nil {
+		return 0
+	}
+	cutoff := time.Now()
+	if watchedAt != nil {
+		cutoff = *watchedAt
+	}
+	day := cutoff.Format("2006-01-02")
+
+	episodes := 0
+	var current models.Season
+	for _, season := range details.Seasons {
+		if season.SeasonNumber <= 0 || season.SeasonNumber > last.SeasonNumber ||
+			season.AirDate == "" || season.AirDate > day {
+			continue
+		}
+		episodes += season.EpisodeCount
+		if season.SeasonNumber > current.SeasonNumber {
+			current = season
+		}
+	}
+	if current.SeasonNumber == 0 {
+		return 0
+	}
+	episodes -= current.EpisodeCount
+
+	if current.SeasonNumber == last.SeasonNumber && last.AirDate <= day {
+		return episodes + last.EpisodeNumber
+	}
+	seasonDetails, err := fetchSeason(current.SeasonNumber)
+	if err != nil {
+		log.Printf("Error fetching season %d of TV show %d for stats: %v", current.SeasonNumber, details.ID, err)
+		if current.SeasonNumber == last.SeasonNumber {
+			return episodes + last.EpisodeNumber
+		}
+		return episodes + current.EpisodeCount
+	}
+	for _, ep := range seasonDetails.Episodes {
+		if ep.AirDate != "" && ep.AirDate <= day {
+			episodes++
+		}
+	}
+	return episodes
+}
+
+func billedCast(cast []models.CastMember) []string {
+	var names []string
+	for _, c := range cast {
+		if c.Order < statsBilledCast {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func sortedPeriods(counts map[string]int) []models.PeriodCount {
+	result := make([]models.PeriodCount, 0, len(counts))
+	for period, count := range counts {
+		result = append(result, models.PeriodCount{Period: period, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Period < result[j].Period
+	})
+	return result
+}
+
+func topNames(counts map[string]int, n int) []models.NameCount {
+	result := make([]models.NameCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, models.NameCount{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+func TestStatsCountsWatchedEpisodes(t *testing.T) {
+	future := time.Now().AddDate(0, 2, 0).Format("2006-01-02")
+	tmdb := newFakeTMDB(t, map[string]string{
+		"/tv/30": fmt.Sprintf(`{"id":30,"name":"Show","episode_run_time":[30,40],"number_of_episodes":31,
+			"last_episode_to_air":{"air_date":"2024-05-01","season_number":2,"episode_number":3},
+			"seasons":[
+				{"season_number":0,"episode_count":5,"air_date":"2019-12-01"},
+				{"season_number":1,"episode_count":10,"air_date":"2020-01-01"},
+				{"season_number":2,"episode_count":8,"air_date":"2024-04-17"},
+				{"season_number":3,"episode_count":8,"air_date":%q}]}`, future),
+		"/tv/30/credits": `{"cast":[]}`,
+	})
+	watchlist := NewWatchlistService(filepath.Join(t.TempDir(), "watchlist.json"))
+	if err := watchlist.AddItem(models.WatchlistItem{Type: "tv", ID: 30, Title: "Show"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := watchlist.ToggleWatched("tv", 30); err != nil {
+		t.Fatal(err)
+	}
+
+	// Season 1 and the three aired episodes of season 2, at 35 minutes each;
+	// not the specials or the episodes still to come
+	stats := NewStatsService(NewTMDBService("test", "US"), watchlist).Compute()
+	if stats.MinutesWatched != 13*35 {
+		t.Errorf("minutes watched = %d, want %d", stats.MinutesWatched, 13*35)
+	}
+
+	// The latest aired episode is in the season airing now, so no season is fetched
+	if n := tmdb.count("/tv/30/season/2"); n != 0 {
+		t.Errorf("season fetched %d times, want 0", n)
+	}
+}
+
+func TestWatchedEpisodesAtWatchedDate(t *testing.T) {
+	// Weekly episodes from start; the first count have air dates
+	weekly := func(start string, count, listed int) *models.SeasonDetails {
+		day, _ := time.Parse("2006-01-02", start)
+		season := &models.SeasonDetails{}
+		for i := range listed {
+			ep := models.Episode{EpisodeNumber: i + 1}
+			if i < count {
+				ep.AirDate = day.AddDate(0, 0, 7*i).Format("2006-01-02")
+			}
+			season.Episodes = append(season.Episodes, ep)
+		}
+		return season
+	}
+	seasons := map[int]*models.SeasonDetails{
+		1: weekly("2020-01-01", 10, 10),
+		2: weekly("2024-04-17", 3, 8), // the rest are still unannounced
+	}
+	details := &models.TVShowDetails{
+		LastEpisodeToAir: &models.Episode{SeasonNumber: 2, EpisodeNumber: 3, AirDate: "2024-05-01"},
+		Seasons: []models.Season{
+			{SeasonNumber: 0, EpisodeCount: 5, AirDate: "2019-12-01"},
+			{SeasonNumber: 1, EpisodeCount: 10, AirDate: "2020-01-01"},
+			{SeasonNumber: 2, EpisodeCount: 8, AirDate: "2024-04-17"},
+		},
+	}
+
+	tests := []struct {
+		watchedAt string
+		broken    bool // season lookups fail
+		episodes  int
+		fetched   []int
+	}{
+		{"2019-12-15", false, 0, nil},
+		{"2020-01-20", false, 3, []int{1}}, // mid season 1
+		{"2022-06-01", false, 10, []int{1}},
+		{"2024-04-25", false, 12, []int{2}}, // mid season 2, before its latest episode
+		{"2024-05-01", false, 13, nil},
+		{"2024-06-01", false, 13, nil},
+		{"2020-01-20", true, 10, []int{1}}, // falls back to the whole season
+		{"2024-04-25", true, 13, []int{2}}, // or to the latest aired episode
+	}
+	for _, tt := range tests {
+		var fetched []int
+		fetchSeason := func(number int) (*models.SeasonDetails, error) {
+			fetched = append(fetched, number)
+			if tt.broken {
+				return nil, &APIError{Service: "TMDB", StatusCode: 500}
+			}
+			return seasons[number], nil
+		}
+		watchedAt, _ := time.Parse("2006-01-02", tt.watchedAt)
+		if n := watchedEpisodes(details, &watchedAt, fetchSeason); n != tt.episodes {
+			t.Errorf("watched %s (broken %v): %d episodes, want %d", tt.watchedAt, tt.broken, n, tt.episodes)
+		}
+		if !slices.Equal(fetched, tt.fetched) {
+			t.Errorf("watched %s (broken %v): fetched seasons %v, want %v", tt.watchedAt, tt.broken, fetched, tt.fetched)
+		}
+	}
+}
+
+func TestTMDBDetailsCacheReturnsCopies(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/tv/30": `{"id":30,"name":"Show","genres":[{"id":1,"name":"Drama"}],"last_episode_to_air":{"name":"Pilot"}}`,
+	})
+	s := NewTMDBService("test", "US")
+
+	first, err := s.GetTVShowDetails(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Name = "changed"
+	first.Genres[0].Name = "changed"
+	first.LastEpisodeToAir.Name = "changed"
+
+	second, err := s.GetTVShowDetails(30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.count("/tv/30"); n != 1 {
+		t.Errorf("looked up %d times, want 1", n)
+	}
+	if second == first || second.Name != "Show" || second.Genres[0].Name != "Drama" || second.LastEpisodeToAir.Name != "Pilot" {
+		t.Errorf("cached details were changed by a caller: %+v", second)
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+const (
+	suggestCacheTTL     = 5 * time.Minute
+	suggestIndexSize    = 5000
+	suggestMinPrefixLen = 2
+)
+
+// SuggestService answers search-as-you-type requests. Titles seen in any
+// list or search result are kept in an in-process prefix index, and TMDB is
+// only asked when the index has no match at all.
+type SuggestService struct {
+	tmdbService *TMDBService
+	index       *prefixIndex
+	// searched records prefixes recently sent to TMDB, and whether TMDB
+	// returned every match, in which case longer prefixes needn't be sent
+	searched *ttlCache[bool]
+}
+
+func NewSuggestService(tmdbService *TMDBService) *SuggestService {
+	return &SuggestService{
+		tmdbService: tmdbService,
+		index:       newPrefixIndex(suggestIndexSize),
+		searched:    newTTLCache[bool](suggestCacheTTL),
+	}
+}
+
+// Suggest returns up to limit movies and TV shows with a title word starting with prefix
+func (s *SuggestService) Suggest(prefix string, limit int) ([]models.Suggestion, error) {
+	key := normalizeTitle(prefix)
+	if len([]rune(key)) < suggestMinPrefixLen {
+		return []models.Suggestion{}, nil
+	}
+
+	matches := s.index.Lookup(key)
+	if len(matches) > 0 || s.wasSearched(key) {
+		return truncateSuggestions(matches, limit), nil
+	}
+
+	resp, err := s.tmdbService.SearchMulti(key, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resp.Results {
+		switch r.MediaType {
+		case "movie":
+			s.RecordMovies([]models.Movie{r.Movie()})
+		case "tv":
+			s.RecordTVShows([]models.TVShow{r.TVShow()})
+		}
+	}
+	s.searched.Set(key, resp.TotalResults <= len(resp.Results))
+
+	return truncateSuggestions(s.index.Lookup(key), limit), nil
+}
+
+// wasSearched reports whether TMDB was recently asked for key, or returned
+// every match for a shorter prefix of it, so asking again would find nothing new
+func (s *SuggestService) wasSearched(key string) bool {
+	if _, ok := s.searched.Get(key); ok {
+		return true
+	}
+	runes := []rune(key)
+	for n := suggestMinPrefixLen; n < len(runes); n++ {
+		if complete, ok := s.searched.Get(string(runes[:n])); ok && complete {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordMovies adds movies from a list or search result to the prefix index
+func (s *SuggestService) RecordMovies(movies []models.Movie) {
+	for _, m := range movies {
+		s.index.Add(models.Suggestion{
+			ID:         m.ID,
+			MediaType:  "movie",
+			Title:      m.Title,
+			Year:       yearOf(m.ReleaseDate),
+			PosterURL:  s.posterURL(m.PosterPath),
+			Popularity: m.Popularity,
+		})
+	}
+}
+
+// RecordTVShows adds TV shows from a list or search result to the prefix index
+func (s *SuggestService) RecordTVShows(shows []models.TVShow) {
+	for _, t := range shows {
+		s.index.Add(models.Suggestion{
+			ID:         t.ID,
+			MediaType:  "tv",
+			Title:      t.Name,
+			Year:       yearOf(t.FirstAirDate),
+			PosterURL:  s.posterURL(t.PosterPath),
+			Popularity: t.Popularity,
+		})
+	}
+}
+
+func (s *SuggestService) posterURL(path *string) string {
+	if path == nil || *path == "" {
+		return ""
+	}
+	return s.tmdbService.BuildImageURL(path, "w92")
+}
+
+func truncateSuggestions(suggestions []models.Suggestion, limit int) []models.Suggestion {
+	if limit > 0 && len(suggestions) > limit {
+		return suggestions[:limit]
+	}
+	return suggestions
+}
+
+func yearOf(date string) string {
+	if len(date) >= 4 {
+		return date[:4]
+	}
+	return ""
+}
+
+// normalizeTitle lowercases and collapses whitespace so "  The  Matrix" matches "the matrix"
+func normalizeTitle(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// prefixIndex maps word prefixes of recently seen titles to suggestions. It
+// holds at most size titles, evicting the least recently seen.
+type prefixIndex struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element // keyed by "type:id"; values are *indexEntry
+	lru     *list.List               // most recently seen first
+	terms   []indexTerm              // sorted by term; rebuilt lazily
+	dirty   bool
+}
+
+type indexEntry struct {
+	key        string
+	suggestion models.Suggestion
+	title      string // normalized
+}
+
+type indexTerm struct {
+	term string
+	key  string
+}
+
+func newPrefixIndex(size int) *prefixIndex {
+	return &prefixIndex{size: size, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+func (p *prefixIndex) Add(s models.Suggestion) {
+	title := normalizeTitle(s.Title)
+	if title == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%d", s.MediaType, s.ID)
+	if el, ok := p.entries[key]; ok {
+		e := el.Value.(*indexEntry)
+		e.suggestion = s
+		p.lru.MoveToFront(el)
+		if e.title != title {
+			e.title = title
+			p.dirty = true
+		}
+		return
+	}
+
+	if len(p.entries) >= p.size {
+		oldest := p.lru.Back()
+		p.lru.Remove(oldest)
+		delete(p.entries, oldest.Value.(*indexEntry).key)
+	}
+	p.entries[key] = p.lru.PushFront(&indexEntry{key: key, suggestion: s, title: title})
+	p.dirty = true
+}
+
+// Lookup returns titles containing prefix at a word boundary ("matrix"
+// finds "The Matrix"), most popular first
+func (p *prefixIndex) Lookup(prefix string) []models.Suggestion {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dirty {
+		p.rebuild()
+	}
+
+	start := sort.Search(len(p.terms), func(i int) bool { return p.terms[i].term >= prefix })
+	seen := make(map[string]bool)
+	results := []models.Suggestion{}
+	for i := start; i < len(p.terms) && strings.HasPrefix(p.terms[i].term, prefix); i++ {
+		key := p.terms[i].key
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		results = append(results, p.entries[key].Value.(*indexEntry).suggestion)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Popularity > results[j].Popularity })
+	return results
+}
+
+// rebuild indexes every word-aligned suffix of each title, so a prefix
+// search over the sorted terms also matches from the middle of a title
+func (p *prefixIndex) rebuild() {
+	p.terms = p.terms[:0]
+	for key, el := range p.entries {
+		words := strings.Fields(el.Value.(*indexEntry).title)
+		for i := range words {
+			p.terms = append(p.terms, indexTerm{term: strings.Join(words[i:], " "), key: key})
+		}
+	}
+	sort.Slice(p.terms, func(i, j int) bool { return p.terms[i].term < p.terms[j].term })
+	p.dirty = false
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	"muvi-discovery-app/internal/models"
+)
+
+func TestSuggestAsksTMDBOnlyOnIndexMiss(t *testing.T) {
+	fake := newFakeTMDB(t, map[string]string{
+		"/search/multi": `{"page":1,"total_pages":1,"total_results":1,"results":[{"id":1,"media_type":"movie","title":"Zyzzyva","popularity":3}]}`,
+	})
+	s := NewSuggestService(NewTMDBService("test", "US"))
+	s.RecordMovies([]models.Movie{{ID: 603, Title: "The Matrix", Popularity: 80}})
+
+	suggest := func(prefix string, want int) {
+		t.Helper()
+		suggestions, err := s.Suggest(prefix, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(suggestions) != want {
+			t.Errorf("Suggest(%q) = %v, want %d suggestions", prefix, suggestions, want)
+		}
+	}
+
+	// One match is enough, however short of the limit
+	suggest("mat", 1)
+	if n := fake.count("/search/multi"); n != 0 {
+		t.Fatalf("TMDB asked %d times for an indexed title", n)
+	}
+
+	suggest("zy", 1)
+	suggest("zy", 1)
+	// TMDB returned everything for "zy", so longer prefixes needn't ask again
+	suggest("zyx", 0)
+	suggest("zyzz", 1)
+	if n := fake.count("/search/multi"); n != 1 {
+		t.Errorf("TMDB asked %d times, want 1", n)
+	}
+}
+
+func TestPrefixIndexEvictsLeastRecentlySeen(t *testing.T) {
+	p := newPrefixIndex(2)
+	add := func(id int, title string) {
+		p.Add(models.Suggestion{ID: id, MediaType: "movie", Title: title})
+	}
+
+	add(1, "Alien")
+	add(2, "Aliens")
+	add(1, "Alien") // seen again, so Aliens is now the oldest
+	add(3, "Alien 3")
+
+	var ids []int
+	for _, s := range p.Lookup("alien") {
+		ids = append(ids, s.ID)
+	}
+	if len(ids) != 2 || ids[0] == 2 || ids[1] == 2 {
+		t.Errorf("indexed %v, want 1 and 3", ids)
+	}
+	if p.lru.Len() != len(p.entries) {
+		t.Errorf("LRU list holds %d titles, index %d", p.lru.Len(), len(p.entries))
+	}
+}
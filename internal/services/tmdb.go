@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"muvi-discovery-app/internal/models"
@@ -14,20 +16,82 @@ import (
 const (
 	TMDBBaseURL      = "https://api.themoviedb.org/3"
 	TMDBImageBaseURL = "https://image.tmdb.org/t/p"
+
+	// detailsCacheTTL bounds how long details and credits are reused before refetching
+	detailsCacheTTL = time.Hour
+
+	// externalIDsCacheTTL is long because a title's IMDb ID practically never changes
+	externalIDsCacheTTL = 7 * 24 * time.Hour
+
+	// certificationsCacheTTL covers the rating systems, which change very rarely
+	certificationsCacheTTL = 24 * time.Hour
+
+	// TMDB allows roughly 50 requests per second; stay comfortably below that
+	tmdbRequestsPerSecond = 40
+	tmdbRequestBurst      = 20
 )
 
 type TMDBService struct {
 	apiKey     string
+	region     string // ISO 3166-1 country for release dates and certifications
+	content    *contentFilter
 	httpClient *http.Client
+	limiter    *rateLimiter
+
+	movieDetailsCache *ttlCache[*models.MovieDetails]
+	movieCreditsCache *ttlCache[*models.Credits]
+	tvDetailsCache    *ttlCache[*models.TVShowDetails]
+	tvCreditsCache    *ttlCache[*models.Credits]
+	collectionCache   *ttlCache[*models.Collection]
+	externalIDsCache  *ttlCache[*models.ExternalIDs]
+	releaseDatesCache *ttlCache[*models.ReleaseDatesResponse]
+	contentRatings    *ttlCache[*models.ContentRatingsResponse]
+	certifications    *ttlCache[map[string][]models.Certification]
+	imagesCache       *ttlCache[*models.ImagesResponse] // by endpoint and language
 }
 
-func NewTMDBService(apiKey string) *TMDBService {
-	return &TMDBService{
+// NewTMDBService creates a TMDB client; region is an ISO 3166-1 code such
+// as "US" and defaults to it when empty
+func NewTMDBService(apiKey, region string) *TMDBService {
+	if region == "" {
+		region = "US"
+	}
+	s := &TMDBService{
 		apiKey: apiKey,
+		region: strings.ToUpper(region),
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-	}
+		movieDetailsCache: newCopyingCache[*models.MovieDetails](detailsCacheTTL),
+		movieCreditsCache: newCopyingCache[*models.Credits](detailsCacheTTL),
+		tvDetailsCache:    newCopyingCache[*models.TVShowDetails](detailsCacheTTL),
+		tvCreditsCache:    newCopyingCache[*models.Credits](detailsCacheTTL),
+		collectionCache:   newCopyingCache[*models.Collection](detailsCacheTTL),
+		externalIDsCache:  newCopyingCache[*models.ExternalIDs](externalIDsCacheTTL),
+		releaseDatesCache: newCopyingCache[*models.ReleaseDatesResponse](detailsCacheTTL),
+		contentRatings:    newCopyingCache[*models.ContentRatingsResponse](detailsCacheTTL),
+		certifications:    newCopyingCache[map[string][]models.Certification](certificationsCacheTTL),
+		imagesCache:       newCopyingCache[*models.ImagesResponse](detailsCacheTTL),
+		limiter:           newRateLimiter(tmdbRequestsPerSecond, tmdbRequestBurst),
+	}
+	s.content = newContentFilter(s, models.ContentProfile{})
+	return s
+}
+
+// SetContentProfile sets the restrictions applied to every list, search and
+// details call. Call it before serving requests.
+func (s *TMDBService) SetContentProfile(profile models.ContentProfile) {
+	s.content = newContentFilter(s, profile)
+}
+
+// ContentProfile returns the restrictions in effect
+func (s *TMDBService) ContentProfile() models.ContentProfile {
+	return s.content.profile
+}
+
+// Region returns the country used for release dates and certifications
+func (s *TMDBService) Region() string {
+	return s.region
 }
 
 func (s *TMDBService) makeRequest(endpoint string, params url.Values) (*http.Response, error) {
@@ -35,9 +99,11 @@ func (s *TMDBService) makeRequest(endpoint string, params url.Values) (*http.Res
 		params = url.Values{}
 	}
 	params.Set("api_key", s.apiKey)
+	params.Set("include_adult", strconv.FormatBool(s.content.profile.AllowAdult))
 
 	reqURL := fmt.Sprintf("%s%s?%s", TMDBBaseURL, endpoint, params.Encode())
 
+	s.limiter.Wait()
 	resp, err := s.httpClient.Get(reqURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make request: %w", err)
@@ -45,7 +111,7 @@ func (s *TMDBService) makeRequest(endpoint string, params url.Values) (*http.Res
 
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
-		return nil, fmt.Errorf("API request failed with status: %d", resp.StatusCode)
+		return nil, &APIError{Service: "TMDB", StatusCode: resp.StatusCode}
 	}
 
 	return resp, nil
@@ -67,6 +133,7 @@ func (s *TMDBService) GetPopularMovies(page int) (*models.TMDBResponse[models.Mo
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.movies(result.Results)
 	return &result, nil
 }
 
@@ -85,12 +152,14 @@ func (s *TMDBService) GetTopRatedMovies(page int) (*models.TMDBResponse[models.M
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.movies(result.Results)
 	return &result, nil
 }
 
 func (s *TMDBService) GetNowPlayingMovies(page int) (*models.TMDBResponse[models.Movie], error) {
 	params := url.Values{}
 	params.Set("page", strconv.Itoa(page))
+	params.Set("region", s.region)
 
 	resp, err := s.makeRequest("/movie/now_playing", params)
 	if err != nil {
@@ -103,11 +172,40 @@ func (s *TMDBService) GetNowPlayingMovies(page int) (*models.TMDBResponse[models
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.movies(result.Results)
 	return &result, nil
 }
 
+// GetUpcomingMovies lists movies releasing in theaters soon
+func (s *TMDBService) GetUpcomingMovies(page int) (*models.TMDBResponse[models.Movie], error) {
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
+	params.Set("region", s.region)
+
+	resp, err := s.makeRequest("/movie/upcoming", params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.TMDBResponse[models.Movie]
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	result.Results = s.content.movies(result.Results)
+	return &result, nil
+}
+
+// GetMovieDetails returns ErrContentRestricted for a movie the content profile hides
 func (s *TMDBService) GetMovieDetails(movieID int) (*models.MovieDetails, error) {
 	endpoint := fmt.Sprintf("/movie/%d", movieID)
+	if cached, ok := s.movieDetailsCache.Get(endpoint); ok {
+		if err := s.content.checkMovie(cached); err != nil {
+			return nil, err
+		}
+		return cached, nil
+	}
 
 	resp, err := s.makeRequest(endpoint, nil)
 	if err != nil {
@@ -120,11 +218,85 @@ func (s *TMDBService) GetMovieDetails(movieID int) (*models.MovieDetails, error)
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	s.movieDetailsCache.Set(endpoint, &result)
+	if err := s.content.checkMovie(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetCollection returns a collection with its parts in release order;
+// unreleased parts without a date come last
+func (s *TMDBService) GetCollection(collectionID int) (*models.Collection, error) {
+	endpoint := fmt.Sprintf("/collection/%d", collectionID)
+	if cached, ok := s.collectionCache.Get(endpoint); ok {
+		return s.filterCollection(cached), nil
+	}
+
+	resp, err := s.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.Collection
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	sort.SliceStable(result.Parts, func(i, j int) bool {
+		a, b := result.Parts[i].ReleaseDate, result.Parts[j].ReleaseDate
+		if a == "" || b == "" {
+			return a != "" && b == ""
+		}
+		return a < b
+	})
+
+	s.collectionCache.Set(endpoint, &result)
+	return s.filterCollection(&result), nil
+}
+
+// filterCollection returns a copy of a collection without the parts the
+// content profile hides, leaving the cached collection whole
+func (s *TMDBService) filterCollection(c *models.Collection) *models.Collection {
+	filtered := *c
+	filtered.Parts = s.content.movies(append([]models.Movie(nil), c.Parts...))
+	return &filtered
+}
+
+// GetMovieReleaseDates returns a movie's releases and certifications in every country
+func (s *TMDBService) GetMovieReleaseDates(movieID int) (*models.ReleaseDatesResponse, error) {
+	endpoint := fmt.Sprintf("/movie/%d/release_dates", movieID)
+	if cached, ok := s.releaseDatesCache.Get(endpoint); ok {
+		return cached, nil
+	}
+
+	resp, err := s.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.ReleaseDatesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	for _, c := range result.Results {
+		sort.SliceStable(c.ReleaseDates, func(i, j int) bool {
+			return c.ReleaseDates[i].ReleaseDate.Before(c.ReleaseDates[j].ReleaseDate)
+		})
+	}
+
+	s.releaseDatesCache.Set(endpoint, &result)
 	return &result, nil
 }
 
 func (s *TMDBService) GetMovieCredits(movieID int) (*models.Credits, error) {
 	endpoint := fmt.Sprintf("/movie/%d/credits", movieID)
+	if cached, ok := s.movieCreditsCache.Get(endpoint); ok {
+		return cached, nil
+	}
 
 	resp, err := s.makeRequest(endpoint, nil)
 	if err != nil {
@@ -137,6 +309,7 @@ func (s *TMDBService) GetMovieCredits(movieID int) (*models.Credits, error) {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	s.movieCreditsCache.Set(endpoint, &result)
 	return &result, nil
 }
 
@@ -157,6 +330,46 @@ func (s *TMDBService) GetMovieVideos(movieID int) (*models.VideosResponse, error
 	return &result, nil
 }
 
+// GetMovieImages returns a movie's posters, backdrops and logos. With a
+// language such as "en", only images in that language or without text are
+// included; an empty language includes all of them.
+func (s *TMDBService) GetMovieImages(movieID int, language string) (*models.ImagesResponse, error) {
+	return s.getImages(fmt.Sprintf("/movie/%d/images", movieID), language)
+}
+
+// GetTVImages returns a TV show's posters, backdrops and logos, filtered by
+// language like GetMovieImages
+func (s *TMDBService) GetTVImages(tvID int, language string) (*models.ImagesResponse, error) {
+	return s.getImages(fmt.Sprintf("/tv/%d/images", tvID), language)
+}
+
+func (s *TMDBService) getImages(endpoint, language string) (*models.ImagesResponse, error) {
+	key := endpoint + "?" + language
+	if cached, ok := s.imagesCache.Get(key); ok {
+		return cached, nil
+	}
+
+	params := url.Values{}
+	if language != "" {
+		// "null" matches images without text, e.g. most backdrops
+		params.Set("include_image_language", language+",null")
+	}
+
+	resp, err := s.makeRequest(endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.ImagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	s.imagesCache.Set(key, &result)
+	return &result, nil
+}
+
 // TV Shows
 func (s *TMDBService) GetPopularTVShows(page int) (*models.TMDBResponse[models.TVShow], error) {
 	params := url.Values{}
@@ -173,6 +386,7 @@ func (s *TMDBService) GetPopularTVShows(page int) (*models.TMDBResponse[models.T
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.tvShows(result.Results)
 	return &result, nil
 }
 
@@ -191,11 +405,59 @@ func (s *TMDBService) GetTopRatedTVShows(page int) (*models.TMDBResponse[models.
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.tvShows(result.Results)
+	return &result, nil
+}
+
+// GetAiringTodayTVShows lists shows with an episode airing today
+func (s *TMDBService) GetAiringTodayTVShows(page int) (*models.TMDBResponse[models.TVShow], error) {
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
+
+	resp, err := s.makeRequest("/tv/airing_today", params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.TMDBResponse[models.TVShow]
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	result.Results = s.content.tvShows(result.Results)
+	return &result, nil
+}
+
+// GetOnTheAirTVShows lists shows with an episode airing in the next seven days
+func (s *TMDBService) GetOnTheAirTVShows(page int) (*models.TMDBResponse[models.TVShow], error) {
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
+
+	resp, err := s.makeRequest("/tv/on_the_air", params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.TMDBResponse[models.TVShow]
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	result.Results = s.content.tvShows(result.Results)
 	return &result, nil
 }
 
+// GetTVShowDetails returns ErrContentRestricted for a show the content profile hides
 func (s *TMDBService) GetTVShowDetails(tvID int) (*models.TVShowDetails, error) {
 	endpoint := fmt.Sprintf("/tv/%d", tvID)
+	if cached, ok := s.tvDetailsCache.Get(endpoint); ok {
+		if err := s.content.checkTVShow(cached); err != nil {
+			return nil, err
+		}
+		return cached, nil
+	}
 	params := url.Values{}
 	params.Set("append_to_response", "external_ids")
 
@@ -210,6 +472,97 @@ func (s *TMDBService) GetTVShowDetails(tvID int) (*models.TVShowDetails, error)
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	s.tvDetailsCache.Set(endpoint, &result)
+	if err := s.content.checkTVShow(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetExternalIDs returns the IMDb and other external IDs of a movie or TV show.
+// It's a much smaller response than details, so list enrichment uses it.
+func (s *TMDBService) GetExternalIDs(mediaType string, id int) (*models.ExternalIDs, error) {
+	endpoint := fmt.Sprintf("/movie/%d/external_ids", id)
+	if mediaType == "tv" {
+		endpoint = fmt.Sprintf("/tv/%d/external_ids", id)
+	}
+	if cached, ok := s.externalIDsCache.Get(endpoint); ok {
+		return cached, nil
+	}
+
+	resp, err := s.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.ExternalIDs
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	s.externalIDsCache.Set(endpoint, &result)
+	return &result, nil
+}
+
+// GetTVContentRatings returns a TV show's rating in every country
+func (s *TMDBService) GetTVContentRatings(tvID int) (*models.ContentRatingsResponse, error) {
+	endpoint := fmt.Sprintf("/tv/%d/content_ratings", tvID)
+	if cached, ok := s.contentRatings.Get(endpoint); ok {
+		return cached, nil
+	}
+
+	resp, err := s.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.ContentRatingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	s.contentRatings.Set(endpoint, &result)
+	return &result, nil
+}
+
+func (s *TMDBService) GetTVShowCredits(tvID int) (*models.Credits, error) {
+	endpoint := fmt.Sprintf("/tv/%d/credits", tvID)
+	if cached, ok := s.tvCreditsCache.Get(endpoint); ok {
+		return cached, nil
+	}
+
+	resp, err := s.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.Credits
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	s.tvCreditsCache.Set(endpoint, &result)
+	return &result, nil
+}
+
+// GetTVSeason returns one season of a TV show with its episodes
+func (s *TMDBService) GetTVSeason(tvID, seasonNumber int) (*models.SeasonDetails, error) {
+	endpoint := fmt.Sprintf("/tv/%d/season/%d", tvID, seasonNumber)
+
+	resp, err := s.makeRequest(endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.SeasonDetails
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
 	return &result, nil
 }
 
@@ -232,9 +585,17 @@ func (s *TMDBService) GetTVShowVideos(tvID int) (*models.VideosResponse, error)
 
 // Search
 func (s *TMDBService) SearchMovies(query string, page int) (*models.TMDBResponse[models.Movie], error) {
+	return s.SearchMoviesByYear(query, 0, page)
+}
+
+// SearchMoviesByYear searches movies released in year; 0 means any year
+func (s *TMDBService) SearchMoviesByYear(query string, year, page int) (*models.TMDBResponse[models.Movie], error) {
 	params := url.Values{}
 	params.Set("query", query)
 	params.Set("page", strconv.Itoa(page))
+	if year > 0 {
+		params.Set("primary_release_year", strconv.Itoa(year))
+	}
 
 	resp, err := s.makeRequest("/search/movie", params)
 	if err != nil {
@@ -247,13 +608,22 @@ func (s *TMDBService) SearchMovies(query string, page int) (*models.TMDBResponse
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.movies(result.Results)
 	return &result, nil
 }
 
 func (s *TMDBService) SearchTVShows(query string, page int) (*models.TMDBResponse[models.TVShow], error) {
+	return s.SearchTVShowsByYear(query, 0, page)
+}
+
+// SearchTVShowsByYear searches TV shows first aired in year; 0 means any year
+func (s *TMDBService) SearchTVShowsByYear(query string, year, page int) (*models.TMDBResponse[models.TVShow], error) {
 	params := url.Values{}
 	params.Set("query", query)
 	params.Set("page", strconv.Itoa(page))
+	if year > 0 {
+		params.Set("first_air_date_year", strconv.Itoa(year))
+	}
 
 	resp, err := s.makeRequest("/search/tv", params)
 	if err != nil {
@@ -266,14 +636,58 @@ func (s *TMDBService) SearchTVShows(query string, page int) (*models.TMDBRespons
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.tvShows(result.Results)
+	return &result, nil
+}
+
+func (s *TMDBService) SearchPeople(query string, page int) (*models.TMDBResponse[models.Person], error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("page", strconv.Itoa(page))
+
+	resp, err := s.makeRequest("/search/person", params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.TMDBResponse[models.Person]
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	result.Results = s.content.people(result.Results)
+	return &result, nil
+}
+
+// SearchMulti searches movies, TV shows and people in one request
+func (s *TMDBService) SearchMulti(query string, page int) (*models.TMDBResponse[models.MultiSearchResult], error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("page", strconv.Itoa(page))
+
+	resp, err := s.makeRequest("/search/multi", params)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result models.TMDBResponse[models.MultiSearchResult]
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	result.Results = s.content.multi(result.Results)
 	return &result, nil
 }
 
 // Trending
-func (s *TMDBService) GetTrendingMovies(timeWindow string) (*models.TMDBResponse[models.Movie], error) {
+func (s *TMDBService) GetTrendingMovies(timeWindow string, page int) (*models.TMDBResponse[models.Movie], error) {
 	endpoint := fmt.Sprintf("/trending/movie/%s", timeWindow)
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
 
-	resp, err := s.makeRequest(endpoint, nil)
+	resp, err := s.makeRequest(endpoint, params)
 	if err != nil {
 		return nil, err
 	}
@@ -284,13 +698,16 @@ func (s *TMDBService) GetTrendingMovies(timeWindow string) (*models.TMDBResponse
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.movies(result.Results)
 	return &result, nil
 }
 
-func (s *TMDBService) GetTrendingTVShows(timeWindow string) (*models.TMDBResponse[models.TVShow], error) {
+func (s *TMDBService) GetTrendingTVShows(timeWindow string, page int) (*models.TMDBResponse[models.TVShow], error) {
 	endpoint := fmt.Sprintf("/trending/tv/%s", timeWindow)
+	params := url.Values{}
+	params.Set("page", strconv.Itoa(page))
 
-	resp, err := s.makeRequest(endpoint, nil)
+	resp, err := s.makeRequest(endpoint, params)
 	if err != nil {
 		return nil, err
 	}
@@ -301,6 +718,7 @@ func (s *TMDBService) GetTrendingTVShows(timeWindow string) (*models.TMDBRespons
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.tvShows(result.Results)
 	return &result, nil
 }
 
@@ -357,6 +775,10 @@ func (s *TMDBService) DiscoverMovies(filters models.SearchFilters, page int) (*m
 	if filters.Rating != nil {
 		params.Set("vote_average.gte", fmt.Sprintf("%.1f", *filters.Rating))
 	}
+	if filters.Language != "" {
+		params.Set("with_original_language", filters.Language)
+	}
+	s.content.discoverParams(params, "movie", filters.Certification)
 	if filters.SortBy != "" {
 		sortDirection := "desc"
 		if filters.SortOrder == "asc" {
@@ -376,6 +798,7 @@ func (s *TMDBService) DiscoverMovies(filters models.SearchFilters, page int) (*m
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.discoveredMovies(result.Results)
 	return &result, nil
 }
 
@@ -392,6 +815,10 @@ func (s *TMDBService) DiscoverTVShows(filters models.SearchFilters, page int) (*
 	if filters.Rating != nil {
 		params.Set("vote_average.gte", fmt.Sprintf("%.1f", *filters.Rating))
 	}
+	if filters.Language != "" {
+		params.Set("with_original_language", filters.Language)
+	}
+	s.content.discoverParams(params, "tv", filters.Certification)
 	if filters.SortBy != "" {
 		sortDirection := "desc"
 		if filters.SortOrder == "asc" {
@@ -411,15 +838,45 @@ func (s *TMDBService) DiscoverTVShows(filters models.SearchFilters, page int) (*
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	result.Results = s.content.discoveredTVShows(result.Results)
 	return &result, nil
 }
 
+// GetCertifications returns the ratings used in the configured region for
+// "movie" or "tv", from least to most restrictive
+func (s *TMDBService) GetCertifications(mediaType string) ([]models.Certification, error) {
+	endpoint := fmt.Sprintf("/certification/%s/list", mediaType)
+	all, ok := s.certifications.Get(endpoint)
+	if !ok {
+		resp, err := s.makeRequest(endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		var result struct {
+			Certifications map[string][]models.Certification `json:"certifications"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		all = result.Certifications
+		s.certifications.Set(endpoint, all)
+	}
+
+	certs := append([]models.Certification(nil), all[s.region]...)
+	sort.Slice(certs, func(i, j int) bool { return certs[i].Order < certs[j].Order })
+	return certs, nil
+}
+
 // Utility functions
+
+// BuildImageURL returns the image proxy URL for a TMDB image path; see ImageService
 func (s *TMDBService) BuildImageURL(path *string, size string) string {
 	if path == nil || *path == "" {
 		return "/static/images/placeholder.jpg"
 	}
-	return fmt.Sprintf("%s/%s%s", TMDBImageBaseURL, size, *path)
+	return fmt.Sprintf("/img/%s%s", size, *path)
 }
 
 func (s *TMDBService) GetPosterURL(path *string) string {
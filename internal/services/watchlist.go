@@ -2,25 +2,32 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"muvi-discovery-app/internal/models"
 )
 
+// ErrAlreadyInWatchlist is returned when adding an item that is already there
+var ErrAlreadyInWatchlist = errors.New("item already in watchlist")
+
 // WatchlistService manages user watchlists using local file storage
 type WatchlistService struct {
 	mu        sync.RWMutex
 	watchlist map[string]models.WatchlistItem // key is "type:id"
 	filePath  string
+	events    *watchlistEvents
 }
 
 func NewWatchlistService(filePath string) *WatchlistService {
 	ws := &WatchlistService{
 		watchlist: make(map[string]models.WatchlistItem),
 		filePath:  filePath,
+		events:    newWatchlistEvents(),
 	}
 	
 	// Load existing data
@@ -29,6 +36,33 @@ func NewWatchlistService(filePath string) *WatchlistService {
 	return ws
 }
 
+// Subscribe registers fn to be called after every successful change, in
+// order, until the returned function is called. fn runs on the goroutine
+// that delivers every event, so it must not block.
+func (ws *WatchlistService) Subscribe(fn func(WatchlistEvent)) (unsubscribe func()) {
+	return ws.events.subscribe(fn)
+}
+
+// EventsSince returns the changes after the event numbered seq, oldest
+// first. It reports false when they can't all be replayed, in which case
+// the caller should reload the watchlist instead.
+func (ws *WatchlistService) EventsSince(seq uint64) ([]WatchlistEvent, bool) {
+	return ws.events.since(seq)
+}
+
+// State returns the watchlist size and the number of the latest event
+// together, so events after seq can be applied to it
+func (ws *WatchlistService) State() (count int, seq uint64) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return len(ws.watchlist), ws.events.lastSeq()
+}
+
+// publish notifies subscribers of a change; callers hold ws.mu
+func (ws *WatchlistService) publish(action string, item models.WatchlistItem) {
+	ws.events.publish(WatchlistEvent{Action: action, Item: item, Count: len(ws.watchlist)})
+}
+
 func (ws *WatchlistService) loadFromFile() {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
@@ -75,14 +109,49 @@ func (ws *WatchlistService) AddItem(item models.WatchlistItem) error {
 	
 	// Check if item already exists
 	if _, exists := ws.watchlist[key]; exists {
-		return fmt.Errorf("item already in watchlist")
+		return ErrAlreadyInWatchlist
 	}
 
 	item.AddedAt = time.Now()
 	item.Watched = false
 	ws.watchlist[key] = item
 
-	return ws.saveToFile()
+	if err := ws.saveToFile(); err != nil {
+		return err
+	}
+	ws.publish("added", item)
+	return nil
+}
+
+// AddItems adds several items with a single save, skipping any already in
+// the watchlist. It returns how many were added.
+func (ws *WatchlistService) AddItems(items []models.WatchlistItem) (int, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	var added []models.WatchlistItem
+	now := time.Now()
+	for _, item := range items {
+		key := fmt.Sprintf("%s:%d", item.Type, item.ID)
+		if _, exists := ws.watchlist[key]; exists {
+			continue
+		}
+		item.AddedAt = now
+		item.Watched = false
+		ws.watchlist[key] = item
+		added = append(added, item)
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+
+	if err := ws.saveToFile(); err != nil {
+		return 0, err
+	}
+	for _, item := range added {
+		ws.publish("added", item)
+	}
+	return len(added), nil
 }
 
 func (ws *WatchlistService) RemoveItem(itemType string, id int) error {
@@ -91,12 +160,17 @@ func (ws *WatchlistService) RemoveItem(itemType string, id int) error {
 
 	key := fmt.Sprintf("%s:%d", itemType, id)
 	
-	if _, exists := ws.watchlist[key]; !exists {
+	item, exists := ws.watchlist[key]
+	if !exists {
 		return fmt.Errorf("item not found in watchlist")
 	}
 
 	delete(ws.watchlist, key)
-	return ws.saveToFile()
+	if err := ws.saveToFile(); err != nil {
+		return err
+	}
+	ws.publish("removed", item)
+	return nil
 }
 
 func (ws *WatchlistService) ToggleWatched(itemType string, id int) error {
@@ -118,8 +192,120 @@ func (ws *WatchlistService) ToggleWatched(itemType string, id int) error {
 		item.WatchedAt = nil
 	}
 
+	return ws.update(key, item, "toggled")
+}
+
+// SetRating stores the user's personal rating (0-10) for an item; nil clears it
+func (ws *WatchlistService) SetRating(itemType string, id int, rating *float64) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if rating != nil && (*rating < 0 || *rating > 10) {
+		return fmt.Errorf("rating must be between 0 and 10")
+	}
+
+	key := fmt.Sprintf("%s:%d", itemType, id)
+
+	item, exists := ws.watchlist[key]
+	if !exists {
+		return fmt.Errorf("item not found in watchlist")
+	}
+
+	item.Rating = rating
+	return ws.update(key, item, "updated")
+}
+
+// SetTags replaces an item's tags, dropping blanks and duplicates
+func (ws *WatchlistService) SetTags(itemType string, id int, tags []string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%d", itemType, id)
+
+	item, exists := ws.watchlist[key]
+	if !exists {
+		return fmt.Errorf("item not found in watchlist")
+	}
+
+	seen := make(map[string]bool)
+	item.Tags = nil
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		item.Tags = append(item.Tags, tag)
+	}
+
+	return ws.update(key, item, "updated")
+}
+
+// SetPoster replaces the poster shown for an item; nil restores TMDB's default
+func (ws *WatchlistService) SetPoster(itemType string, id int, posterPath *string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%d", itemType, id)
+
+	item, exists := ws.watchlist[key]
+	if !exists {
+		return fmt.Errorf("item not found in watchlist")
+	}
+
+	item.CustomPosterPath = posterPath
+	return ws.update(key, item, "updated")
+}
+
+// UpdateMetadata replaces an item's copy of TMDB data (title, poster, release
+// date, score, status and season count) with meta's and returns the item as it
+// was. Everything the user set is kept. Subscribers only hear of real changes.
+func (ws *WatchlistService) UpdateMetadata(itemType string, id int, meta models.WatchlistItem) (models.WatchlistItem, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%d", itemType, id)
+
+	old, exists := ws.watchlist[key]
+	if !exists {
+		return old, fmt.Errorf("item not found in watchlist")
+	}
+
+	item := old
+	item.Title = meta.Title
+	item.PosterPath = meta.PosterPath
+	item.ReleaseDate = meta.ReleaseDate
+	item.VoteAverage = meta.VoteAverage
+	item.Status = meta.Status
+	item.NumberOfSeasons = meta.NumberOfSeasons
+	now := time.Now()
+	item.RefreshedAt = &now
+
+	changed := item.Title != old.Title || !equalPaths(item.PosterPath, old.PosterPath) ||
+		item.ReleaseDate != old.ReleaseDate || item.VoteAverage != old.VoteAverage ||
+		item.Status != old.Status || item.NumberOfSeasons != old.NumberOfSeasons
+	if !changed {
+		ws.watchlist[key] = item
+		return old, ws.saveToFile()
+	}
+	return old, ws.update(key, item, "updated")
+}
+
+func equalPaths(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// update stores a changed item and notifies subscribers; callers hold ws.mu
+func (ws *WatchlistService) update(key string, item models.WatchlistItem, action string) error {
 	ws.watchlist[key] = item
-	return ws.saveToFile()
+	if err := ws.saveToFile(); err != nil {
+		return err
+	}
+	ws.publish(action, item)
+	return nil
 }
 
 func (ws *WatchlistService) GetAllItems() []models.WatchlistItem {
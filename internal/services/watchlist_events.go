@@ -0,0 +1,127 @@
+package services
+
+import (
+	"sync"
+
+	"muvi-discovery-app/internal/models"
+)
+
+// WatchlistEvent describes one change to the watchlist
+type WatchlistEvent struct {
+	// Seq numbers events from 1 in publish order, so a client that
+	// reconnects can ask for the ones it missed
+	Seq    uint64               `json:"seq"`
+	Action string               `json:"action"` // "added", "removed", "toggled" or "updated"
+	Item   models.WatchlistItem `json:"item"`
+	Count  int                  `json:"count"` // watchlist size after the change
+}
+
+// eventHistorySize is how many recent events are kept for EventsSince
+const eventHistorySize = 256
+
+// watchlistEvents delivers events to subscribers on a single goroutine, in
+// publish order. Publishing never blocks, so it is safe while holding the
+// watchlist lock, and subscribers may call back into the WatchlistService.
+type watchlistEvents struct {
+	mu          sync.Mutex
+	cond        *sync.Cond
+	queue       []WatchlistEvent
+	subscribers []subscriber
+	nextID      int
+	seq         uint64
+	history     []WatchlistEvent // the latest events, oldest first
+}
+
+type subscriber struct {
+	id int
+	fn func(WatchlistEvent)
+}
+
+func newWatchlistEvents() *watchlistEvents {
+	e := &watchlistEvents{}
+	e.cond = sync.NewCond(&e.mu)
+	go e.dispatch()
+	return e
+}
+
+// subscribe adds fn and returns a function that removes it again
+func (e *watchlistEvents) subscribe(fn func(WatchlistEvent)) func() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.nextID++
+	id := e.nextID
+	e.subscribers = append(e.subscribers, subscriber{id, fn})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { e.unsubscribe(id) })
+	}
+}
+
+func (e *watchlistEvents) unsubscribe(id int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// dispatch may be ranging over the old slice, so build a new one
+	subscribers := make([]subscriber, 0, len(e.subscribers))
+	for _, s := range e.subscribers {
+		if s.id != id {
+			subscribers = append(subscribers, s)
+		}
+	}
+	e.subscribers = subscribers
+}
+
+func (e *watchlistEvents) publish(event WatchlistEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seq++
+	event.Seq = e.seq
+	e.history = append(e.history, event)
+	if len(e.history) > eventHistorySize {
+		e.history = e.history[len(e.history)-eventHistorySize:]
+	}
+	e.queue = append(e.queue, event)
+	e.cond.Signal()
+}
+
+// lastSeq returns the sequence number of the latest event, 0 before any
+func (e *watchlistEvents) lastSeq() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.seq
+}
+
+// since returns the events after seq. It reports false when some of them
+// are no longer kept, or seq is from before a restart.
+func (e *watchlistEvents) since(seq uint64) ([]WatchlistEvent, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if seq > e.seq {
+		return nil, false
+	}
+	if seq == e.seq {
+		return nil, true
+	}
+	if len(e.history) == 0 || e.history[0].Seq > seq+1 {
+		return nil, false
+	}
+	start := len(e.history) - int(e.seq-seq)
+	return append([]WatchlistEvent(nil), e.history[start:]...), true
+}
+
+func (e *watchlistEvents) dispatch() {
+	for {
+		e.mu.Lock()
+		for len(e.queue) == 0 {
+			e.cond.Wait()
+		}
+		event := e.queue[0]
+		e.queue = e.queue[1:]
+		subscribers := e.subscribers
+		e.mu.Unlock()
+
+		for _, s := range subscribers {
+			s.fn(event)
+		}
+	}
+}
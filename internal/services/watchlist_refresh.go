@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+const (
+	// watchlistRefreshAge is how old an item's TMDB data may get before a
+	// refresh run looks it up again
+	watchlistRefreshAge = 24 * time.Hour
+
+	// watchlistRefreshBatch bounds how many titles one run looks up
+	watchlistRefreshBatch = 100
+
+	// watchlistRefreshPause spaces lookups out, so a run takes a small share
+	// of the TMDB rate limit and pages stay fast while it works
+	watchlistRefreshPause = 500 * time.Millisecond
+
+	// watchlistChangeLogSize is how many detected changes are kept
+	watchlistChangeLogSize = 500
+
+	// A title whose lookup fails is retried after watchlistRefreshRetry,
+	// doubling each time up to watchlistRefreshMaxRetry, so titles TMDB no
+	// longer has can't fill every batch
+	watchlistRefreshRetry    = time.Hour
+	watchlistRefreshMaxRetry = 7 * 24 * time.Hour
+)
+
+// WatchlistRefreshService keeps the TMDB data copied into watchlist items
+// current, and records changes worth telling the user about: a new release
+// date, a new season or a new status, such as a show having ended.
+type WatchlistRefreshService struct {
+	tmdbService      *TMDBService
+	watchlistService *WatchlistService
+	changesPath      string
+
+	mu       sync.Mutex
+	changes  []models.WatchlistChange  // oldest first
+	failures map[string]refreshFailure // by "type:id"; cleared on success
+}
+
+// refreshFailure backs off lookups of a title that keeps failing. It is only
+// kept in memory, so a restart retries every title once.
+type refreshFailure struct {
+	count   int
+	retryAt time.Time
+}
+
+func NewWatchlistRefreshService(tmdbService *TMDBService, watchlistService *WatchlistService, changesPath string) *WatchlistRefreshService {
+	s := &WatchlistRefreshService{
+		tmdbService:      tmdbService,
+		watchlistService: watchlistService,
+		changesPath:      changesPath,
+		failures:         make(map[string]refreshFailure),
+	}
+	s.loadChanges()
+	return s
+}
+
+// Changes returns up to limit recorded changes, newest first; 0 returns all
+func (s *WatchlistRefreshService) Changes(limit int) []models.WatchlistChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := []models.WatchlistChange{}
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		if limit > 0 && len(changes) == limit {
+			break
+		}
+		changes = append(changes, s.changes[i])
+	}
+	return changes
+}
+
+// Refresh looks up watchlist items whose data is older than a day, least
+// recently refreshed first, skipping titles whose last lookup failed until
+// their retry time. Lookups go through TMDBService and so wait on its rate
+// limiter. It returns early when ctx is cancelled, as on shutdown.
+func (s *WatchlistRefreshService) Refresh(ctx context.Context) {
+	due := s.dueItems(time.Now())
+	if len(due) == 0 {
+		return
+	}
+
+	refreshed, found := 0, 0
+	for i, item := range due {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(watchlistRefreshPause):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		key := refreshKey(item)
+		changes, err := s.refreshItem(item)
+		if err != nil {
+			retry := s.fail(key)
+			if !errors.Is(err, ErrContentRestricted) {
+				log.Printf("Error refreshing watchlist item %s, retrying in %s: %v", key, retry, err)
+			}
+			continue
+		}
+		s.mu.Lock()
+		delete(s.failures, key)
+		s.mu.Unlock()
+		refreshed++
+		found += len(changes)
+		s.record(changes)
+	}
+	log.Printf("Refreshed %d of %d watchlist titles, %d changes found", refreshed, len(due), found)
+}
+
+// fail records a failed lookup and returns how long until the next one
+func (s *WatchlistRefreshService) fail(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.failures[key]
+	delay := min(watchlistRefreshRetry<<min(f.count, 10), watchlistRefreshMaxRetry)
+	s.failures[key] = refreshFailure{count: f.count + 1, retryAt: time.Now().Add(delay)}
+	return delay
+}
+
+func refreshKey(item models.WatchlistItem) string {
+	return item.Type + ":" + strconv.Itoa(item.ID)
+}
+
+// dueItems returns the items last refreshed over a day before now, oldest
+// first, leaving out those backing off after a failure
+func (s *WatchlistRefreshService) dueItems(now time.Time) []models.WatchlistItem {
+	cutoff := now.Add(-watchlistRefreshAge)
+	items := s.watchlistService.GetAllItems()
+
+	s.mu.Lock()
+	// Forget failures of titles no longer on the watchlist
+	onList := make(map[string]bool, len(items))
+	for _, item := range items {
+		onList[refreshKey(item)] = true
+	}
+	for key := range s.failures {
+		if !onList[key] {
+			delete(s.failures, key)
+		}
+	}
+	var due []models.WatchlistItem
+	for _, item := range items {
+		if f, failed := s.failures[refreshKey(item)]; failed && now.Before(f.retryAt) {
+			continue
+		}
+		if item.RefreshedAt == nil || item.RefreshedAt.Before(cutoff) {
+			due = append(due, item)
+		}
+	}
+	s.mu.Unlock()
+
+	// Never refreshed sorts as the zero time, so new items go first
+	refreshedAt := func(item models.WatchlistItem) time.Time {
+		if item.RefreshedAt == nil {
+			return time.Time{}
+		}
+		return *item.RefreshedAt
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return refreshedAt(due[i]).Before(refreshedAt(due[j]))
+	})
+	if len(due) > watchlistRefreshBatch {
+		due = due[:watchlistRefreshBatch]
+	}
+	return due
+}
+
+// refreshItem updates one item from TMDB and returns what changed
+func (s *WatchlistRefreshService) refreshItem(item models.WatchlistItem) ([]models.WatchlistChange, error) {
+	var meta models.WatchlistItem
+	if item.Type == "tv" {
+		details, err := s.tmdbService.GetTVShowDetails(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		meta = models.WatchlistItem{
+			Title:           details.Name,
+			PosterPath:      details.PosterPath,
+			ReleaseDate:     details.FirstAirDate,
+			VoteAverage:     details.VoteAverage,
+			Status:          details.Status,
+			NumberOfSeasons: details.NumberOfSeasons,
+		}
+	} else {
+		details, err := s.tmdbService.GetMovieDetails(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		meta = models.WatchlistItem{
+			Title:       details.Title,
+			PosterPath:  details.PosterPath,
+			ReleaseDate: details.ReleaseDate,
+			VoteAverage: details.VoteAverage,
+			Status:      details.Status,
+		}
+	}
+
+	old, err := s.watchlistService.UpdateMetadata(item.Type, item.ID, meta)
+	if err != nil {
+		// Removed while it was being looked up
+		return nil, nil
+	}
+	return detectChanges(old, meta), nil
+}
+
+// detectChanges compares an item before a refresh with its fresh data.
+// Status and season count are only compared once a refresh has set them.
+func detectChanges(old, fresh models.WatchlistItem) []models.WatchlistChange {
+	var changes []models.WatchlistChange
+	add := func(kind, from, to string) {
+		changes = append(changes, models.WatchlistChange{
+			Type:       old.Type,
+			ID:         old.ID,
+			Title:      fresh.Title,
+			Kind:       kind,
+			Old:        from,
+			New:        to,
+			DetectedAt: time.Now().UTC(),
+		})
+	}
+
+	if fresh.ReleaseDate != "" && fresh.ReleaseDate != old.ReleaseDate {
+		add("release_date", old.ReleaseDate, fresh.ReleaseDate)
+	}
+	if old.NumberOfSeasons > 0 && fresh.NumberOfSeasons > old.NumberOfSeasons {
+		add("new_season", strconv.Itoa(old.NumberOfSeasons), strconv.Itoa(fresh.NumberOfSeasons))
+	}
+	if old.Status != "" && fresh.Status != "" && fresh.Status != old.Status {
+		add("status", old.Status, fresh.Status)
+	}
+	return changes
+}
+
+// record adds changes to the log and saves it
+func (s *WatchlistRefreshService) record(changes []models.WatchlistChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, c := range changes {
+		log.Printf("Watchlist change for %s:%d (%s): %s %q -> %q", c.Type, c.ID, c.Title, c.Kind, c.Old, c.New)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, changes...)
+	if len(s.changes) > watchlistChangeLogSize {
+		s.changes = s.changes[len(s.changes)-watchlistChangeLogSize:]
+	}
+
+	data, err := json.MarshalIndent(s.changes, "", "  ")
+	if err == nil {
+		err = os.WriteFile(s.changesPath, data, 0644)
+	}
+	if err != nil {
+		log.Printf("Error saving watchlist changes: %v", err)
+	}
+}
+
+func (s *WatchlistRefreshService) loadChanges() {
+	data, err := os.ReadFile(s.changesPath)
+	if err != nil {
+		// Nothing recorded yet
+		return
+	}
+	if err := json.Unmarshal(data, &s.changes); err != nil {
+		log.Printf("Ignoring unreadable watchlist changes %s: %v", s.changesPath, err)
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+func newTestRefreshService(t *testing.T, items ...models.WatchlistItem) *WatchlistRefreshService {
+	t.Helper()
+	dir := t.TempDir()
+	watchlist := NewWatchlistService(filepath.Join(dir, "watchlist.json"))
+	if _, err := watchlist.AddItems(items); err != nil {
+		t.Fatal(err)
+	}
+	return NewWatchlistRefreshService(NewTMDBService("test", "US"), watchlist, filepath.Join(dir, "changes.json"))
+}
+
+func TestWatchlistRefreshBacksOffFailedLookups(t *testing.T) {
+	tmdb := newFakeTMDB(t, nil)
+	gone := models.WatchlistItem{Type: "movie", ID: 1000, Title: "Gone"}
+	s := newTestRefreshService(t, gone)
+
+	s.Refresh(context.Background())
+	if n := tmdb.count("/movie/1000"); n != 1 {
+		t.Fatalf("looked up %d times, want 1", n)
+	}
+	if due := s.dueItems(time.Now()); len(due) != 0 {
+		t.Errorf("due = %v right after a failure, want none", due)
+	}
+
+	// A second run within the retry delay leaves it alone
+	s.Refresh(context.Background())
+	if n := tmdb.count("/movie/1000"); n != 1 {
+		t.Errorf("looked up %d times, want 1", n)
+	}
+
+	later := time.Now().Add(watchlistRefreshRetry + time.Minute)
+	if due := s.dueItems(later); len(due) != 1 {
+		t.Errorf("due = %v after the retry delay, want the title", due)
+	}
+	if delay := s.fail(refreshKey(gone)); delay != 2*watchlistRefreshRetry {
+		t.Errorf("second retry delay = %s, want %s", delay, 2*watchlistRefreshRetry)
+	}
+	for range 20 {
+		s.fail(refreshKey(gone))
+	}
+	if delay := s.fail(refreshKey(gone)); delay != watchlistRefreshMaxRetry {
+		t.Errorf("retry delay = %s, want the cap %s", delay, watchlistRefreshMaxRetry)
+	}
+
+	// Removing the title forgets its failures
+	if err := s.watchlistService.RemoveItem(gone.Type, gone.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.dueItems(time.Now())
+	if len(s.failures) != 0 {
+		t.Errorf("failures = %v, want none", s.failures)
+	}
+}
+
+func TestWatchlistRefreshFailuresDontStarveBatch(t *testing.T) {
+	var items []models.WatchlistItem
+	for id := 1; id <= watchlistRefreshBatch+1; id++ {
+		items = append(items, models.WatchlistItem{Type: "movie", ID: id})
+	}
+	s := newTestRefreshService(t, items...)
+
+	// The first batch failed; the title left over is next even though the
+	// failed ones were never refreshed either
+	for _, item := range items[:watchlistRefreshBatch] {
+		s.fail(refreshKey(item))
+	}
+	due := s.dueItems(time.Now())
+	if len(due) != 1 || due[0].ID != watchlistRefreshBatch+1 {
+		t.Errorf("due = %v, want only the title that hasn't failed", due)
+	}
+}
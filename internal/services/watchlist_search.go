@@ -0,0 +1,262 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"muvi-discovery-app/internal/fulltext"
+	"muvi-discovery-app/internal/models"
+)
+
+// watchlistSearchCast is how many billed cast members are indexed per title
+const watchlistSearchCast = 10
+
+// Failed metadata fetches are retried after watchlistSearchRetry, doubling
+// each time up to watchlistSearchMaxRetry
+const (
+	watchlistSearchRetry    = time.Minute
+	watchlistSearchMaxRetry = time.Hour
+)
+
+// WatchlistSearchService keeps a full-text index over watchlist titles,
+// tags, overviews and cast. Overviews and cast come from TMDB and are cached
+// on disk so only newly added titles need fetching.
+type WatchlistSearchService struct {
+	tmdbService      *TMDBService
+	watchlistService *WatchlistService
+	index            *fulltext.Index
+
+	// mu orders index and metadata updates, so a fetch that finishes after
+	// its item was removed can't put it back
+	mu       sync.Mutex
+	members  map[string]models.WatchlistItem // items on the watchlist; key is "type:id"
+	metadata map[string]watchlistMetadata    // key is "type:id"
+	failures map[string]int                  // failed fetches in a row, by key
+	metaPath string
+	fetches  chan models.WatchlistItem
+}
+
+// watchlistMetadata is the TMDB text indexed alongside a watchlist item
+type watchlistMetadata struct {
+	Overview string   `json:"overview"`
+	Cast     []string `json:"cast"`
+	Genres   []string `json:"genres"`
+}
+
+func NewWatchlistSearchService(tmdbService *TMDBService, watchlistService *WatchlistService, metaPath string) *WatchlistSearchService {
+	s := &WatchlistSearchService{
+		tmdbService:      tmdbService,
+		watchlistService: watchlistService,
+		index: fulltext.New(map[string]float64{
+			"title":    3,
+			"tags":     2.5,
+			"cast":     1.5,
+			"genres":   1.2,
+			"overview": 1,
+		}),
+		members:  make(map[string]models.WatchlistItem),
+		metadata: make(map[string]watchlistMetadata),
+		failures: make(map[string]int),
+		metaPath: metaPath,
+		fetches:  make(chan models.WatchlistItem, 1024),
+	}
+
+	s.loadMetadata()
+	go s.fetchMetadata()
+
+	watchlistService.Subscribe(s.handleEvent)
+	for _, item := range watchlistService.GetAllItems() {
+		s.indexItem(item)
+	}
+
+	return s
+}
+
+// Search returns watchlist items matching query, best match first
+func (s *WatchlistSearchService) Search(query string) []models.WatchlistItem {
+	results := s.index.Search(query, 0)
+
+	items := make([]models.WatchlistItem, 0, len(results))
+	for _, r := range results {
+		itemType, idStr, _ := strings.Cut(r.ID, ":")
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			continue
+		}
+		if item, ok := s.watchlistService.GetItem(itemType, id); ok {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+func (s *WatchlistSearchService) handleEvent(event WatchlistEvent) {
+	key := fmt.Sprintf("%s:%d", event.Item.Type, event.Item.ID)
+	if event.Action == "removed" {
+		s.mu.Lock()
+		delete(s.members, key)
+		delete(s.metadata, key)
+		delete(s.failures, key)
+		s.index.Delete(key)
+		s.mu.Unlock()
+		s.saveMetadata()
+		return
+	}
+	s.indexItem(event.Item)
+}
+
+// indexItem indexes what is known now and queues a metadata fetch if needed
+func (s *WatchlistSearchService) indexItem(item models.WatchlistItem) {
+	key := fmt.Sprintf("%s:%d", item.Type, item.ID)
+
+	s.mu.Lock()
+	s.members[key] = item
+	_, ok := s.metadata[key]
+	s.put(key, item)
+	s.mu.Unlock()
+
+	if !ok {
+		s.queueFetch(item)
+	}
+}
+
+// put indexes an item with its metadata; callers hold s.mu
+func (s *WatchlistSearchService) put(key string, item models.WatchlistItem) {
+	meta := s.metadata[key]
+	s.index.Put(key, fulltext.Document{
+		"title":    item.Title,
+		"tags":     strings.Join(item.Tags, " "),
+		"overview": meta.Overview,
+		"cast":     strings.Join(meta.Cast, " "),
+		"genres":   strings.Join(meta.Genres, " "),
+	})
+}
+
+func (s *WatchlistSearchService) queueFetch(item models.WatchlistItem) {
+	select {
+	case s.fetches <- item:
+	default:
+		// The queue is full; try again once it has drained
+		time.AfterFunc(watchlistSearchRetry, func() { s.queueFetch(item) })
+	}
+}
+
+// fetchMetadata loads overviews and cast one title at a time, so a large
+// watchlist doesn't burst TMDB on startup
+func (s *WatchlistSearchService) fetchMetadata() {
+	for item := range s.fetches {
+		key := fmt.Sprintf("%s:%d", item.Type, item.ID)
+
+		s.mu.Lock()
+		_, done := s.metadata[key]
+		_, member := s.members[key]
+		s.mu.Unlock()
+		if done || !member {
+			continue
+		}
+
+		// Titles TMDB won't describe are searched by title and tags only
+		meta, err := s.lookupMetadata(item)
+		if err != nil && !IsNotFound(err) && !errors.Is(err, ErrContentRestricted) {
+			s.retryFetch(key, item, err)
+			continue
+		}
+
+		// The item may have changed since it was queued, or been removed
+		s.mu.Lock()
+		current, member := s.members[key]
+		if member {
+			s.metadata[key] = meta
+			delete(s.failures, key)
+			s.put(key, current)
+		}
+		s.mu.Unlock()
+		if member {
+			s.saveMetadata()
+		}
+	}
+}
+
+// retryFetch queues a failed fetch again after a growing delay
+func (s *WatchlistSearchService) retryFetch(key string, item models.WatchlistItem, err error) {
+	s.mu.Lock()
+	failures := s.failures[key]
+	s.failures[key] = failures + 1
+	s.mu.Unlock()
+
+	delay := min(watchlistSearchRetry<<min(failures, 6), watchlistSearchMaxRetry)
+	log.Printf("Error fetching search metadata for %s, retrying in %s: %v", key, delay, err)
+	time.AfterFunc(delay, func() { s.queueFetch(item) })
+}
+
+func (s *WatchlistSearchService) lookupMetadata(item models.WatchlistItem) (watchlistMetadata, error) {
+	var meta watchlistMetadata
+	var credits *models.Credits
+	var err error
+
+	if item.Type == "tv" {
+		details, detailsErr := s.tmdbService.GetTVShowDetails(item.ID)
+		if detailsErr != nil {
+			return meta, detailsErr
+		}
+		meta.Overview = details.Overview
+		for _, g := range details.Genres {
+			meta.Genres = append(meta.Genres, g.Name)
+		}
+		credits, err = s.tmdbService.GetTVShowCredits(item.ID)
+	} else {
+		details, detailsErr := s.tmdbService.GetMovieDetails(item.ID)
+		if detailsErr != nil {
+			return meta, detailsErr
+		}
+		meta.Overview = details.Overview
+		for _, g := range details.Genres {
+			meta.Genres = append(meta.Genres, g.Name)
+		}
+		credits, err = s.tmdbService.GetMovieCredits(item.ID)
+	}
+	if err != nil {
+		return meta, err
+	}
+
+	for _, c := range credits.Cast {
+		if len(meta.Cast) == watchlistSearchCast {
+			break
+		}
+		meta.Cast = append(meta.Cast, c.Name)
+	}
+	return meta, nil
+}
+
+func (s *WatchlistSearchService) loadMetadata() {
+	data, err := os.ReadFile(s.metaPath)
+	if err != nil {
+		// No cache yet; everything is fetched in the background
+		return
+	}
+	if err := json.Unmarshal(data, &s.metadata); err != nil {
+		log.Printf("Ignoring unreadable search metadata cache %s: %v", s.metaPath, err)
+		s.metadata = make(map[string]watchlistMetadata)
+	}
+}
+
+func (s *WatchlistSearchService) saveMetadata() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.MarshalIndent(s.metadata, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding search metadata: %v", err)
+		return
+	}
+	if err := os.WriteFile(s.metaPath, data, 0644); err != nil {
+		log.Printf("Error saving search metadata: %v", err)
+	}
+}
@@ -0,0 +1,669 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+// Webhook events. Watchlist events carry the WatchlistItem, release events
+// a ReleaseNotice.
+const (
+	EventWatchlistAdded   = "watchlist.added"
+	EventWatchlistRemoved = "watchlist.removed"
+	EventWatchlistWatched = "watchlist.watched"
+	EventTitleReleased    = "title.released"
+	EventEpisodeAired     = "episode.aired"
+	// EventPing is only sent by test deliveries
+	EventPing = "ping"
+)
+
+// WebhookEvents lists the events a webhook can subscribe to
+var WebhookEvents = []string{
+	EventWatchlistAdded,
+	EventWatchlistRemoved,
+	EventWatchlistWatched,
+	EventTitleReleased,
+	EventEpisodeAired,
+}
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// the timestamp, a dot and the body, keyed with the webhook's secret.
+const (
+	WebhookEventHeader     = "X-Muvi-Event"
+	WebhookDeliveryHeader  = "X-Muvi-Delivery"
+	WebhookTimestampHeader = "X-Muvi-Timestamp"
+	WebhookSignatureHeader = "X-Muvi-Signature"
+)
+
+const (
+	webhookTimeout   = 10 * time.Second
+	webhookWorkers   = 2
+	webhookQueueSize = 256
+	// webhookLogSize is how many deliveries the log keeps, across webhooks
+	webhookLogSize = 200
+)
+
+// webhookRetryDelays are the waits before each retry of a failed delivery;
+// it is given up after the last one
+var webhookRetryDelays = []time.Duration{
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+}
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+	// ErrPrivateDestination stops deliveries to loopback, private and
+	// link-local addresses, unless SetAllowPrivateNetworks allows them
+	ErrPrivateDestination = errors.New("webhook destination is a private or loopback address")
+)
+
+// nonPublicPrefixes are ranges net/netip has no predicate for
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// WebhookService delivers watchlist and release events to configured URLs.
+// Deliveries are signed, run in the background and are retried with backoff;
+// every attempt goes in a delivery log. Webhooks and the log are saved to disk.
+type WebhookService struct {
+	calendarService *CalendarService
+	client          *http.Client
+	filePath        string
+	logPath         string
+	allowPrivate    atomic.Bool
+
+	mu         sync.Mutex
+	webhooks   []models.Webhook
+	deliveries []models.WebhookDelivery // oldest first
+	releasedOn string                   // the day released lists notices for
+	released   map[string]bool          // release notices already sent that day
+	retries    map[*time.Timer]bool
+	closed     bool
+
+	queue   chan webhookJob
+	workers sync.WaitGroup
+}
+
+type webhookJob struct {
+	webhook models.Webhook
+	payload models.WebhookPayload
+	body    []byte
+	attempt int
+}
+
+// webhookState is the saved form of the webhooks file
+type webhookState struct {
+	Webhooks   []models.Webhook `json:"webhooks"`
+	ReleasedOn string           `json:"released_on,omitempty"`
+	Released   []string         `json:"released,omitempty"`
+}
+
+func NewWebhookService(filePath, logPath string, watchlistService *WatchlistService, calendarService *CalendarService) *WebhookService {
+	s := &WebhookService{
+		calendarService: calendarService,
+		filePath:        filePath,
+		logPath:         logPath,
+		released:        make(map[string]bool),
+		retries:         make(map[*time.Timer]bool),
+		queue:           make(chan webhookJob, webhookQueueSize),
+	}
+	s.client = s.newClient()
+	s.load()
+
+	for range webhookWorkers {
+		s.workers.Add(1)
+		go s.work()
+	}
+
+	watchlistService.Subscribe(s.handleWatchlistEvent)
+	return s
+}
+
+// SetAllowPrivateNetworks lets webhooks deliver to loopback and private
+// addresses, for automation running on the same machine or network. It is
+// off by default so webhooks can't be used to reach internal services.
+func (s *WebhookService) SetAllowPrivateNetworks(allow bool) {
+	s.allowPrivate.Store(allow)
+	// Open connections were checked under the old setting
+	s.client.CloseIdleConnections()
+}
+
+// List returns the webhooks in creation order, without their secrets
+func (s *WebhookService) List() []models.Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	webhooks := make([]models.Webhook, len(s.webhooks))
+	for i, wh := range s.webhooks {
+		wh.Secret = ""
+		webhooks[i] = wh
+	}
+	return webhooks
+}
+
+// Create adds a webhook for the given events, or all of them when none are
+// given. The returned webhook is the only copy with its secret.
+func (s *WebhookService) Create(rawURL string, events []string) (models.Webhook, error) {
+	if strings.ContainsAny(rawURL, "<>\"'` \t\r\n") {
+		return models.Webhook{}, fmt.Errorf("%w: URL contains characters that must be percent-encoded", ErrInvalidWebhook)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.Webhook{}, fmt.Errorf("%w: URL must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	// Names are checked again when they resolve, on every delivery
+	if !s.allowPrivate.Load() && isPrivateHost(u.Hostname()) {
+		return models.Webhook{}, fmt.Errorf("%w: URL points to a private or loopback address", ErrInvalidWebhook)
+	}
+	var subscribed []string
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return models.Webhook{}, fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+		if !slices.Contains(subscribed, event) {
+			subscribed = append(subscribed, event)
+		}
+	}
+
+	wh := models.Webhook{
+		ID:        randomHex(8),
+		URL:       u.String(),
+		Events:    subscribed,
+		Secret:    "whsec_" + randomHex(24),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks = append(s.webhooks, wh)
+	if err := s.save(); err != nil {
+		s.webhooks = s.webhooks[:len(s.webhooks)-1]
+		return models.Webhook{}, err
+	}
+	return wh, nil
+}
+
+// Delete removes a webhook; its pending retries are dropped
+func (s *WebhookService) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.webhooks, func(wh models.Webhook) bool { return wh.ID == id })
+	if i < 0 {
+		return ErrWebhookNotFound
+	}
+	s.webhooks = slices.Delete(s.webhooks, i, i+1)
+	return s.save()
+}
+
+// Deliveries returns the logged deliveries of a webhook, or of every webhook
+// when id is empty, newest first
+func (s *WebhookService) Deliveries(id string) []models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deliveries := []models.WebhookDelivery{}
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		if id == "" || s.deliveries[i].WebhookID == id {
+			deliveries = append(deliveries, s.deliveries[i])
+		}
+	}
+	return deliveries
+}
+
+// Test sends a ping to a webhook right away and returns the result. Test
+// deliveries are logged but never retried.
+func (s *WebhookService) Test(id string) (models.WebhookDelivery, error) {
+	s.mu.Lock()
+	wh, ok := s.find(id)
+	s.mu.Unlock()
+	if !ok {
+		return models.WebhookDelivery{}, ErrWebhookNotFound
+	}
+
+	job, err := newWebhookJob(wh, EventPing, map[string]string{
+		"webhook_id": wh.ID,
+		"message":    "Test delivery from Muvi Discovery",
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery := s.deliver(job)
+	s.record(delivery)
+	return delivery, nil
+}
+
+// Emit sends an event to every webhook subscribed to it, in the background
+func (s *WebhookService) Emit(event string, data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var payload models.WebhookPayload
+	var body []byte
+	for _, wh := range s.webhooks {
+		if len(wh.Events) > 0 && !slices.Contains(wh.Events, event) {
+			continue
+		}
+		// The payload, and so its ID, is shared by every webhook
+		if body == nil {
+			job, err := newWebhookJob(wh, event, data)
+			if err != nil {
+				log.Printf("Error encoding %s webhook payload: %v", event, err)
+				return
+			}
+			payload, body = job.payload, job.body
+		}
+		if !s.enqueue(webhookJob{webhook: wh, payload: payload, body: body, attempt: 1}) {
+			log.Printf("Webhook queue full, dropping %s for webhook %s", event, wh.ID)
+		}
+	}
+}
+
+// Wants reports whether any webhook is subscribed to one of the events
+func (s *WebhookService) Wants(events ...string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, wh := range s.webhooks {
+		if len(wh.Events) == 0 {
+			return true
+		}
+		for _, event := range events {
+			if slices.Contains(wh.Events, event) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NotifyReleases emits title.released and episode.aired for watchlist titles
+// that come out today, once per title or episode. It is run by the scheduler.
+func (s *WebhookService) NotifyReleases(ctx context.Context) {
+	if ctx.Err() != nil || !s.Wants(EventTitleReleased, EventEpisodeAired) {
+		return
+	}
+
+	today := time.Now().Format(calendarDateLayout)
+	for _, e := range s.calendarService.Entries(0, true) {
+		if e.Date != today {
+			continue
+		}
+		key := fmt.Sprintf("%s:%d:%d:%d", e.Type, e.ID, e.SeasonNumber, e.EpisodeNumber)
+		if !s.markReleased(today, key) {
+			continue
+		}
+
+		event := EventTitleReleased
+		if e.Type == "tv" {
+			event = EventEpisodeAired
+		}
+		s.Emit(event, models.ReleaseNotice{
+			Type:          e.Type,
+			ID:            e.ID,
+			Title:         e.Title,
+			Date:          e.Date,
+			PosterPath:    e.PosterPath,
+			SeasonNumber:  e.SeasonNumber,
+			EpisodeNumber: e.EpisodeNumber,
+			EpisodeName:   e.EpisodeName,
+		})
+	}
+}
+
+// markReleased records a release notice for day, reporting false if it was
+// already sent
+func (s *WebhookService) markReleased(day, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.releasedOn != day {
+		s.releasedOn = day
+		s.released = make(map[string]bool)
+	}
+	if s.released[key] {
+		return false
+	}
+	s.released[key] = true
+	if err := s.save(); err != nil {
+		log.Printf("Error saving webhooks: %v", err)
+	}
+	return true
+}
+
+// Close stops taking new deliveries, drops pending retries and waits for
+// queued deliveries to finish, or until ctx is done
+func (s *WebhookService) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for timer := range s.retries {
+		timer.Stop()
+	}
+	if len(s.retries) > 0 {
+		log.Printf("Dropping %d pending webhook retries", len(s.retries))
+	}
+	s.retries = nil
+	close(s.queue)
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *WebhookService) handleWatchlistEvent(event WatchlistEvent) {
+	switch event.Action {
+	case "added":
+		s.Emit(EventWatchlistAdded, event.Item)
+	case "removed":
+		s.Emit(EventWatchlistRemoved, event.Item)
+	case "toggled":
+		if event.Item.Watched {
+			s.Emit(EventWatchlistWatched, event.Item)
+		}
+	}
+}
+
+func newWebhookJob(wh models.Webhook, event string, data interface{}) (webhookJob, error) {
+	payload := models.WebhookPayload{
+		ID:        "evt_" + randomHex(12),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return webhookJob{}, err
+	}
+	return webhookJob{webhook: wh, payload: payload, body: body, attempt: 1}, nil
+}
+
+// enqueue queues a delivery without blocking; callers hold s.mu
+func (s *WebhookService) enqueue(job webhookJob) bool {
+	if s.closed {
+		return false
+	}
+	select {
+	case s.queue <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *WebhookService) work() {
+	defer s.workers.Done()
+	for job := range s.queue {
+		delivery := s.deliver(job)
+		if !delivery.Success {
+			log.Printf("Webhook %s delivery of %s failed on attempt %d: %s",
+				job.webhook.ID, job.payload.Event, job.attempt, deliveryProblem(delivery))
+			if job.attempt <= len(webhookRetryDelays) {
+				delay := webhookRetryDelays[job.attempt-1]
+				if s.retry(job, delay) {
+					next := delivery.DeliveredAt.Add(delay)
+					delivery.NextRetry = &next
+				}
+			}
+		}
+		s.record(delivery)
+	}
+}
+
+// retry queues the next attempt after delay, with the webhook as it is then
+func (s *WebhookService) retry(job webhookJob, delay time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+
+	var timer *time.Timer
+	// The callback takes s.mu, so it can't run before timer is stored
+	timer = time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.retries[timer] {
+			return
+		}
+		delete(s.retries, timer)
+
+		wh, ok := s.find(job.webhook.ID)
+		if !ok {
+			return
+		}
+		job.webhook = wh
+		job.attempt++
+		if !s.enqueue(job) {
+			log.Printf("Webhook queue full, dropping retry of %s for webhook %s", job.payload.Event, wh.ID)
+		}
+	})
+	s.retries[timer] = true
+	return true
+}
+
+// deliver makes one signed delivery attempt
+func (s *WebhookService) deliver(job webhookJob) models.WebhookDelivery {
+	delivery := models.WebhookDelivery{
+		PayloadID:   job.payload.ID,
+		WebhookID:   job.webhook.ID,
+		URL:         job.webhook.URL,
+		Event:       job.payload.Event,
+		Attempt:     job.attempt,
+		DeliveredAt: time.Now().UTC(),
+	}
+
+	req, err := http.NewRequest("POST", job.webhook.URL, bytes.NewReader(job.body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	timestamp := strconv.FormatInt(delivery.DeliveredAt.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Muvi-Discovery-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, job.payload.Event)
+	req.Header.Set(WebhookDeliveryHeader, job.payload.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(job.webhook.Secret, timestamp, job.body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	delivery.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	// Read a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	delivery.StatusCode = resp.StatusCode
+	delivery.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	return delivery
+}
+
+// newClient returns a client that refuses to connect to private addresses.
+// The check runs on the resolved address of every connection, redirects
+// included, so a public name that resolves to an internal address is caught.
+func (s *WebhookService) newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if s.allowPrivate.Load() {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if addr, err := netip.ParseAddr(host); err != nil || isPrivateAddr(addr) {
+				return ErrPrivateDestination
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		// No proxy: it would connect on the webhook's behalf, past the check
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// isPrivateHost reports whether a URL host is an address or name that is
+// never public
+func isPrivateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && isPrivateAddr(addr)
+}
+
+func isPrivateAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// SignWebhook returns the hex signature sent in the X-Muvi-Signature header,
+// after "sha256=". Receivers compute it over the X-Muvi-Timestamp header and
+// the raw body to check a delivery is genuine.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliveryProblem(d models.WebhookDelivery) string {
+	if d.Error != "" {
+		return d.Error
+	}
+	return fmt.Sprintf("status %d", d.StatusCode)
+}
+
+// record adds a delivery to the log and saves it
+func (s *WebhookService) record(delivery models.WebhookDelivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	if len(s.deliveries) > webhookLogSize {
+		s.deliveries = s.deliveries[len(s.deliveries)-webhookLogSize:]
+	}
+
+	data, err := json.MarshalIndent(s.deliveries, "", "  ")
+	if err == nil {
+		err = os.WriteFile(s.logPath, data, 0644)
+	}
+	if err != nil {
+		log.Printf("Error saving webhook delivery log: %v", err)
+	}
+}
+
+// find returns the webhook with its secret; callers hold s.mu
+func (s *WebhookService) find(id string) (models.Webhook, bool) {
+	for _, wh := range s.webhooks {
+		if wh.ID == id {
+			return wh, true
+		}
+	}
+	return models.Webhook{}, false
+}
+
+// save writes the webhooks file; callers hold s.mu
+func (s *WebhookService) save() error {
+	state := webhookState{Webhooks: s.webhooks, ReleasedOn: s.releasedOn}
+	for key := range s.released {
+		state.Released = append(state.Released, key)
+	}
+	slices.Sort(state.Released)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.filePath, data, 0600)
+}
+
+func (s *WebhookService) load() {
+	if data, err := os.ReadFile(s.filePath); err == nil {
+		var state webhookState
+		if err := json.Unmarshal(data, &state); err != nil {
+			log.Printf("Ignoring unreadable webhooks file %s: %v", s.filePath, err)
+		} else {
+			s.webhooks = state.Webhooks
+			s.releasedOn = state.ReleasedOn
+			for _, key := range state.Released {
+				s.released[key] = true
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(s.logPath); err == nil {
+		if err := json.Unmarshal(data, &s.deliveries); err != nil {
+			log.Printf("Ignoring unreadable webhook delivery log %s: %v", s.logPath, err)
+		}
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
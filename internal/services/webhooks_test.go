@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"muvi-discovery-app/internal/models"
+)
+
+// newTestWebhookService returns a webhook service saving to a temporary
+// directory, closed when the test ends
+func newTestWebhookService(t *testing.T, dir string) *WebhookService {
+	t.Helper()
+	watchlist := NewWatchlistService(filepath.Join(dir, "watchlist.json"))
+	s := NewWebhookService(filepath.Join(dir, "webhooks.json"), filepath.Join(dir, "webhook_deliveries.json"),
+		watchlist, NewCalendarService(NewTMDBService("test", "US"), watchlist))
+	t.Cleanup(func() { s.Close(context.Background()) })
+	return s
+}
+
+// setRetryDelays shortens webhookRetryDelays for the rest of the test
+func setRetryDelays(t *testing.T, delays ...time.Duration) {
+	t.Helper()
+	saved := webhookRetryDelays
+	webhookRetryDelays = delays
+	t.Cleanup(func() { webhookRetryDelays = saved })
+}
+
+// waitForDeliveries polls the delivery log until it holds n entries
+func waitForDeliveries(t *testing.T, s *WebhookService, n int) []models.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries := s.Deliveries("")
+		if len(deliveries) >= n {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d deliveries logged, want %d", len(deliveries), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	// hmac.new(b"whsec_test", b'1700000000.{"event":"ping"}', sha256).hexdigest()
+	const want = "aa8efe37b751e71157c508c5ac4acb1e9fe5225db98355dfc00f4b680afbc447"
+	if got := SignWebhook("whsec_test", "1700000000", []byte(`{"event":"ping"}`)); got != want {
+		t.Errorf("SignWebhook = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{r.Header, body}
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService(t, t.TempDir())
+	s.SetAllowPrivateNetworks(true)
+	wh, err := s.Create(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delivery, err := s.Test(wh.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !delivery.Success || delivery.StatusCode != http.StatusOK {
+		t.Fatalf("delivery = %+v, want success", delivery)
+	}
+
+	r := <-requests
+	mac := hmac.New(sha256.New, []byte(wh.Secret))
+	mac.Write([]byte(r.header.Get(WebhookTimestampHeader) + "."))
+	mac.Write(r.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := r.header.Get(WebhookSignatureHeader); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+	if event := r.header.Get(WebhookEventHeader); event != EventPing {
+		t.Errorf("event header = %q, want %q", event, EventPing)
+	}
+	if id := r.header.Get(WebhookDeliveryHeader); id != delivery.PayloadID {
+		t.Errorf("delivery header = %q, want %q", id, delivery.PayloadID)
+	}
+}
+
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService(t, t.TempDir())
+	if _, err := s.Create(server.URL, nil); !errors.Is(err, ErrInvalidWebhook) {
+		t.Errorf("Create(%s) error = %v, want ErrInvalidWebhook", server.URL, err)
+	}
+
+	// A name that resolves to a private address passes Create, so the check
+	// that matters is the one on the resolved address when connecting
+	s.SetAllowPrivateNetworks(true)
+	wh, err := s.Create(strings.Replace(server.URL, "127.0.0.1", "localtest.invalid", 1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetAllowPrivateNetworks(false)
+	s.client.Transport.(*http.Transport).DialContext = resolveTo(server.Listener.Addr().String(),
+		s.client.Transport.(*http.Transport).DialContext)
+
+	delivery, err := s.Test(wh.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Success || !strings.Contains(delivery.Error, ErrPrivateDestination.Error()) {
+		t.Errorf("delivery = %+v, want refused as private", delivery)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("server got %d requests, want 0", n)
+	}
+}
+
+// resolveTo makes dial connect to addr whatever the host asked for, as if
+// every name resolved to it
+func resolveTo(addr string, dial func(ctx context.Context, network, address string) (net.Conn, error)) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dial(ctx, network, addr)
+	}
+}
+
+func TestIsPrivateAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		private bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true}, // cloud metadata
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := isPrivateAddr(netip.MustParseAddr(tt.addr)); got != tt.private {
+			t.Errorf("isPrivateAddr(%s) = %v, want %v", tt.addr, got, tt.private)
+		}
+	}
+}
+
+func TestWebhookRetriesAfterServerError(t *testing.T) {
+	setRetryDelays(t, 10*time.Millisecond)
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService(t, t.TempDir())
+	s.SetAllowPrivateNetworks(true)
+	if _, err := s.Create(server.URL, []string{EventWatchlistAdded}); err != nil {
+		t.Fatal(err)
+	}
+	s.Emit(EventWatchlistRemoved, nil) // not subscribed
+	s.Emit(EventWatchlistAdded, map[string]int{"id": 603})
+
+	deliveries := waitForDeliveries(t, s, 2)
+	retry, first := deliveries[0], deliveries[1]
+	if first.Success || first.StatusCode != http.StatusInternalServerError || first.Attempt != 1 || first.NextRetry == nil {
+		t.Errorf("first attempt = %+v, want a failure with a retry", first)
+	}
+	if !retry.Success || retry.Attempt != 2 || retry.NextRetry != nil {
+		t.Errorf("retry = %+v, want a successful second attempt", retry)
+	}
+	if retry.PayloadID != first.PayloadID || retry.Event != EventWatchlistAdded {
+		t.Errorf("retry payload %s (%s), want %s (%s)", retry.PayloadID, retry.Event, first.PayloadID, EventWatchlistAdded)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("server got %d requests, want 2", n)
+	}
+}
+
+func TestWebhookCloseStopsRetries(t *testing.T) {
+	setRetryDelays(t, 50*time.Millisecond)
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	s := newTestWebhookService(t, t.TempDir())
+	s.SetAllowPrivateNetworks(true)
+	if _, err := s.Create(server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	s.Emit(EventWatchlistAdded, nil)
+
+	// The retry is scheduled before the attempt is logged
+	waitForDeliveries(t, s, 1)
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("server got %d requests after Close, want 1", n)
+	}
+	if n := len(s.Deliveries("")); n != 1 {
+		t.Errorf("%d deliveries logged, want 1", n)
+	}
+}
+
+func TestWebhookDeliveryLog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	s := newTestWebhookService(t, dir)
+	s.SetAllowPrivateNetworks(true)
+	first, err := s.Create(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Create(server.URL+"/other", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{first.ID, second.ID, first.ID} {
+		if _, err := s.Test(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Test("missing"); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("Test(missing) error = %v, want ErrWebhookNotFound", err)
+	}
+
+	all := s.Deliveries("")
+	if len(all) != 3 || all[0].WebhookID != first.ID || all[1].WebhookID != second.ID {
+		t.Fatalf("deliveries = %+v, want 3, newest first", all)
+	}
+	if got := s.Deliveries(second.ID); len(got) != 1 || got[0].URL != second.URL {
+		t.Errorf("deliveries of %s = %+v, want 1", second.ID, got)
+	}
+
+	// The log and the webhooks, without their secrets in List, survive a restart
+	s.Close(context.Background())
+	reloaded := newTestWebhookService(t, dir)
+	if got := reloaded.Deliveries(""); len(got) != 3 || got[0].PayloadID != all[0].PayloadID {
+		t.Errorf("reloaded deliveries = %+v, want the saved 3", got)
+	}
+	if list := reloaded.List(); len(list) != 2 || list[0].ID != first.ID || list[0].Secret != "" {
+		t.Errorf("reloaded webhooks = %+v, want 2 without secrets", list)
+	}
+
+	for range webhookLogSize {
+		reloaded.record(all[0])
+	}
+	if n := len(reloaded.Deliveries("")); n != webhookLogSize {
+		t.Errorf("%d deliveries kept, want %d", n, webhookLogSize)
+	}
+}
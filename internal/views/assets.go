@@ -0,0 +1,150 @@
+package views
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// compressibleExts are the asset types worth precompressing
+var compressibleExts = map[string]bool{
+	".css": true, ".js": true, ".svg": true, ".json": true, ".txt": true, ".html": true,
+}
+
+// Assets serves static files under /static/. Each file is also reachable at
+// a fingerprinted name such as css/style.3f2a9c1e5b.css that changes with
+// its content, so those URLs can be cached forever. In dev mode files are
+// read from disk on every request and URLs aren't fingerprinted.
+//
+// Compressible files are sent gzipped to clients that accept it. Brotli
+// variants are not implemented, since the standard library has no brotli
+// encoder; a client asking only for br gets the uncompressed file.
+type Assets struct {
+	fsys    fs.FS
+	dev     bool
+	files   map[string]*asset // by name, e.g. "css/style.css"
+	hashed  map[string]*asset // by fingerprinted name
+	modTime time.Time
+}
+
+type asset struct {
+	name       string
+	hashedName string
+	hash       string
+	data       []byte
+	gzipped    []byte // nil when compression doesn't pay off
+}
+
+// NewAssets fingerprints and precompresses every file in fsys. With dev set,
+// files are served straight from fsys instead.
+func NewAssets(fsys fs.FS, dev bool) (*Assets, error) {
+	a := &Assets{
+		fsys:    fsys,
+		dev:     dev,
+		files:   make(map[string]*asset),
+		hashed:  make(map[string]*asset),
+		modTime: time.Now(),
+	}
+	if dev {
+		return a, nil
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		f := &asset{name: name, hash: hex.EncodeToString(sum[:5]), data: data}
+		ext := path.Ext(name)
+		f.hashedName = strings.TrimSuffix(name, ext) + "." + f.hash + ext
+		if compressibleExts[ext] {
+			f.gzipped = gzipBytes(data)
+		}
+
+		a.files[name] = f
+		a.hashed[f.hashedName] = f
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading static assets: %w", err)
+	}
+	return a, nil
+}
+
+// gzipBytes compresses data, returning nil unless it saves at least a tenth
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	if buf.Len() > len(data)*9/10 {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// URL returns the URL to reference a static file by, e.g. "css/style.css".
+// Unknown files get their plain URL.
+func (a *Assets) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if f, ok := a.files[name]; ok {
+		return "/static/" + f.hashedName
+	}
+	return "/static/" + name
+}
+
+// ServeHTTP serves the file named by the request path after /static/.
+// Fingerprinted names are immutable; plain names must be revalidated.
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/static/")
+
+	if a.dev {
+		w.Header().Set("Cache-Control", "no-cache")
+		http.StripPrefix("/static/", http.FileServer(http.FS(a.fsys))).ServeHTTP(w, r)
+		return
+	}
+
+	f, ok := a.hashed[name]
+	if ok {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else if f, ok = a.files[name]; ok {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, etag := f.data, `"`+f.hash+`"`
+	if f.gzipped != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(r) {
+			data, etag = f.gzipped, `"`+f.hash+`-gz"`
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, f.name, a.modTime, bytes.NewReader(data))
+}
+
+// acceptsGzip reports whether Accept-Encoding lists gzip without q=0
+func acceptsGzip(r *http.Request) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		enc, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.TrimSpace(enc) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package views
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAssetsContentEncoding(t *testing.T) {
+	css := strings.Repeat("body { margin: 0; }\n", 100)
+	assets, err := NewAssets(fstest.MapFS{"css/style.css": {Data: []byte(css)}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"br, gzip, deflate", "gzip"},
+		{"gzip;q=0", ""},
+		{"br", ""}, // brotli isn't offered
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, assets.URL("css/style.css"), nil)
+		if tt.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		assets.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Accept-Encoding %q: status %d", tt.acceptEncoding, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != tt.want {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q, want %q", tt.acceptEncoding, got, tt.want)
+		}
+		if tt.want == "" && rec.Body.String() != css {
+			t.Errorf("Accept-Encoding %q: body isn't the original file", tt.acceptEncoding)
+		}
+		if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: Vary = %q", tt.acceptEncoding, vary)
+		}
+	}
+}
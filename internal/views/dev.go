@@ -0,0 +1,165 @@
+package views
+
+import (
+	"fmt"
+	"html"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// templateErrorLocation finds the file and line in text/template errors such as
+// `template: movie_details.html:25:14: executing "movie-details-content" at <.Foo>: ...`
+var templateErrorLocation = regexp.MustCompile(`template: ([^:\s]+):(\d+)(?::\d+)?:`)
+
+// errorContextLines is how many source lines are shown around an error
+const errorContextLines = 4
+
+// devTemplates re-parses templates whenever a file changes, checking
+// modification times on each request, and renders errors as a page
+type devTemplates struct {
+	fsys     fs.FS
+	assets   *Assets
+	patterns []string
+
+	mu    sync.Mutex
+	stamp string
+	tpl   *template.Template
+	err   error
+}
+
+// ParseDev is ParseFS for development: templates are read from fsys, usually
+// the web directory on disk, and re-parsed when they change. Parse and
+// execution errors are shown in the browser with the file and line.
+func ParseDev(fsys fs.FS, assets *Assets, patterns ...string) (Template, error) {
+	d := &devTemplates{fsys: fsys, assets: assets, patterns: patterns}
+	if _, err := d.current(); err != nil {
+		// Still start, so the error can be fixed with the server running
+		log.Printf("Template error: %v", err)
+	}
+	return Template{dev: d}, nil
+}
+
+// current returns the templates, re-parsing them if any file changed
+func (d *devTemplates) current() (*template.Template, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stamp, err := d.modStamp()
+	if err != nil {
+		return nil, err
+	}
+	if stamp != d.stamp {
+		if d.stamp != "" {
+			log.Printf("Templates changed, reloading")
+		}
+		d.tpl, d.err = parse(d.fsys, d.assets, d.patterns)
+		d.stamp = stamp
+	}
+	return d.tpl, d.err
+}
+
+// modStamp summarizes the names, sizes and modification times of every template file
+func (d *devTemplates) modStamp() (string, error) {
+	var b strings.Builder
+	for _, pattern := range d.patterns {
+		names, err := fs.Glob(d.fsys, pattern)
+		if err != nil {
+			return "", err
+		}
+		for _, name := range names {
+			info, err := fs.Stat(d.fsys, name)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, "%s:%d:%d;", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return b.String(), nil
+}
+
+// execute renders like Template.Execute, but shows a failure as the error
+// page so it can be fixed from the browser
+func (d *devTemplates) execute(w http.ResponseWriter, status int, name string, data interface{}) {
+	tpl, err := d.current()
+	if err == nil {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err = tpl.ExecuteTemplate(buf, name, data); err == nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(status)
+			buf.WriteTo(w)
+			return
+		}
+	}
+
+	log.Printf("failed to execute template %s: %v", name, err)
+	d.writeErrorPage(w, err)
+}
+
+// writeErrorPage shows a template error with the source around the failing line
+func (d *devTemplates) writeErrorPage(w http.ResponseWriter, err error) {
+	var source strings.Builder
+	location := "unknown location"
+	if m := templateErrorLocation.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[2])
+		file := d.findFile(m[1])
+		location = fmt.Sprintf("%s, line %d", file, line)
+		if data, readErr := fs.ReadFile(d.fsys, file); readErr == nil {
+			lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+			for i := max(1, line-errorContextLines); i <= min(len(lines), line+errorContextLines); i++ {
+				class := ""
+				if i == line {
+					class = ` class="error-line"`
+				}
+				fmt.Fprintf(&source, "<span%s>%4d  %s</span>\n", class, i, html.EscapeString(lines[i-1]))
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<title>Template error</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #1f2937; }
+h1 { color: #b91c1c; font-size: 1.5rem; }
+.message { background: #fef2f2; border-left: 4px solid #b91c1c; padding: 1rem; white-space: pre-wrap; font-family: monospace; }
+pre { background: #111827; color: #e5e7eb; padding: 1rem; border-radius: 0.5rem; overflow-x: auto; }
+pre span { display: block; }
+.error-line { background: #7f1d1d; }
+</style>
+</head>
+<body>
+<h1>Template error</h1>
+<p>%s</p>
+<div class="message">%s</div>
+<pre>%s</pre>
+<p>Fix the template and reload; this page is only shown in dev mode.</p>
+</body>
+</html>
+`, html.EscapeString(location), html.EscapeString(err.Error()), source.String())
+}
+
+// findFile maps a template name, which text/template takes from the file's
+// base name, back to its path in fsys
+func (d *devTemplates) findFile(name string) string {
+	for _, pattern := range d.patterns {
+		names, _ := fs.Glob(d.fsys, pattern)
+		for _, n := range names {
+			if path.Base(n) == name {
+				return n
+			}
+		}
+	}
+	return name
+}
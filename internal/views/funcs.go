@@ -0,0 +1,332 @@
+package views
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// placeholderImage is shown for titles and people without an image
+const placeholderImage = "/static/images/placeholder.jpg"
+
+// dateLayout is how TMDB writes dates such as release_date
+const dateLayout = "2006-01-02"
+
+// now is replaced in tests
+var now = time.Now
+
+// helperFuncs format media fields for templates. Numeric helpers accept any
+// integer or float, or a pointer to one; date helpers accept TMDB's
+// "2006-01-02" strings or a time.Time.
+var helperFuncs = template.FuncMap{
+	"image":         imageURL,
+	"runtime":       formatRuntime,
+	"currency":      formatCurrency,
+	"date":          formatDate,
+	"localDate":     formatLocalDate,
+	"relativeDate":  formatRelativeDate,
+	"year":          formatYear,
+	"stars":         formatStars,
+	"languageName":  languageName,
+	"truncateWords": truncateWords,
+	"plural":        plural,
+}
+
+// imageURL returns the image proxy URL for a TMDB image path at a size such
+// as "w500", or the placeholder when there is no image.
+// Usage: {{image "w500" .PosterPath}}
+func imageURL(size string, path interface{}) string {
+	var p string
+	switch v := path.(type) {
+	case string:
+		p = v
+	case *string:
+		if v != nil {
+			p = *v
+		}
+	}
+	if p == "" {
+		return placeholderImage
+	}
+	return "/img/" + size + p
+}
+
+// formatRuntime turns minutes into "2h 14m", "2h" or "45m"; zero is ""
+func formatRuntime(minutes interface{}) string {
+	f, ok := toFloat(minutes)
+	if !ok || f <= 0 {
+		return ""
+	}
+	m := int(f)
+	switch {
+	case m < 60:
+		return fmt.Sprintf("%dm", m)
+	case m%60 == 0:
+		return fmt.Sprintf("%dh", m/60)
+	}
+	return fmt.Sprintf("%dh %dm", m/60, m%60)
+}
+
+// formatCurrency writes a US dollar amount with thousands separators, e.g.
+// "$150,000,000". TMDB reports budgets and revenue in whole dollars.
+func formatCurrency(amount interface{}) string {
+	f, ok := toFloat(amount)
+	if !ok {
+		return ""
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	digits := strconv.FormatInt(int64(math.Round(f)), 10)
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + "$" + b.String()
+}
+
+// formatDate writes a date as "May 1, 2024". Strings that aren't dates are
+// returned unchanged.
+func formatDate(date interface{}) string {
+	return formatLocalDate("en", date)
+}
+
+// dateFormat spells out dates in one language
+type dateFormat struct {
+	months [12]string
+	format func(day int, month string, year int) string
+}
+
+// dateFormats by ISO 639-1 code; other languages use English
+var dateFormats = map[string]dateFormat{
+	"en": {
+		[12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		func(d int, m string, y int) string { return fmt.Sprintf("%.3s %d, %d", m, d, y) },
+	},
+	"de": {
+		[12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		func(d int, m string, y int) string { return fmt.Sprintf("%d. %s %d", d, m, y) },
+	},
+	"fr": {
+		[12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		func(d int, m string, y int) string { return fmt.Sprintf("%d %s %d", d, m, y) },
+	},
+	"es": {
+		[12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		func(d int, m string, y int) string { return fmt.Sprintf("%d de %s de %d", d, m, y) },
+	},
+	"pt": {
+		[12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		func(d int, m string, y int) string { return fmt.Sprintf("%d de %s de %d", d, m, y) },
+	},
+	"it": {
+		[12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		func(d int, m string, y int) string { return fmt.Sprintf("%d %s %d", d, m, y) },
+	},
+	"nl": {
+		[12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		func(d int, m string, y int) string { return fmt.Sprintf("%d %s %d", d, m, y) },
+	},
+}
+
+// formatLocalDate writes a date the way a language does, e.g. "1. Mai 2024"
+// for "de". Usage: {{.ReleaseDate | localDate "de"}}
+func formatLocalDate(lang string, date interface{}) string {
+	t, ok := toTime(date)
+	if !ok {
+		return fallbackString(date)
+	}
+	f, ok := dateFormats[strings.ToLower(lang)]
+	if !ok {
+		f = dateFormats["en"]
+	}
+	return f.format(t.Day(), f.months[t.Month()-1], t.Year())
+}
+
+// formatRelativeDate describes a date relative to today: "today",
+// "tomorrow", "in 3 days", "2 weeks ago", "in 4 months" or "1 year ago"
+func formatRelativeDate(date interface{}) string {
+	t, ok := toTime(date)
+	if !ok {
+		return fallbackString(date)
+	}
+	today := now()
+	y, m, d := today.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = t.Date()
+	days := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(start).Hours() / 24)
+
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "tomorrow"
+	case -1:
+		return "yesterday"
+	}
+
+	abs := days
+	if abs < 0 {
+		abs = -abs
+	}
+	var amount string
+	switch {
+	case abs < 14:
+		amount = plural(abs, "day")
+	case abs < 60:
+		amount = plural(abs/7, "week")
+	case abs < 365:
+		amount = plural(abs/30, "month")
+	default:
+		amount = plural(abs/365, "year")
+	}
+	if days > 0 {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
+
+// formatYear returns a date's year, or "" when there is no date
+func formatYear(date interface{}) string {
+	t, ok := toTime(date)
+	if !ok {
+		return ""
+	}
+	return strconv.Itoa(t.Year())
+}
+
+// formatStars turns a 0-10 rating into up to five stars, rounded to the
+// nearest half: 7.3 becomes "★★★½"
+func formatStars(rating interface{}) string {
+	f, ok := toFloat(rating)
+	if !ok || f <= 0 {
+		return ""
+	}
+	halves := int(math.Round(math.Min(f, 10)))
+	s := strings.Repeat("★", halves/2)
+	if halves%2 == 1 {
+		s += "½"
+	}
+	return s
+}
+
+// languageNames maps ISO 639-1 codes, as TMDB gives for original_language,
+// to English names
+var languageNames = map[string]string{
+	"ar": "Arabic", "bn": "Bengali", "ca": "Catalan", "cn": "Cantonese",
+	"cs": "Czech", "da": "Danish", "de": "German", "el": "Greek",
+	"en": "English", "es": "Spanish", "et": "Estonian", "fa": "Persian",
+	"fi": "Finnish", "fr": "French", "he": "Hebrew", "hi": "Hindi",
+	"hr": "Croatian", "hu": "Hungarian", "id": "Indonesian", "is": "Icelandic",
+	"it": "Italian", "ja": "Japanese", "kn": "Kannada", "ko": "Korean",
+	"la": "Latin", "lt": "Lithuanian", "lv": "Latvian", "ml": "Malayalam",
+	"mr": "Marathi", "ms": "Malay", "nb": "Norwegian Bokmål", "nl": "Dutch",
+	"no": "Norwegian", "pa": "Punjabi", "pl": "Polish", "pt": "Portuguese",
+	"ro": "Romanian", "ru": "Russian", "sk": "Slovak", "sl": "Slovenian",
+	"sr": "Serbian", "sv": "Swedish", "ta": "Tamil", "te": "Telugu",
+	"th": "Thai", "tl": "Tagalog", "tr": "Turkish", "uk": "Ukrainian",
+	"ur": "Urdu", "vi": "Vietnamese", "xx": "No Language", "zh": "Mandarin",
+}
+
+// languageName names a language code, e.g. "ja" is "Japanese". Unknown
+// codes are returned in upper case.
+func languageName(code string) string {
+	if name, ok := languageNames[strings.ToLower(code)]; ok {
+		return name
+	}
+	return strings.ToUpper(code)
+}
+
+// truncateWords shortens text to at most n words, ending in "…" when
+// anything was cut. Usage: {{.Overview | truncateWords 30}}
+func truncateWords(n int, text string) string {
+	words := strings.Fields(text)
+	if len(words) <= n {
+		return text
+	}
+	if n <= 0 {
+		return ""
+	}
+	return strings.TrimRight(strings.Join(words[:n], " "), ",;:.-–—") + "…"
+}
+
+// plural writes a count with its noun: "1 season", "3 seasons". The plural
+// form defaults to the singular plus "s".
+// Usage: {{plural .NumberOfSeasons "season"}} or {{plural .Count "person" "people"}}
+func plural(n int, singular string, pluralForm ...string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	word := singular + "s"
+	if len(pluralForm) > 0 {
+		word = pluralForm[0]
+	}
+	return fmt.Sprintf("%d %s", n, word)
+}
+
+// toFloat reads any integer or float, or a non-nil pointer to one
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return 0, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// toTime reads a "2006-01-02" string, a time.Time or a pointer to either.
+// Zero times and empty strings aren't dates.
+func toTime(v interface{}) (time.Time, bool) {
+	switch d := v.(type) {
+	case string:
+		if len(d) > len(dateLayout) {
+			// Full timestamps such as "2024-05-01T00:00:00.000Z"
+			d = d[:len(dateLayout)]
+		}
+		t, err := time.Parse(dateLayout, d)
+		return t, err == nil
+	case *string:
+		if d != nil {
+			return toTime(*d)
+		}
+	case time.Time:
+		return d, !d.IsZero()
+	case *time.Time:
+		if d != nil {
+			return toTime(*d)
+		}
+	}
+	return time.Time{}, false
+}
+
+// fallbackString shows a value a date helper couldn't parse as it is
+func fallbackString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case *string:
+		if s != nil {
+			return *s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+package views
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestImageURL(t *testing.T) {
+	path := "/abc.jpg"
+	empty := ""
+	tests := []struct {
+		size string
+		path interface{}
+		want string
+	}{
+		{"w500", "/abc.jpg", "/img/w500/abc.jpg"},
+		{"original", &path, "/img/original/abc.jpg"},
+		{"w185", "", placeholderImage},
+		{"w185", &empty, placeholderImage},
+		{"w185", (*string)(nil), placeholderImage},
+		{"w185", nil, placeholderImage},
+	}
+	for _, tt := range tests {
+		if got := imageURL(tt.size, tt.path); got != tt.want {
+			t.Errorf("imageURL(%q, %v) = %q, want %q", tt.size, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRuntime(t *testing.T) {
+	minutes := 95
+	tests := []struct {
+		minutes interface{}
+		want    string
+	}{
+		{134, "2h 14m"},
+		{120, "2h"},
+		{45, "45m"},
+		{&minutes, "1h 35m"},
+		{0, ""},
+		{(*int)(nil), ""},
+		{"90", ""},
+	}
+	for _, tt := range tests {
+		if got := formatRuntime(tt.minutes); got != tt.want {
+			t.Errorf("formatRuntime(%v) = %q, want %q", tt.minutes, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		amount interface{}
+		want   string
+	}{
+		{150000000, "$150,000,000"},
+		{int64(2923706026), "$2,923,706,026"},
+		{999, "$999"},
+		{1000, "$1,000"},
+		{0, "$0"},
+		{-4500, "-$4,500"},
+		{1234.56, "$1,235"},
+		{"lots", ""},
+	}
+	for _, tt := range tests {
+		if got := formatCurrency(tt.amount); got != tt.want {
+			t.Errorf("formatCurrency(%v) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		date interface{}
+		want string
+	}{
+		{"2024-05-01", "May 1, 2024"},
+		{"2019-09-21T00:00:00.000Z", "Sep 21, 2019"},
+		{time.Date(2010, time.July, 16, 0, 0, 0, 0, time.UTC), "Jul 16, 2010"},
+		{"", ""},
+		{"TBA", "TBA"},
+		{time.Time{}, ""},
+	}
+	for _, tt := range tests {
+		if got := formatDate(tt.date); got != tt.want {
+			t.Errorf("formatDate(%v) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestFormatLocalDate(t *testing.T) {
+	tests := []struct {
+		lang string
+		want string
+	}{
+		{"en", "Mar 4, 2024"},
+		{"de", "4. März 2024"},
+		{"fr", "4 mars 2024"},
+		{"es", "4 de marzo de 2024"},
+		{"pt", "4 de março de 2024"},
+		{"it", "4 marzo 2024"},
+		{"nl", "4 maart 2024"},
+		{"DE", "4. März 2024"},
+		{"ja", "Mar 4, 2024"},
+	}
+	for _, tt := range tests {
+		if got := formatLocalDate(tt.lang, "2024-03-04"); got != tt.want {
+			t.Errorf("formatLocalDate(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRelativeDate(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2024, time.May, 1, 22, 30, 0, 0, time.UTC) }
+
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2024-05-01", "today"},
+		{"2024-05-02", "tomorrow"},
+		{"2024-04-30", "yesterday"},
+		{"2024-05-04", "in 3 days"},
+		{"2024-04-21", "10 days ago"},
+		{"2024-05-22", "in 3 weeks"},
+		{"2024-08-01", "in 3 months"},
+		{"2024-03-01", "2 months ago"},
+		{"2023-04-30", "1 year ago"},
+		{"2027-06-01", "in 3 years"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := formatRelativeDate(tt.date); got != tt.want {
+			t.Errorf("formatRelativeDate(%q) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestFormatYear(t *testing.T) {
+	tests := []struct {
+		date interface{}
+		want string
+	}{
+		{"2024-05-01", "2024"},
+		{time.Date(1999, time.March, 31, 0, 0, 0, 0, time.UTC), "1999"},
+		{"", ""},
+		{"soon", ""},
+	}
+	for _, tt := range tests {
+		if got := formatYear(tt.date); got != tt.want {
+			t.Errorf("formatYear(%v) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestFormatStars(t *testing.T) {
+	rating := 8.0
+	tests := []struct {
+		rating interface{}
+		want   string
+	}{
+		{7.3, "★★★½"},
+		{10.0, "★★★★★"},
+		{12.0, "★★★★★"},
+		{6.0, "★★★"},
+		{0.8, "½"},
+		{&rating, "★★★★"},
+		{0.0, ""},
+		{(*float64)(nil), ""},
+	}
+	for _, tt := range tests {
+		if got := formatStars(tt.rating); got != tt.want {
+			t.Errorf("formatStars(%v) = %q, want %q", tt.rating, got, tt.want)
+		}
+	}
+}
+
+func TestLanguageName(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"en", "English"},
+		{"ja", "Japanese"},
+		{"KO", "Korean"},
+		{"xx", "No Language"},
+		{"qq", "QQ"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := languageName(tt.code); got != tt.want {
+			t.Errorf("languageName(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateWords(t *testing.T) {
+	tests := []struct {
+		n    int
+		text string
+		want string
+	}{
+		{3, "A hobbit sets out  on a quest", "A hobbit sets…"},
+		{4, "Short   text stays   as is", "Short text stays as…"},
+		{10, "Short   text stays   as is", "Short   text stays   as is"},
+		{2, "Earth, wind and fire", "Earth, wind…"},
+		{1, "Earth, wind and fire", "Earth…"},
+		{0, "Anything", ""},
+		{5, "", ""},
+	}
+	for _, tt := range tests {
+		if got := truncateWords(tt.n, tt.text); got != tt.want {
+			t.Errorf("truncateWords(%d, %q) = %q, want %q", tt.n, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		n     int
+		forms []string
+		want  string
+	}{
+		{1, []string{"season"}, "1 season"},
+		{3, []string{"season"}, "3 seasons"},
+		{0, []string{"episode"}, "0 episodes"},
+		{1, []string{"person", "people"}, "1 person"},
+		{4, []string{"person", "people"}, "4 people"},
+	}
+	for _, tt := range tests {
+		if got := plural(tt.n, tt.forms[0], tt.forms[1:]...); got != tt.want {
+			t.Errorf("plural(%d, %v) = %q, want %q", tt.n, tt.forms, got, tt.want)
+		}
+	}
+}
+
+// TestHelperFuncsInTemplates checks the helpers are registered and work with
+// template argument order, including pipelines
+func TestHelperFuncsInTemplates(t *testing.T) {
+	tpl := template.Must(template.New("t").Funcs(helperFuncs).Parse(
+		`{{image "w92" .Poster}} {{runtime .Runtime}} {{currency .Budget}} {{.Date | localDate "de"}} ` +
+			`{{.Overview | truncateWords 2}} {{plural .Seasons "season"}} {{languageName .Lang}} {{stars .Vote}}`))
+
+	var b strings.Builder
+	err := tpl.Execute(&b, map[string]interface{}{
+		"Poster": "/p.jpg", "Runtime": 61, "Budget": 2500000, "Date": "2024-12-24",
+		"Overview": "One two three", "Seasons": 2, "Lang": "fr", "Vote": 9.1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/img/w92/p.jpg 1h 1m $2,500,000 24. Dezember 2024 One two… 2 seasons French ★★★★½"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
@@ -1,28 +1,61 @@
 package views
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
-	"log"
 	"net/http"
 	"reflect"
+	"sync"
 	"text/template"
 )
 
 type Template struct {
 	textTpl *template.Template
+	dev     *devTemplates // set in dev mode, replacing textTpl
 }
 
-func (t Template) Execute(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	err := t.textTpl.ExecuteTemplate(w, name, data)
-	if err != nil {
-		log.Printf("failed to execute template %s: %v", name, err)
-		http.Error(w, "Failed to execute template", http.StatusInternalServerError)
-		return
+// bufferPool holds the buffers pages are rendered into
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// maxPooledBuffer keeps an unusually large page from pinning its buffer
+const maxPooledBuffer = 1 << 20
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(buf)
 	}
 }
 
+// Execute renders the named template with the given status. The page is
+// rendered into a buffer first, so when a template fails nothing has been
+// written and the caller can still send an error page instead.
+func (t Template) Execute(w http.ResponseWriter, r *http.Request, status int, name string, data interface{}) error {
+	if t.dev != nil {
+		t.dev.execute(w, status, name, data)
+		return nil
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := t.textTpl.ExecuteTemplate(buf, name, data); err != nil {
+		return fmt.Errorf("executing template %s: %w", name, err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+	return nil
+}
+
 func Must(t Template, err error) Template {
 	if err != nil {
 		panic(err)
@@ -30,11 +63,30 @@ func Must(t Template, err error) Template {
 	return t
 }
 
-func ParseFS(fs fs.FS, patterns ...string) (Template, error) {
+// ParseFS parses the templates matching patterns; assets backs the "asset"
+// function, which returns a static file's fingerprinted URL
+func ParseFS(fs fs.FS, assets *Assets, patterns ...string) (Template, error) {
+	tpl, err := parse(fs, assets, patterns)
+	if err != nil {
+		return Template{}, err
+	}
+	return Template{
+		textTpl: tpl,
+	}, nil
+}
+
+func parse(fs fs.FS, assets *Assets, patterns []string) (*template.Template, error) {
 	// Create template functions
 	funcMap := template.FuncMap{
+		"asset": assets.URL,
 		"add": func(a, b int) int { return a + b },
 		"sub": func(a, b int) int { return a - b },
+		"deref": func(f *float64) float64 {
+			if f == nil {
+				return 0
+			}
+			return *f
+		},
 		"seq": func(start, end int) []int {
 			var result []int
 			if start > end {
@@ -70,11 +122,9 @@ func ParseFS(fs fs.FS, patterns ...string) (Template, error) {
 		},
 	}
 
-	tpl, err := template.New("").Funcs(funcMap).ParseFS(fs, patterns...)
+	tpl, err := template.New("").Funcs(funcMap).Funcs(helperFuncs).ParseFS(fs, patterns...)
 	if err != nil {
-		return Template{}, fmt.Errorf("parsing template: %w", err)
+		return nil, fmt.Errorf("parsing template: %w", err)
 	}
-	return Template{
-		textTpl: tpl,
-	}, nil
+	return tpl, nil
 }
@@ -4,3 +4,6 @@ import "embed"
 
 //go:embed templates
 var Templates embed.FS
+
+//go:embed static
+var Static embed.FS
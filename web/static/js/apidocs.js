@@ -0,0 +1,98 @@
+// Renders the OpenAPI document served at /api/openapi.json as a browsable page
+
+function escapeHTML(value) {
+    return String(value)
+        .replace(/&/g, '&amp;')
+        .replace(/</g, '&lt;')
+        .replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;');
+}
+
+function schemaName(schema) {
+    if (!schema) {
+        return '';
+    }
+    if (schema.$ref) {
+        return schema.$ref.split('/').pop();
+    }
+    if (schema.type === 'array') {
+        return schemaName(schema.items) + '[]';
+    }
+    if (schema.oneOf) {
+        return schema.oneOf.map(schemaName).join(' | ');
+    }
+    if (schema.allOf) {
+        return schema.allOf.map(schemaName).join(' & ');
+    }
+    if (schema.type === 'object' && schema.properties) {
+        const fields = Object.entries(schema.properties)
+            .map(([name, prop]) => `${name}: ${schemaName(prop)}`);
+        return `{ ${fields.join(', ')} }`;
+    }
+    return schema.enum ? schema.enum.join(' | ') : (schema.type || 'any');
+}
+
+function renderOperation(path, method, op) {
+    const params = (op.parameters || []).map(param => `
+        <tr>
+            <td><code>${escapeHTML(param.name)}</code></td>
+            <td>${escapeHTML(param.in)}</td>
+            <td>${escapeHTML(schemaName(param.schema))}</td>
+            <td>${param.required ? 'yes' : ''}</td>
+            <td>${escapeHTML(param.description || '')}</td>
+        </tr>`).join('');
+
+    const responses = Object.entries(op.responses || {}).map(([status, resp]) => {
+        const content = resp.content ? Object.values(resp.content)[0] : null;
+        return `
+        <tr>
+            <td><code>${escapeHTML(status)}</code></td>
+            <td>${escapeHTML(resp.description || '')}</td>
+            <td>${content ? escapeHTML(schemaName(content.schema)) : ''}</td>
+        </tr>`;
+    }).join('');
+
+    let body = '';
+    if (op.requestBody) {
+        const content = Object.values(op.requestBody.content)[0];
+        body = `<p><strong>Body:</strong> <code>${escapeHTML(schemaName(content.schema))}</code></p>`;
+    }
+
+    return `
+        <details class="api-operation">
+            <summary>
+                <span class="api-method api-method-${method}">${method.toUpperCase()}</span>
+                <code>${escapeHTML(path)}</code>
+                <span class="api-summary">${escapeHTML(op.summary || '')}</span>
+            </summary>
+            ${params ? `<table class="api-table"><tr><th>Parameter</th><th>In</th><th>Type</th><th>Required</th><th></th></tr>${params}</table>` : ''}
+            ${body}
+            <table class="api-table"><tr><th>Status</th><th>Description</th><th>Body</th></tr>${responses}</table>
+        </details>`;
+}
+
+function renderSpec(spec) {
+    const container = document.getElementById('apiDocs');
+    const groups = {};
+
+    Object.entries(spec.paths).forEach(([path, ops]) => {
+        Object.entries(ops).forEach(([method, op]) => {
+            const tag = (op.tags && op.tags[0]) || 'other';
+            (groups[tag] = groups[tag] || []).push(renderOperation(path, method, op));
+        });
+    });
+
+    let html = `<p>${escapeHTML(spec.info.description || '')}</p>`;
+    Object.entries(groups).forEach(([tag, ops]) => {
+        html += `<section class="api-group"><h2>${escapeHTML(tag)}</h2>${ops.join('')}</section>`;
+    });
+    container.innerHTML = html;
+}
+
+fetch('/api/openapi.json')
+    .then(response => response.json())
+    .then(renderSpec)
+    .catch(error => {
+        console.error('Error:', error);
+        document.getElementById('apiDocs').innerHTML = '<div class="error-message"><p>Failed to load the API specification</p></div>';
+    });
@@ -71,89 +71,282 @@ function handleTrailerEscape(event) {
     }
 }
 
+// Partial requests get server-rendered HTML for one block of a page
+// instead of JSON or the whole page
+function fetchPartial(url, options = {}) {
+    const headers = Object.assign({ 'HX-Request': 'true' }, options.headers);
+    return fetch(url, Object.assign({}, options, { headers }))
+        .then(response => {
+            if (!response.ok) {
+                throw new Error(`Request failed with status ${response.status}`);
+            }
+            const count = response.headers.get('X-Watchlist-Count');
+            if (count !== null) {
+                setWatchlistBadge(parseInt(count, 10));
+            }
+            return response.text();
+        });
+}
+
+function parseFragment(html) {
+    const template = document.createElement('template');
+    template.innerHTML = html.trim();
+    return template.content;
+}
+
 // Watchlist functionality
 function addToWatchlist(id, type, title, posterPath, releaseDate, voteAverage, buttonElement) {
     const item = {
         id: id,
         type: type,
         title: title,
-        poster_path: posterPath,
+        poster_path: posterPath || null,
         release_date: releaseDate,
         vote_average: voteAverage
     };
 
-    fetch('/api/watchlist', {
+    fetchPartial('/api/watchlist', {
         method: 'POST',
         headers: {
             'Content-Type': 'application/json',
         },
         body: JSON.stringify(item)
     })
+    .then(html => {
+        showNotification('Added to watchlist!', 'success');
+        // Swap in the server-rendered remove button
+        const actions = buttonElement && buttonElement.closest('.watchlist-actions');
+        if (actions) {
+            actions.replaceWith(parseFragment(html));
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        showNotification('Failed to add to watchlist', 'error');
+    });
+}
+
+function addCollectionToWatchlist(collectionId, buttonElement) {
+    fetch(`/api/collections/${collectionId}/watchlist`, {
+        method: 'POST'
+    })
     .then(response => response.json())
     .then(data => {
         if (data.status === 'success') {
-            showNotification('Added to watchlist!', 'success');
-            // Update button if provided
+            showNotification(`Added ${data.added} movie${data.added === 1 ? '' : 's'} to watchlist!`, 'success');
             if (buttonElement) {
-                buttonElement.textContent = 'Remove from Watchlist';
+                buttonElement.textContent = 'All in Watchlist';
                 buttonElement.className = 'btn btn-secondary';
-                buttonElement.onclick = () => removeFromWatchlist(id, type, buttonElement);
+                buttonElement.disabled = true;
             }
-            
-            // Update watchlist count
-            updateWatchlistCount();
+            setWatchlistBadge(data.count);
+
+            // Every part is in the watchlist now; watched ones keep their badge
+            document.querySelectorAll('.collection-part .media-poster').forEach(poster => {
+                if (!poster.querySelector('.watched-badge')) {
+                    const badge = document.createElement('div');
+                    badge.className = 'watched-badge in-watchlist';
+                    badge.textContent = 'In Watchlist';
+                    poster.appendChild(badge);
+                }
+            });
         }
     })
     .catch(error => {
         console.error('Error:', error);
-        showNotification('Failed to add to watchlist', 'error');
+        showNotification('Failed to add collection to watchlist', 'error');
     });
 }
 
 function removeFromWatchlist(id, type, buttonElement) {
-    fetch(`/api/watchlist/${id}?type=${type}`, {
+    fetchPartial(`/api/watchlist/${id}?type=${type}`, {
         method: 'DELETE'
     })
+    .then(html => {
+        showNotification('Removed from watchlist!', 'success');
+
+        // On the watchlist page the card goes; elsewhere the add button comes back
+        const card = buttonElement && buttonElement.closest('.watchlist-item');
+        const actions = buttonElement && buttonElement.closest('.watchlist-actions');
+        if (card) {
+            card.remove();
+        } else if (actions) {
+            actions.replaceWith(parseFragment(html));
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        showNotification('Failed to remove from watchlist', 'error');
+    });
+}
+
+function toggleWatched(id, type, buttonElement) {
+    fetchPartial(`/api/watchlist/${id}/toggle?type=${type}`, {
+        method: 'PUT'
+    })
+    .then(html => {
+        showNotification('Updated watch status!', 'success');
+        // Replace the card with the server-rendered one
+        const card = buttonElement && buttonElement.closest('.watchlist-item');
+        if (card) {
+            card.replaceWith(parseFragment(html));
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        showNotification('Failed to update watch status', 'error');
+    });
+}
+
+function setRating(id, type, value) {
+    const rating = value === '' ? null : parseFloat(value);
+
+    fetch(`/api/watchlist/${id}/rating?type=${type}`, {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ rating: rating })
+    })
     .then(response => response.json())
     .then(data => {
         if (data.status === 'success') {
-            showNotification('Removed from watchlist!', 'success');
-            
-            // If on watchlist page, remove the item
-            if (window.location.pathname === '/watchlist') {
-                location.reload();
-            } else if (buttonElement) {
-                // Update button
-                buttonElement.textContent = 'Add to Watchlist';
-                buttonElement.className = 'btn btn-primary';
-                buttonElement.onclick = () => addToWatchlist(id, type, '', '', '', 0, buttonElement);
-            }
-            
-            // Update watchlist count
-            updateWatchlistCount();
+            showNotification('Rating saved!', 'success');
         }
     })
     .catch(error => {
         console.error('Error:', error);
-        showNotification('Failed to remove from watchlist', 'error');
+        showNotification('Failed to save rating', 'error');
     });
 }
 
-function toggleWatched(id, type) {
-    fetch(`/api/watchlist/${id}/toggle?type=${type}`, {
-        method: 'PUT'
+function setTags(id, type, value) {
+    const tags = value.split(',').map(tag => tag.trim()).filter(Boolean);
+
+    fetch(`/api/watchlist/${id}/tags?type=${type}`, {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ tags: tags })
     })
     .then(response => response.json())
     .then(data => {
         if (data.status === 'success') {
-            showNotification('Updated watch status!', 'success');
-            // Reload the page to reflect changes
-            location.reload();
+            showNotification('Tags saved!', 'success');
         }
     })
     .catch(error => {
         console.error('Error:', error);
-        showNotification('Failed to update watch status', 'error');
+        showNotification('Failed to save tags', 'error');
+    });
+}
+
+function setWatchlistPoster(id, type, posterPath, buttonElement) {
+    fetch(`/api/watchlist/${id}/poster?type=${type}`, {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ poster_path: posterPath })
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.status === 'success') {
+            showNotification(posterPath ? 'Watchlist poster updated!' : 'Default poster restored!', 'success');
+            if (buttonElement) {
+                buttonElement.disabled = true;
+            }
+
+            // Reload to mark the selected poster
+            updateWatchlistCount();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        showNotification('Failed to update poster', 'error');
+    });
+}
+
+// Webhooks
+function createWebhook(form) {
+    const events = Array.from(form.querySelectorAll('input[name="events"]:checked'), input => input.value);
+
+    fetch('/api/v1/webhooks', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ url: form.elements.url.value, events: events })
+    })
+    .then(response => response.json())
+    .then(body => {
+        if (body.error) {
+            showNotification(body.error.message, 'error');
+            return;
+        }
+        showNotification('Webhook added!', 'success');
+        // The secret is only returned now, so show it before reloading
+        form.hidden = true;
+        const secret = document.querySelector('.webhook-secret');
+        secret.querySelector('code').textContent = body.data.secret;
+        secret.hidden = false;
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        showNotification('Failed to add webhook', 'error');
+    });
+}
+
+function testWebhook(id, buttonElement) {
+    buttonElement.disabled = true;
+
+    fetch(`/api/v1/webhooks/${id}/test`, {
+        method: 'POST'
+    })
+    .then(response => response.json())
+    .then(body => {
+        if (body.error) {
+            showNotification(body.error.message, 'error');
+            return;
+        }
+        const delivery = body.data;
+        if (delivery.success) {
+            showNotification(`Test delivered (${delivery.status_code})`, 'success');
+        } else {
+            showNotification(`Test failed: ${delivery.error || 'status ' + delivery.status_code}`, 'error');
+        }
+
+        // Reload to show the delivery in the log
+        updateWatchlistCount();
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        showNotification('Failed to send test delivery', 'error');
+    })
+    .finally(() => {
+        buttonElement.disabled = false;
+    });
+}
+
+function deleteWebhook(id, buttonElement) {
+    if (!confirm('Delete this webhook?')) return;
+
+    fetch(`/api/v1/webhooks/${id}`, {
+        method: 'DELETE'
+    })
+    .then(response => {
+        if (!response.ok) {
+            throw new Error(`Request failed with status ${response.status}`);
+        }
+        showNotification('Webhook deleted', 'success');
+        buttonElement.closest('.webhook').remove();
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        showNotification('Failed to delete webhook', 'error');
     });
 }
 
@@ -165,6 +358,148 @@ function updateWatchlistCount() {
     }, 1000);
 }
 
+// setWatchlistBadge shows the watchlist size next to the nav link
+function setWatchlistBadge(count) {
+    document.querySelectorAll('.nav-link[href="/watchlist"]').forEach(link => {
+        let badge = link.querySelector('.badge');
+        if (count > 0) {
+            if (!badge) {
+                badge = document.createElement('span');
+                badge.className = 'badge';
+                link.appendChild(badge);
+            }
+            badge.textContent = count;
+        } else if (badge) {
+            badge.remove();
+        }
+    });
+}
+
+// Watchlist sync: changes made in other tabs or on other devices arrive as
+// server-sent events and are applied to whatever this page shows. Only one
+// tab holds the stream; it relays the events to the browser's other tabs
+// over a BroadcastChannel, and when it closes another tab takes over.
+function initializeWatchlistSync() {
+    if (!window.EventSource) return;
+
+    let lastSeq = 0;
+    const handle = (type, data) => {
+        lastSeq = Math.max(lastSeq, data.seq);
+        if (type === 'sync') {
+            setWatchlistBadge(data.count);
+            // Changes were missed while disconnected and can't be replayed
+            if (data.reload && document.querySelector('.watchlist-item')) {
+                showNotification('Your watchlist changed elsewhere. Reload to see the latest.', 'info');
+            }
+        } else {
+            applyWatchlistEvent(data);
+        }
+    };
+
+    if (!window.BroadcastChannel || !navigator.locks) {
+        const source = openWatchlistStream(0, handle);
+        window.addEventListener('pagehide', () => source.close());
+        return;
+    }
+
+    const channel = new BroadcastChannel('watchlist-events');
+    channel.addEventListener('message', event => handle(event.data.type, event.data.data));
+
+    // The lock is held until this tab goes away, then granted to the next tab waiting
+    navigator.locks.request('watchlist-events', () => new Promise(release => {
+        // Ask for what was missed since the last event relayed to this tab
+        const source = openWatchlistStream(lastSeq, (type, data) => {
+            handle(type, data);
+            channel.postMessage({ type, data });
+        });
+        window.addEventListener('pagehide', () => {
+            source.close();
+            release();
+        });
+    }));
+}
+
+// openWatchlistStream connects to the watchlist event stream, starting after
+// event since when it is set, and passes each event to onEvent
+function openWatchlistStream(since, onEvent) {
+    const url = since > 0 ? `/api/v1/watchlist/events?since=${since}` : '/api/v1/watchlist/events';
+    const source = new EventSource(url);
+    source.addEventListener('sync', event => onEvent('sync', JSON.parse(event.data)));
+    source.addEventListener('watchlist', event => onEvent('watchlist', JSON.parse(event.data)));
+    return source;
+}
+
+// applyWatchlistEvent updates the page for one change. It is idempotent, as
+// changes made in this tab come back as events too.
+function applyWatchlistEvent(change) {
+    const item = change.item;
+    const selector = `[data-type="${item.type}"][data-id="${item.id}"]`;
+    setWatchlistBadge(change.count);
+
+    document.querySelectorAll(`[data-watchlist-button]${selector}`).forEach(actions => {
+        const inWatchlist = change.action !== 'removed';
+        if (actions.dataset.inWatchlist !== String(inWatchlist)) {
+            actions.replaceChildren(watchlistButton(item, inWatchlist));
+            actions.dataset.inWatchlist = inWatchlist;
+        }
+    });
+
+    const card = document.querySelector(`.watchlist-item${selector}`);
+    switch (change.action) {
+    case 'removed':
+        if (card) {
+            card.remove();
+            showNotification(`${item.title} was removed from your watchlist`, 'info');
+        }
+        break;
+    case 'toggled':
+        if (card) {
+            setCardWatched(card, !!item.watched);
+        }
+        break;
+    case 'added':
+        if (!card && document.querySelector('.watchlist-grid')) {
+            showNotification(`${item.title} was added to your watchlist. Reload to see it.`, 'info');
+        }
+        break;
+    }
+}
+
+// watchlistButton builds the add or remove button of a detail page
+function watchlistButton(item, inWatchlist) {
+    const button = document.createElement('button');
+    if (inWatchlist) {
+        button.className = 'btn btn-secondary';
+        button.textContent = 'Remove from Watchlist';
+        button.addEventListener('click', () => removeFromWatchlist(item.id, item.type, button));
+    } else {
+        button.className = 'btn btn-primary';
+        button.textContent = 'Add to Watchlist';
+        button.addEventListener('click', () => addToWatchlist(item.id, item.type, item.title,
+            item.poster_path || '', item.release_date || '', item.vote_average || 0, button));
+    }
+    return button;
+}
+
+function setCardWatched(card, watched) {
+    if (card.classList.contains('watched') === watched) return;
+
+    card.classList.toggle('watched', watched);
+    const toggle = card.querySelector('.watchlist-actions .btn');
+    if (toggle) {
+        toggle.textContent = watched ? 'Mark as Unwatched' : 'Mark as Watched';
+    }
+    const badge = card.querySelector('.watched-badge');
+    if (watched && !badge) {
+        const poster = card.querySelector('.media-poster');
+        if (poster) {
+            poster.insertAdjacentHTML('beforeend', '<div class="watched-badge">✓ Watched</div>');
+        }
+    } else if (!watched && badge) {
+        badge.remove();
+    }
+}
+
 // Notification system
 function showNotification(message, type = 'info') {
     // Remove existing notifications
@@ -245,20 +580,121 @@ document.head.appendChild(style);
 
 // Search functionality
 function initializeSearch() {
-    const searchForm = document.querySelector('.search-form');
-    const searchInput = document.querySelector('.search-input');
-    
-    if (searchForm && searchInput) {
-        // Add debounced search suggestions (optional enhancement)
-        let searchTimeout;
-        
-        searchInput.addEventListener('input', function() {
-            clearTimeout(searchTimeout);
-            searchTimeout = setTimeout(() => {
-                // Could implement search suggestions here
-            }, 300);
+    document.querySelectorAll('.search-input, .search-input-large').forEach(attachSuggestions);
+}
+
+// Search-as-you-type suggestions backed by /api/suggest
+function attachSuggestions(input) {
+    const form = input.form;
+    if (!form) return;
+
+    const list = document.createElement('ul');
+    list.className = 'suggestions';
+    list.hidden = true;
+    form.classList.add('has-suggestions');
+    form.appendChild(list);
+    input.setAttribute('autocomplete', 'off');
+
+    const cache = new Map();
+    let searchTimeout;
+    let controller;
+    let active = -1;
+
+    function hide() {
+        list.hidden = true;
+        active = -1;
+    }
+
+    function highlight(index) {
+        const items = list.querySelectorAll('.suggestion');
+        items.forEach((item, i) => item.classList.toggle('active', i === index));
+        active = index;
+    }
+
+    function render(suggestions) {
+        list.innerHTML = '';
+        if (!suggestions.length) {
+            hide();
+            return;
+        }
+
+        suggestions.forEach(s => {
+            const li = document.createElement('li');
+            const link = document.createElement('a');
+            link.className = 'suggestion';
+            link.href = s.media_type === 'tv' ? `/tv/${s.id}` : `/movies/${s.id}`;
+
+            const img = document.createElement('img');
+            img.src = s.poster_url || '/static/images/placeholder.jpg';
+            img.alt = '';
+
+            const title = document.createElement('span');
+            title.className = 'suggestion-title';
+            title.textContent = s.title;
+
+            const meta = document.createElement('span');
+            meta.className = 'suggestion-meta';
+            meta.textContent = [s.year, s.media_type === 'tv' ? 'TV' : 'Movie'].filter(Boolean).join(' · ');
+
+            link.append(img, title, meta);
+            li.appendChild(link);
+            list.appendChild(li);
         });
+
+        active = -1;
+        list.hidden = false;
+    }
+
+    async function fetchSuggestions(query) {
+        if (cache.has(query)) {
+            render(cache.get(query));
+            return;
+        }
+
+        if (controller) controller.abort();
+        controller = new AbortController();
+
+        try {
+            const response = await fetch(`/api/suggest?q=${encodeURIComponent(query)}`, { signal: controller.signal });
+            if (!response.ok) return;
+            const suggestions = await response.json();
+            cache.set(query, suggestions);
+            // Ignore answers for text the user has already changed
+            if (input.value.trim() === query) render(suggestions);
+        } catch (error) {
+            if (error.name !== 'AbortError') console.error('Suggestions error:', error);
+        }
     }
+
+    input.addEventListener('input', function() {
+        clearTimeout(searchTimeout);
+        const query = input.value.trim();
+        if (query.length < 2) {
+            hide();
+            return;
+        }
+        searchTimeout = setTimeout(() => fetchSuggestions(query), 200);
+    });
+
+    input.addEventListener('keydown', function(e) {
+        const items = list.querySelectorAll('.suggestion');
+        if (list.hidden || !items.length) return;
+
+        if (e.key === 'ArrowDown') {
+            e.preventDefault();
+            highlight((active + 1) % items.length);
+        } else if (e.key === 'ArrowUp') {
+            e.preventDefault();
+            highlight((active - 1 + items.length) % items.length);
+        } else if (e.key === 'Enter' && active >= 0) {
+            e.preventDefault();
+            window.location.href = items[active].href;
+        } else if (e.key === 'Escape') {
+            hide();
+        }
+    });
+
+    input.addEventListener('blur', () => setTimeout(hide, 150));
 }
 
 // Mobile navigation toggle
@@ -299,30 +735,66 @@ function initializeLazyLoading() {
     }
 }
 
-// Infinite scroll for content pages
+// Infinite scroll for content pages: when the reader nears the end of a
+// list, the next page is fetched as a partial and its cards are appended
 function initializeInfiniteScroll() {
-    const loadMoreButton = document.querySelector('.load-more');
-    
-    if (loadMoreButton) {
-        let loading = false;
-        
-        window.addEventListener('scroll', () => {
-            if (loading) return;
-            
-            const { scrollTop, scrollHeight, clientHeight } = document.documentElement;
-            
-            if (scrollTop + clientHeight >= scrollHeight - 1000) {
-                loading = true;
-                loadMoreContent();
-            }
-        });
-    }
+    if (!document.querySelector('.pagination a[rel="next"]')) return;
+
+    document.body.classList.add('infinite-scroll');
+    let loading = false;
+
+    window.addEventListener('scroll', () => {
+        if (loading) return;
+
+        const { scrollTop, scrollHeight, clientHeight } = document.documentElement;
+
+        if (scrollTop + clientHeight >= scrollHeight - 1000) {
+            loading = true;
+            loadMoreContent().then(more => {
+                loading = !more;
+            });
+        }
+    });
 }
 
+// loadMoreContent appends the next page and resolves to whether there are
+// more pages after it
 function loadMoreContent() {
-    // This would be implemented based on the current page
-    // For now, it's a placeholder
-    console.log('Loading more content...');
+    const pagination = document.querySelector('.pagination');
+    const next = pagination && pagination.querySelector('a[rel="next"]');
+    if (!next) return Promise.resolve(false);
+
+    pagination.classList.add('loading');
+    return fetchPartial(next.href)
+        .then(html => {
+            const fragment = parseFragment(html);
+
+            // Append each grid's cards to the grid with the same id, adding
+            // sections, such as TV results in a search, that this page lacked
+            fragment.querySelectorAll('[data-scroll-grid]').forEach(grid => {
+                const target = document.getElementById(grid.id);
+                if (target) {
+                    target.append(...grid.children);
+                } else {
+                    pagination.before(grid.closest('[data-scroll-section]') || grid);
+                }
+            });
+
+            const nextPagination = fragment.querySelector('.pagination');
+            if (nextPagination) {
+                pagination.replaceWith(nextPagination);
+                return !!nextPagination.querySelector('a[rel="next"]');
+            }
+            pagination.remove();
+            return false;
+        })
+        .catch(error => {
+            console.error('Error loading more content:', error);
+            pagination.classList.remove('loading');
+            // Let the reader fall back to the Next link
+            document.body.classList.remove('infinite-scroll');
+            return false;
+        });
 }
 
 // Theme toggle functionality (optional enhancement)
@@ -431,6 +903,7 @@ document.addEventListener('DOMContentLoaded', function() {
         initializeMobileNav();
         initializeLazyLoading();
         initializeInfiniteScroll();
+        initializeWatchlistSync();
         initializeThemeToggle();
         initializeKeyboardShortcuts();
         initializeFormValidation();